@@ -10,17 +10,31 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
+	"github.com/yasin-yalcin-dev/go-url-shortener/internal/audit"
+	"github.com/yasin-yalcin-dev/go-url-shortener/internal/cache"
 	"github.com/yasin-yalcin-dev/go-url-shortener/internal/config"
 	"github.com/yasin-yalcin-dev/go-url-shortener/internal/handler"
 	"github.com/yasin-yalcin-dev/go-url-shortener/internal/redis"
 	"github.com/yasin-yalcin-dev/go-url-shortener/internal/service"
 	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/analytics"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/botfilter"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/geoip"
 	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/logger"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/metadata"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/metrics"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/privacy"
 	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/ratelimiter"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/requestid"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/tracing"
 
 	"go.uber.org/zap"
 
@@ -34,22 +48,59 @@ func main() {
 		log.Fatalf("could not load config: %v", err)
 	}
 
-	// Create logger
-	appLogger, err := logger.New(cfg.LogLevel)
+	// Create logger, log files are rotated
+	appLogger, err := logger.New(logger.Config{
+		Level:              cfg.LogLevel,
+		Encoding:           cfg.LogConfig.Encoding,
+		Outputs:            cfg.LogConfig.Outputs,
+		SamplingInitial:    cfg.LogConfig.SamplingInitial,
+		SamplingThereafter: cfg.LogConfig.SamplingThereafter,
+		Rotation: logger.RotationConfig{
+			MaxSizeMB:  cfg.LogConfig.MaxSizeMB,
+			MaxAgeDays: cfg.LogConfig.MaxAgeDays,
+			MaxBackups: cfg.LogConfig.MaxBackups,
+			Compress:   cfg.LogConfig.Compress,
+		},
+		Redaction: logger.RedactionConfig{
+			Query:       cfg.LogConfig.RedactQuery,
+			Credentials: cfg.LogConfig.RedactCredentials,
+			IPMode:      cfg.LogConfig.RedactIPMode,
+		},
+	})
 	if err != nil {
 		log.Fatalf("Failed to initialize logger: %v", err)
 	}
-	defer appLogger.Sync()
+	defer appLogger.Close()
+
+	// OpenTelemetry tracing, spans are dropped unless an exporter is configured
+	tracerProvider, err := tracing.Setup(context.Background(), tracing.Config{
+		Exporter:     cfg.TracingConfig.Exporter,
+		OTLPEndpoint: cfg.TracingConfig.OTLPEndpoint,
+		ServiceName:  cfg.TracingConfig.ServiceName,
+		SampleRatio:  cfg.TracingConfig.SampleRatio,
+	})
+	if err != nil {
+		log.Fatalf("Failed to set up tracing: %v", err)
+	}
+
+	// Prometheus metrics, served on /metrics
+	appMetrics := metrics.New()
 
 	// Create rate limiter
 	// 10 requests per second, burst of 20 requests
-	rateLimiter := ratelimiter.NewRateLimiter(10, 20)
+	rateLimiter := ratelimiter.NewRateLimiter(10, 20, ratelimiter.WithRejectHook(func() {
+		appMetrics.RateLimited("requests")
+	}))
 
 	// Clean up old entries every hour
 	rateLimiter.Clean(1 * time.Hour)
 
-	// Connect to Redis
-	redisClient, err := redis.Connect(cfg)
+	// Connect to Redis, clients created by a reconnect are traced as well
+	var redisOptions []redis.ConnectOption
+	if cfg.TracingConfig.Exporter != tracing.ExporterNone {
+		redisOptions = append(redisOptions, redis.WithClientSetup(tracing.InstrumentRedis))
+	}
+	redisClient, err := redis.Connect(cfg, redisOptions...)
 	if err != nil {
 		appLogger.Error("Redis connection failed",
 			zap.Error(err),
@@ -66,36 +117,330 @@ func main() {
 		}
 	}()
 
-	// Initialize Redis store
-	redisStore := redis.NewRedisStore(redisClient.Client())
+	appMetrics.RegisterRedisPool(redisClient.PoolStats)
+
+	// Background loops run until main returns
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+
+	// Replace the Redis client when the connection stays broken
+	go redisClient.Supervise(backgroundCtx, redis.SupervisorConfig{
+		Interval:         cfg.RedisConfig.HealthCheckInterval,
+		FailureThreshold: cfg.RedisConfig.HealthCheckFailures,
+		MinBackoff:       cfg.RedisConfig.MinRetryBackoff,
+		MaxBackoff:       cfg.RedisConfig.ReconnectMaxBackoff,
+		OnReconnect: func() {
+			appLogger.Info("Reconnected to Redis", zap.String("address", cfg.RedisConfig.Address))
+		},
+		OnError: func(err error) {
+			appLogger.Warn("Redis health check failed",
+				zap.Error(err),
+				zap.String("address", cfg.RedisConfig.Address),
+			)
+		},
+	})
+
+	// Initialize Redis store, it follows client replacements
+	var linkStore redis.URLStore = redis.NewSupervisedRedisStore(redisClient)
+
+	// Hot links are served from an in-process cache, changed links are
+	// invalidated on all replicas through Redis pub/sub
+	if cfg.CacheConfig.Enabled {
+		linkCache := cache.NewStore(linkStore, cfg.CacheConfig.Size,
+			cache.WithTTL(cfg.CacheConfig.TTL),
+			cache.WithNegativeTTL(cfg.CacheConfig.NegativeTTL),
+			cache.WithInvalidator(cache.NewInvalidator(redisClient.Client, cfg.CacheConfig.Channel), func(err error) {
+				appLogger.Warn("Failed to publish link cache invalidation", zap.Error(err))
+			}),
+			cache.WithMetrics(appMetrics),
+		)
+		go linkCache.Listen(backgroundCtx, func(err error) {
+			appLogger.Warn("Link cache invalidations interrupted", zap.Error(err))
+		})
+		linkStore = linkCache
+	}
 
 	// Initialize service
-	urlService := service.NewURLShorteningService(cfg, redisStore)
+	serviceOptions := []service.ServiceOption{service.WithMetrics(appMetrics)}
+
+	// Link mutations are appended to an audit log in a Redis Stream
+	var auditLog *audit.Log
+	if cfg.AuditConfig.Enabled {
+		auditLog = audit.NewLog(redisClient.Client, cfg.AuditConfig.StreamName, cfg.AuditConfig.MaxLen)
+		serviceOptions = append(serviceOptions, service.WithAuditLog(auditLog, func(event audit.Event, err error) {
+			appLogger.Error("Failed to record audit event",
+				zap.Error(err),
+				zap.String("short_id", event.ShortID),
+				zap.String("action", event.Action),
+				zap.String("actor", event.Actor),
+			)
+		}))
+	}
 
-	// Analytics store
-	analyticsStore := analytics.NewAnalyticsStore(redisClient.Client())
+	if cfg.MetadataConfig.Enabled {
+		fetcher := metadata.NewFetcher(
+			metadata.WithTimeout(cfg.MetadataConfig.Timeout),
+			metadata.WithMaxBodySize(cfg.MetadataConfig.MaxBodySize),
+		)
+		serviceOptions = append(serviceOptions, service.WithMetadataFetcher(fetcher, func(shortID string, err error) {
+			appLogger.Debug("Failed to fetch link metadata",
+				zap.String("short_id", shortID),
+				zap.Error(err),
+			)
+		}))
+	}
+	urlService := service.NewURLShorteningService(cfg, linkStore, serviceOptions...)
+
+	// Client IPs are anonymized before they are stored
+	ipAnonymizer, err := privacy.NewAnonymizer(cfg.AnalyticsConfig.IPMode, cfg.AnalyticsConfig.IPHashSecret)
+	if err != nil {
+		log.Fatalf("Failed to create IP anonymizer: %v", err)
+	}
+	if ipAnonymizer.Mode() == privacy.ModeHash && cfg.AnalyticsConfig.IPHashSecret == "" {
+		appLogger.Warn("ANALYTICS_IP_HASH_SECRET is not set, IP hashes will differ between instances")
+	}
+
+	// Analytics store, access events are written asynchronously in batches
+	analyticsOptions := []analytics.StoreOption{
+		analytics.WithIPAnonymizer(ipAnonymizer),
+		analytics.WithRetention(cfg.AnalyticsConfig.Retention),
+		analytics.WithClientSource(redisClient.Client),
+	}
+	if cfg.AnalyticsConfig.StreamEnabled {
+		analyticsOptions = append(analyticsOptions, analytics.WithEventStream(analytics.StreamConfig{
+			Name:   cfg.AnalyticsConfig.StreamName,
+			MaxLen: int64(cfg.AnalyticsConfig.StreamMaxLen),
+			IPSalt: cfg.AnalyticsConfig.IPHashSalt,
+		}))
+	}
+	redisAnalytics := analytics.NewAnalyticsStore(redisClient.Client(), analyticsOptions...)
+
+	// Analytics recorded before keys were hash-tagged are merged in the background
+	go func() {
+		migrated, err := redisAnalytics.MigrateLegacyKeys(backgroundCtx)
+		if err != nil {
+			appLogger.Error("Failed to migrate legacy analytics keys", zap.Error(err))
+			return
+		}
+		if migrated > 0 {
+			appLogger.Info("Migrated legacy analytics keys", zap.Int("keys", migrated))
+		}
+	}()
+
+	analyticsStore := analytics.NewPipeline(
+		redisAnalytics,
+		analytics.PipelineConfig{
+			QueueSize:      cfg.AnalyticsConfig.QueueSize,
+			Workers:        cfg.AnalyticsConfig.Workers,
+			BatchSize:      cfg.AnalyticsConfig.BatchSize,
+			FlushInterval:  cfg.AnalyticsConfig.FlushInterval,
+			EnqueueTimeout: cfg.AnalyticsConfig.EnqueueTimeout,
+			OnError: func(err error, events int) {
+				appLogger.Error("Failed to write analytics batch",
+					zap.Error(err),
+					zap.Int("events", events),
+				)
+			},
+		},
+	)
+
+	appMetrics.RegisterAnalytics(analyticsStore.Stats)
+
+	// Bot classifier, the pattern file is reloaded when it changes
+	var botClassifier *botfilter.Classifier
+	if cfg.AnalyticsConfig.BotPatternFile != "" {
+		botClassifier, err = botfilter.LoadClassifier(cfg.AnalyticsConfig.BotPatternFile)
+		if err != nil {
+			log.Fatalf("Failed to load bot patterns: %v", err)
+		}
+		botClassifier.Watch(backgroundCtx, cfg.AnalyticsConfig.BotReload, func(err error) {
+			appLogger.Error("Failed to reload bot patterns", zap.Error(err))
+		})
+	} else {
+		botClassifier, err = botfilter.NewClassifier()
+		if err != nil {
+			log.Fatalf("Failed to create bot classifier: %v", err)
+		}
+	}
+
+	// Optional GeoIP database for countries not set by a proxy
+	var geoResolver geoip.Resolver
+	if cfg.GeoIPFile != "" {
+		geoDB, err := geoip.Load(cfg.GeoIPFile)
+		if err != nil {
+			log.Fatalf("Failed to load GeoIP database: %v", err)
+		}
+		appLogger.Info("GeoIP database loaded", zap.Int("networks", geoDB.Len()))
+		geoResolver = geoDB
+	}
+
+	// Password attempts are throttled per client and link, and per link
+	passwordLimiter := ratelimiter.NewRateLimiter(
+		float64(cfg.PasswordConfig.Attempts)/cfg.PasswordConfig.Window.Seconds(),
+		cfg.PasswordConfig.Attempts,
+		ratelimiter.WithRejectHook(func() {
+			appMetrics.RateLimited("password")
+		}),
+	)
+	passwordLimiter.Clean(cfg.PasswordConfig.Window)
+	passwordLinkLimiter := ratelimiter.NewRateLimiter(
+		float64(cfg.PasswordConfig.LinkAttempts)/cfg.PasswordConfig.Window.Seconds(),
+		cfg.PasswordConfig.LinkAttempts,
+		ratelimiter.WithRejectHook(func() {
+			appMetrics.RateLimited("password")
+		}),
+	)
+	passwordLinkLimiter.Clean(cfg.PasswordConfig.Window)
+	trustedProxies, err := privacy.ParseProxies(cfg.PasswordConfig.TrustedProxies)
+	if err != nil {
+		log.Fatalf("Invalid trusted proxies: %v", err)
+	}
+
+	// Source IPs of audited changes are truncated unless AUDIT_IP_MODE is none
+	auditAnonymizer, err := privacy.NewAnonymizer(cfg.AuditConfig.IPMode, "")
+	if err != nil {
+		log.Fatalf("Invalid audit IP mode: %v", err)
+	}
 
 	// Initialize handler
 	shortenHandler := &handler.ShortenHandler{
-		Service:   urlService,
-		Logger:    appLogger,
-		Analytics: analyticsStore,
+		Service:             urlService,
+		Logger:              appLogger,
+		Analytics:           analyticsStore,
+		BotClassifier:       botClassifier,
+		PasswordLimiter:     passwordLimiter,
+		PasswordLinkLimiter: passwordLinkLimiter,
+		TrustedProxies:      trustedProxies,
+		GeoIP:               geoResolver,
+		Metrics:             appMetrics,
+	}
+	// Probes for load balancers and Kubernetes
+	healthHandler := &handler.HealthHandler{
+		Checks: []handler.HealthCheck{
+			handler.RedisCheck(redisClient),
+			// Not ready before the queue is full and events are dropped
+			handler.AnalyticsQueueCheck(analyticsStore.Stats, 0.9),
+		},
+		Timeout: cfg.ReadinessTimeout,
 	}
+
 	// Create a new router
 	r := chi.NewRouter()
 
-	// Apply rate limiting middleware
-	r.Use(rateLimiter.ChiMiddleware)
+	// Every request gets an ID, returned in X-Request-ID, error bodies and logs
+	r.Use(requestid.Middleware)
+	r.Use(logger.Middleware)
+
+	// One access log entry per request, URLs and client IPs are redacted
+	r.Use(appLogger.AccessLog)
+
+	// Count and time every request, including rate limited ones
+	r.Use(appMetrics.Middleware)
+
+	// Continue W3C traces and start a server span per request
+	r.Use(tracing.Middleware)
+
+	// Operational routes are not rate limited
+	r.Get("/healthz", healthHandler.Liveness)
+	r.Get("/readyz", healthHandler.Readiness)
+	r.Method(http.MethodGet, "/metrics", appMetrics.Handler())
+
+	r.Group(func(r chi.Router) {
+		// Apply rate limiting middleware
+		r.Use(rateLimiter.ChiMiddleware)
+
+		// Link mutations are audited as made by anonymous clients, unless a
+		// gateway names the actor
+		r.Use(audit.Middleware(cfg.AuditConfig.ActorHeader, audit.ActorAnonymous, trustedProxies, auditAnonymizer))
+
+		// Routes
+		r.Post("/shorten", shortenHandler.ShortenURL)  // URL shortening endpoint
+		r.Get("/{shortened}", shortenHandler.Redirect) // URL redirect endpoint
+		r.Head("/{shortened}", shortenHandler.Redirect)
+		r.Post("/{shortened}", shortenHandler.Redirect)  // Password form submissions and 307/308 links
+		r.Get("/{shortened}/*", shortenHandler.Redirect) // Path passthrough, static routes below take precedence
+		r.Head("/{shortened}/*", shortenHandler.Redirect)
+		r.Post("/{shortened}/*", shortenHandler.Redirect)
+		r.Get("/{shortened}/analytics", shortenHandler.GetURLAnalytics)
+		r.Get("/{shortened}/analytics/export", shortenHandler.ExportURLAnalytics)
+	})
+
+	// Admin endpoints are only served when ADMIN_TOKEN is set
+	if cfg.AdminToken != "" {
+		r.Group(func(r chi.Router) {
+			r.Use(rateLimiter.ChiMiddleware)
+			r.Use(handler.RequireToken(cfg.AdminToken))
+			r.Use(audit.Middleware(cfg.AuditConfig.ActorHeader, audit.ActorAdmin, trustedProxies, auditAnonymizer))
 
-	// Routes
-	r.Post("/shorten", shortenHandler.ShortenURL)  // URL shortening endpoint
-	r.Get("/{shortened}", shortenHandler.Redirect) // URL redirect endpoint
-	r.Get("/{shortened}/analytics", shortenHandler.GetURLAnalytics)
+			// Read or change the log level at runtime
+			r.Method(http.MethodGet, "/admin/log-level", appLogger.LevelHandler())
+			r.Method(http.MethodPut, "/admin/log-level", appLogger.LevelHandler())
+
+			r.Delete("/admin/links/{shortened}", shortenHandler.DeleteURL)
+			r.Delete("/admin/links/{shortened}/analytics", shortenHandler.PurgeURLAnalytics)
+			r.Get("/admin/analytics/top", shortenHandler.TopLinks)
+			if auditLog != nil {
+				auditHandler := &handler.AuditHandler{Log: auditLog, Logger: appLogger}
+				r.Get("/admin/audit", auditHandler.Query)
+			}
+		})
+	}
+
+	server := &http.Server{
+		Addr:    ":" + cfg.ServerPort,
+		Handler: r,
+	}
+
+	// Stop on SIGINT/SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	// Start the server
-	log.Printf("Server starting on port %s...", cfg.ServerPort)
-	if err := http.ListenAndServe(":"+cfg.ServerPort, r); err != nil {
-		appLogger.Error("Server startup failed", zap.Error(err))
-		log.Fatalf("Server startup failed: %v", err)
+	serverErr := make(chan error, 1)
+	go func() {
+		log.Printf("Server starting on port %s...", cfg.ServerPort)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+		close(serverErr)
+	}()
+
+	select {
+	case err := <-serverErr:
+		if err != nil {
+			appLogger.Error("Server startup failed", zap.Error(err))
+			log.Fatalf("Server startup failed: %v", err)
+		}
+	case <-ctx.Done():
+	}
+
+	// Graceful shutdown: report not ready, stop accepting requests, then flush queued analytics
+	appLogger.Info("Shutting down server")
+	healthHandler.SetShuttingDown()
+	if cfg.ShutdownDrainDelay > 0 {
+		// Give load balancers time to notice before connections are refused
+		time.Sleep(cfg.ShutdownDrainDelay)
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		appLogger.Error("Server shutdown failed", zap.Error(err))
+	}
+	if err := urlService.Close(shutdownCtx); err != nil {
+		appLogger.Error("Failed to finish metadata fetches", zap.Error(err))
+	}
+	if err := analyticsStore.Close(shutdownCtx); err != nil {
+		appLogger.Error("Failed to flush analytics queue",
+			zap.Error(err),
+			zap.Int("pending", analyticsStore.Stats().QueueDepth),
+		)
+	}
+	// Stop background loops before the final flush
+	stopBackground()
+
+	// Spans of the final requests and batches are flushed last
+	if err := tracerProvider.Shutdown(shutdownCtx); err != nil {
+		appLogger.Error("Failed to flush traces", zap.Error(err))
 	}
 }
@@ -0,0 +1,125 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package audit
+
+import (
+	"context"
+	"net/http"
+	"net/netip"
+	"time"
+
+	"github.com/yasin-yalcin-dev/go-url-shortener/internal/model"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/privacy"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/requestid"
+)
+
+// Link mutations
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+// Actors recorded when no identity is known
+const (
+	ActorAnonymous = "anonymous" // Public API clients
+	ActorAdmin     = "admin"     // Holders of the admin token
+	ActorSystem    = "system"    // Background work such as metadata fetches
+)
+
+// maxActorLength is the longest actor taken from a header
+const maxActorLength = 128
+
+// redacted replaces password hashes in link snapshots
+const redacted = "REDACTED"
+
+// Event is a recorded mutation of a link
+type Event struct {
+	ID        string      `json:"id,omitempty"` // Stream entry ID, set once recorded
+	Time      time.Time   `json:"time"`
+	Actor     string      `json:"actor"`
+	Action    string      `json:"action"`
+	ShortID   string      `json:"short_id"`
+	SourceIP  string      `json:"source_ip,omitempty"`
+	RequestID string      `json:"request_id,omitempty"`
+	Before    *model.Link `json:"before,omitempty"` // Unset for created links
+	After     *model.Link `json:"after,omitempty"`  // Unset for deleted links
+}
+
+// origin is who caused the mutations of a context
+type origin struct {
+	actor    string
+	sourceIP string
+}
+
+type originKey struct{}
+
+// WithActor returns a context whose mutations are recorded for actor, without a source IP
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, originKey{}, origin{actor: actor})
+}
+
+// Middleware records the client IP and actor of a request for its mutations.
+// The actor is read from header when it is set, trust it only behind a gateway
+// that authenticates clients and overwrites the header. Otherwise, or when the
+// request has no such header, defaultActor is recorded. X-Forwarded-For is only
+// followed through trusted proxies, and the client IP is anonymized before it
+// is recorded.
+func Middleware(header, defaultActor string, trusted []netip.Prefix, anonymizer *privacy.Anonymizer) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			actor := defaultActor
+			if header != "" {
+				if value := r.Header.Get(header); value != "" && len(value) <= maxActorLength {
+					actor = value
+				}
+			}
+			sourceIP := anonymizer.Anonymize(privacy.TrustedClientIP(r, trusted), time.Now())
+			ctx := context.WithValue(r.Context(), originKey{}, origin{actor: actor, sourceIP: sourceIP})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// NewEvent creates the event of a mutation made in ctx. Snapshots are copied
+// without password hashes.
+func NewEvent(ctx context.Context, action string, before, after *model.Link) Event {
+	o, ok := ctx.Value(originKey{}).(origin)
+	if !ok {
+		o.actor = ActorSystem
+	}
+	event := Event{
+		Time:      time.Now().UTC(),
+		Actor:     o.actor,
+		Action:    action,
+		SourceIP:  o.sourceIP,
+		RequestID: requestid.FromContext(ctx),
+		Before:    snapshot(before),
+		After:     snapshot(after),
+	}
+	if after != nil {
+		event.ShortID = after.ShortID
+	} else if before != nil {
+		event.ShortID = before.ShortID
+	}
+	return event
+}
+
+// snapshot copies a link, masking its password hash
+func snapshot(link *model.Link) *model.Link {
+	if link == nil {
+		return nil
+	}
+	copied := *link
+	if copied.PasswordHash != "" {
+		copied.PasswordHash = redacted
+	}
+	return &copied
+}
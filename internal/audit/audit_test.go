@@ -0,0 +1,105 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package audit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/yasin-yalcin-dev/go-url-shortener/internal/model"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/privacy"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/requestid"
+)
+
+func TestMiddleware(t *testing.T) {
+	// httptest requests come from 192.0.2.1
+	proxies := []netip.Prefix{netip.MustParsePrefix("192.0.2.0/24")}
+
+	testCases := []struct {
+		name          string
+		header        string
+		actor         string
+		trusted       []netip.Prefix
+		ipMode        string
+		expectedActor string
+		expectedIP    string
+	}{
+		{name: "Default Actor", header: "", actor: "jane", trusted: proxies, ipMode: privacy.ModeNone, expectedActor: ActorAnonymous, expectedIP: "203.0.113.7"},
+		{name: "Actor Header", header: "X-Authenticated-User", actor: "jane", trusted: proxies, ipMode: privacy.ModeNone, expectedActor: "jane", expectedIP: "203.0.113.7"},
+		{name: "Missing Actor Header", header: "X-Authenticated-User", actor: "", trusted: proxies, ipMode: privacy.ModeNone, expectedActor: ActorAnonymous, expectedIP: "203.0.113.7"},
+		{name: "Actor Too Long", header: "X-Authenticated-User", actor: strings.Repeat("a", 200), trusted: proxies, ipMode: privacy.ModeNone, expectedActor: ActorAnonymous, expectedIP: "203.0.113.7"},
+		{name: "Untrusted Forwarded Header", header: "", actor: "", ipMode: privacy.ModeNone, expectedActor: ActorAnonymous, expectedIP: "192.0.2.1"},
+		{name: "Truncated Source IP", header: "", actor: "", trusted: proxies, ipMode: privacy.ModeTruncate, expectedActor: ActorAnonymous, expectedIP: "203.0.113.0"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			anonymizer, err := privacy.NewAnonymizer(tc.ipMode, "")
+			if err != nil {
+				t.Fatalf("Failed to create anonymizer: %v", err)
+			}
+
+			var event Event
+			handler := requestid.Middleware(Middleware(tc.header, ActorAnonymous, tc.trusted, anonymizer)(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					event = NewEvent(r.Context(), ActionCreate, nil, &model.Link{ShortID: "abc123"})
+				}),
+			))
+
+			req := httptest.NewRequest(http.MethodPost, "/shorten", nil)
+			req.Header.Set("X-Forwarded-For", "203.0.113.7")
+			req.Header.Set(requestid.Header, "req-1")
+			if tc.actor != "" {
+				req.Header.Set("X-Authenticated-User", tc.actor)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if event.Actor != tc.expectedActor {
+				t.Errorf("Expected actor %q, got %q", tc.expectedActor, event.Actor)
+			}
+			if event.SourceIP != tc.expectedIP {
+				t.Errorf("Expected source IP %q, got %q", tc.expectedIP, event.SourceIP)
+			}
+			if event.RequestID != "req-1" || event.ShortID != "abc123" {
+				t.Errorf("Unexpected event %+v", event)
+			}
+		})
+	}
+}
+
+func TestNewEvent(t *testing.T) {
+	before := &model.Link{ShortID: "abc123", Original: "https://example.com", PasswordHash: "$2a$10$hash"}
+
+	// Mutations outside of requests are made by the system
+	event := NewEvent(context.Background(), ActionDelete, before, nil)
+	if event.Actor != ActorSystem || event.ShortID != "abc123" || event.After != nil {
+		t.Errorf("Unexpected event %+v", event)
+	}
+	if event.Time.IsZero() {
+		t.Error("Expected the event time to be set")
+	}
+
+	// Password hashes are never recorded
+	if event.Before.PasswordHash != redacted {
+		t.Errorf("Expected the password hash to be redacted, got %q", event.Before.PasswordHash)
+	}
+	if before.PasswordHash != "$2a$10$hash" {
+		t.Error("Expected the link itself to be unchanged")
+	}
+
+	event = NewEvent(WithActor(context.Background(), "jane"), ActionUpdate, before, before)
+	if event.Actor != "jane" || event.SourceIP != "" {
+		t.Errorf("Unexpected event %+v", event)
+	}
+}
@@ -0,0 +1,193 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/yasin-yalcin-dev/go-url-shortener/internal/model"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	// DefaultStreamName is the default Redis Stream key of the audit log
+	DefaultStreamName = "audit:links"
+
+	// queryPage is the number of stream entries read per XRANGE
+	queryPage = 500
+
+	// DefaultLimit and MaxLimit bound the events returned by a query
+	DefaultLimit = 100
+	MaxLimit     = 1000
+)
+
+// Log is an append-only audit log of link mutations in a Redis Stream
+type Log struct {
+	client func() redis.UniversalClient
+	name   string
+	maxLen int64
+}
+
+// NewLog creates an audit log in the stream name, using the current client of
+// source. maxLen caps the stream approximately, 0 keeps every event.
+func NewLog(source func() redis.UniversalClient, name string, maxLen int64) *Log {
+	if name == "" {
+		name = DefaultStreamName
+	}
+	return &Log{client: source, name: name, maxLen: maxLen}
+}
+
+// Record appends an event to the stream
+func (l *Log) Record(ctx context.Context, event Event) (err error) {
+	ctx, span := tracing.Start(ctx, "AuditLog.Record",
+		attribute.String("short_id", event.ShortID),
+		attribute.String("action", event.Action),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	values := map[string]interface{}{
+		"time":       event.Time.UTC().Format(time.RFC3339Nano),
+		"actor":      event.Actor,
+		"action":     event.Action,
+		"short_id":   event.ShortID,
+		"source_ip":  event.SourceIP,
+		"request_id": event.RequestID,
+	}
+	for field, link := range map[string]*model.Link{"before": event.Before, "after": event.After} {
+		if link == nil {
+			continue
+		}
+		data, err := json.Marshal(link)
+		if err != nil {
+			return fmt.Errorf("failed to encode audit snapshot: %v", err)
+		}
+		values[field] = string(data)
+	}
+
+	err = l.client().XAdd(ctx, &redis.XAddArgs{
+		Stream: l.name,
+		MaxLen: l.maxLen,
+		Approx: l.maxLen > 0,
+		Values: values,
+	}).Err()
+	if err != nil {
+		return fmt.Errorf("failed to record audit event: %v", err)
+	}
+	return nil
+}
+
+// Filter selects the events returned by Query, empty fields match all events
+type Filter struct {
+	ShortID string
+	Actor   string
+	From    time.Time
+	To      time.Time
+	After   string // Stream ID of the last event of the previous page
+	Limit   int    // DefaultLimit when 0, at most MaxLimit
+}
+
+// Query returns the matching events between From and To, oldest first.
+// When more events match, next is the cursor to pass as Filter.After.
+func (l *Log) Query(ctx context.Context, filter Filter) (events []Event, next string, err error) {
+	ctx, span := tracing.Start(ctx, "AuditLog.Query", attribute.String("short_id", filter.ShortID))
+	defer func() { tracing.End(span, err) }()
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+
+	// Stream IDs start with the millisecond timestamp of the entry
+	start, end := "-", "+"
+	if !filter.From.IsZero() {
+		start = strconv.FormatInt(filter.From.UnixMilli(), 10)
+	}
+	if !filter.To.IsZero() {
+		end = strconv.FormatInt(filter.To.UnixMilli(), 10)
+	}
+	if filter.After != "" {
+		start = "(" + filter.After
+	}
+
+	// The stream holds events of all links, so it is scanned page by page
+	for {
+		messages, err := l.client().XRangeN(ctx, l.name, start, end, queryPage).Result()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return nil, "", fmt.Errorf("failed to read audit log: %v", err)
+		}
+
+		for _, msg := range messages {
+			event, err := parseEvent(msg)
+			if err != nil {
+				return nil, "", err
+			}
+			if !filter.matches(event) {
+				continue
+			}
+			if len(events) == limit {
+				return events, events[len(events)-1].ID, nil
+			}
+			events = append(events, event)
+		}
+
+		if len(messages) < queryPage {
+			return events, "", nil
+		}
+		start = "(" + messages[len(messages)-1].ID
+	}
+}
+
+// matches reports whether the event passes the short ID and actor filters
+func (f Filter) matches(event Event) bool {
+	return (f.ShortID == "" || event.ShortID == f.ShortID) &&
+		(f.Actor == "" || strings.EqualFold(event.Actor, f.Actor))
+}
+
+// parseEvent converts a stream entry into an Event
+func parseEvent(msg redis.XMessage) (Event, error) {
+	field := func(name string) string {
+		value, _ := msg.Values[name].(string)
+		return value
+	}
+
+	event := Event{
+		ID:        msg.ID,
+		Actor:     field("actor"),
+		Action:    field("action"),
+		ShortID:   field("short_id"),
+		SourceIP:  field("source_ip"),
+		RequestID: field("request_id"),
+	}
+	event.Time, _ = time.Parse(time.RFC3339Nano, field("time"))
+
+	for name, target := range map[string]**model.Link{"before": &event.Before, "after": &event.After} {
+		data := field(name)
+		if data == "" {
+			continue
+		}
+		var link model.Link
+		if err := json.Unmarshal([]byte(data), &link); err != nil {
+			return Event{}, fmt.Errorf("failed to decode audit snapshot of %s: %v", msg.ID, err)
+		}
+		*target = &link
+	}
+	return event, nil
+}
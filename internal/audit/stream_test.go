@@ -0,0 +1,134 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package audit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/yasin-yalcin-dev/go-url-shortener/internal/model"
+)
+
+// setupLog creates an audit log backed by miniredis
+func setupLog(t *testing.T) *Log {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewLog(func() redis.UniversalClient { return client }, "", 0)
+}
+
+func TestLog_RecordAndQuery(t *testing.T) {
+	log := setupLog(t)
+	ctx := context.Background()
+
+	created := &model.Link{ShortID: "abc123", Original: "https://example.com", Tags: []string{"news"}}
+	events := []Event{
+		{Actor: "jane", Action: ActionCreate, ShortID: "abc123", After: created, SourceIP: "203.0.113.7", RequestID: "req-1"},
+		{Actor: "john", Action: ActionCreate, ShortID: "def456", After: &model.Link{ShortID: "def456"}},
+		{Actor: "admin", Action: ActionDelete, ShortID: "abc123", Before: created},
+	}
+	for _, event := range events {
+		event.Time = time.Now().UTC()
+		if err := log.Record(ctx, event); err != nil {
+			t.Fatalf("Failed to record event: %v", err)
+		}
+	}
+
+	testCases := []struct {
+		name            string
+		filter          Filter
+		expectedActions []string
+	}{
+		{name: "All Events", filter: Filter{}, expectedActions: []string{ActionCreate, ActionCreate, ActionDelete}},
+		{name: "By Link", filter: Filter{ShortID: "abc123"}, expectedActions: []string{ActionCreate, ActionDelete}},
+		{name: "By Actor", filter: Filter{Actor: "John"}, expectedActions: []string{ActionCreate}},
+		{name: "Outside Time Range", filter: Filter{To: time.Now().Add(-time.Hour)}, expectedActions: nil},
+		{name: "Inside Time Range", filter: Filter{From: time.Now().Add(-time.Hour), To: time.Now().Add(time.Hour), ShortID: "def456"}, expectedActions: []string{ActionCreate}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, next, err := log.Query(ctx, tc.filter)
+			if err != nil {
+				t.Fatalf("Query failed: %v", err)
+			}
+			if next != "" {
+				t.Errorf("Expected no next page, got %s", next)
+			}
+			if len(got) != len(tc.expectedActions) {
+				t.Fatalf("Expected %d events, got %d", len(tc.expectedActions), len(got))
+			}
+			for i, action := range tc.expectedActions {
+				if got[i].Action != action {
+					t.Errorf("Expected event %d to be %s, got %s", i, action, got[i].Action)
+				}
+			}
+		})
+	}
+
+	// Snapshots and request details are read back
+	got, _, _ := log.Query(ctx, Filter{ShortID: "abc123"})
+	first, last := got[0], got[1]
+	if first.ID == "" || first.Time.IsZero() || first.SourceIP != "203.0.113.7" || first.RequestID != "req-1" {
+		t.Errorf("Unexpected event %+v", first)
+	}
+	if first.Before != nil || first.After == nil || first.After.Original != "https://example.com" || len(first.After.Tags) != 1 {
+		t.Errorf("Unexpected snapshots %+v / %+v", first.Before, first.After)
+	}
+	if last.Before == nil || last.After != nil {
+		t.Errorf("Expected only a before snapshot, got %+v / %+v", last.Before, last.After)
+	}
+}
+
+func TestLog_QueryPages(t *testing.T) {
+	log := setupLog(t)
+	ctx := context.Background()
+
+	// Enough events of other links to span several stream reads
+	for i := 0; i < queryPage+10; i++ {
+		shortID := "other"
+		if i%100 == 0 {
+			shortID = "abc123"
+		}
+		event := Event{Time: time.Now().UTC(), Actor: "jane", Action: ActionCreate, ShortID: shortID}
+		if err := log.Record(ctx, event); err != nil {
+			t.Fatalf("Failed to record event: %v", err)
+		}
+	}
+
+	var ids []string
+	filter := Filter{ShortID: "abc123", Limit: 2}
+	for page := 0; page < 5; page++ {
+		events, next, err := log.Query(ctx, filter)
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		for _, event := range events {
+			ids = append(ids, event.ID)
+		}
+		if next == "" {
+			break
+		}
+		filter.After = next
+	}
+
+	if len(ids) != 6 {
+		t.Fatalf("Expected 6 events across pages, got %d", len(ids))
+	}
+	for i := 1; i < len(ids); i++ {
+		if ids[i] == ids[i-1] {
+			t.Errorf("Expected distinct events, got %s twice", ids[i])
+		}
+	}
+}
@@ -0,0 +1,103 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// Delays between attempts to subscribe again after the subscription failed
+	minResubscribeBackoff = 100 * time.Millisecond
+	maxResubscribeBackoff = 10 * time.Second
+)
+
+// Invalidator broadcasts changed short IDs to the caches of all replicas
+// through a Redis pub/sub channel
+type Invalidator struct {
+	client  func() redis.UniversalClient
+	channel string
+}
+
+// NewInvalidator creates an invalidator on the given channel. The client is
+// read from source on every use, so it follows a reconnecting supervisor.
+func NewInvalidator(source func() redis.UniversalClient, channel string) *Invalidator {
+	return &Invalidator{client: source, channel: channel}
+}
+
+// Publish tells all replicas that a link changed
+func (i *Invalidator) Publish(ctx context.Context, shortID string) error {
+	if err := i.client().Publish(ctx, i.channel, shortID).Err(); err != nil {
+		return fmt.Errorf("failed to publish cache invalidation: %v", err)
+	}
+	return nil
+}
+
+// Listen calls remove for every published short ID until ctx is done. Messages
+// may be lost while the subscription is down, so reset is called whenever
+// the channel is (re)subscribed. Failed subscriptions are retried with backoff.
+func (i *Invalidator) Listen(ctx context.Context, remove func(shortID string), reset func(), onError func(error)) {
+	backoff := minResubscribeBackoff
+	for {
+		subscribed, err := i.listen(ctx, remove, reset)
+		if ctx.Err() != nil {
+			return
+		}
+		if subscribed {
+			backoff = minResubscribeBackoff
+		}
+		if onError != nil {
+			onError(fmt.Errorf("cache invalidation subscription failed: %v", err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxResubscribeBackoff)
+	}
+}
+
+// listen receives messages from one subscription until it fails
+func (i *Invalidator) listen(ctx context.Context, remove func(string), reset func()) (subscribed bool, err error) {
+	pubsub := i.client().Subscribe(ctx, i.channel)
+	defer pubsub.Close()
+
+	// Receive blocks on the connection, closing it ends the loop on shutdown
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			pubsub.Close()
+		case <-done:
+		}
+	}()
+
+	for {
+		msg, err := pubsub.Receive(ctx)
+		if err != nil {
+			return subscribed, err
+		}
+
+		switch msg := msg.(type) {
+		case *redis.Subscription:
+			subscribed = true
+			reset()
+		case *redis.Message:
+			remove(msg.Payload)
+		}
+	}
+}
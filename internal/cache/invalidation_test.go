@@ -0,0 +1,135 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/yasin-yalcin-dev/go-url-shortener/internal/model"
+	"github.com/yasin-yalcin-dev/go-url-shortener/internal/redis"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/metadata"
+)
+
+const testChannel = "test:invalidate"
+
+// newReplica creates a cache with its own Redis client, like another instance
+func newReplica(t *testing.T, mr *miniredis.Miniredis) *Store {
+	t.Helper()
+	client := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	source := func() goredis.UniversalClient { return client }
+	return NewStore(redis.NewRedisStore(client), 100,
+		WithInvalidator(NewInvalidator(source, testChannel), func(err error) {
+			t.Errorf("Failed to publish invalidation: %v", err)
+		}),
+	)
+}
+
+// waitFor polls condition until it holds or a second passed
+func waitFor(t *testing.T, message string, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal(message)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// subscribers returns the number of subscriptions to the test channel
+func subscribers(mr *miniredis.Miniredis) int {
+	return mr.PubSubNumSub(testChannel)[testChannel]
+}
+
+func TestInvalidator_AcrossReplicas(t *testing.T) {
+	mr := miniredis.RunT(t)
+	writer, reader := newReplica(t, mr), newReplica(t, mr)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go reader.Listen(ctx, nil)
+	waitFor(t, "Expected the reader to subscribe", func() bool { return subscribers(mr) == 1 })
+
+	if err := writer.SaveLinkWithTTL(ctx, &model.Link{ShortID: "abc123", Original: "https://example.com"}, 0); err != nil {
+		t.Fatalf("Failed to save link: %v", err)
+	}
+	if _, err := reader.GetLink(ctx, "abc123"); err != nil {
+		t.Fatalf("GetLink failed: %v", err)
+	}
+	if reader.Len() != 1 {
+		t.Fatalf("Expected the reader to cache the link, got %d entries", reader.Len())
+	}
+
+	// An update on one replica drops the link from the other's cache
+	if err := writer.SaveLinkMetadata(ctx, "abc123", &metadata.Metadata{Title: "Example"}); err != nil {
+		t.Fatalf("Failed to save metadata: %v", err)
+	}
+	waitFor(t, "Expected the reader's cached link to be invalidated", func() bool { return reader.Len() == 0 })
+
+	link, err := reader.GetLink(ctx, "abc123")
+	if err != nil || link.Metadata == nil || link.Metadata.Title != "Example" {
+		t.Errorf("Expected the updated link, got %+v (%v)", link, err)
+	}
+
+	// Listen returns once the context is done
+	done := make(chan struct{})
+	go func() {
+		writer.Listen(ctx, nil)
+		close(done)
+	}()
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("Expected Listen to return after cancel")
+	}
+}
+
+func TestInvalidator_Resubscribe(t *testing.T) {
+	mr := miniredis.RunT(t)
+	reader := newReplica(t, mr)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errs := make(chan error, 10)
+	go reader.Listen(ctx, func(err error) {
+		select {
+		case errs <- err:
+		default:
+		}
+	})
+	waitFor(t, "Expected the reader to subscribe", func() bool { return subscribers(mr) == 1 })
+
+	if err := reader.SaveLinkWithTTL(ctx, &model.Link{ShortID: "abc123", Original: "https://example.com"}, 0); err != nil {
+		t.Fatalf("Failed to save link: %v", err)
+	}
+	reader.GetLink(ctx, "abc123")
+
+	// Invalidations published while the subscription is down are lost
+	mr.Close()
+	select {
+	case <-errs:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the broken subscription to be reported")
+	}
+	if err := mr.Restart(); err != nil {
+		t.Fatalf("Failed to restart miniredis: %v", err)
+	}
+
+	// so the cache is cleared once the channel is subscribed again
+	waitFor(t, "Expected the reader to subscribe again", func() bool { return subscribers(mr) == 1 })
+	waitFor(t, "Expected the cache to be cleared after resubscribing", func() bool { return reader.Len() == 0 })
+}
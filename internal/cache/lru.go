@@ -0,0 +1,128 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/yasin-yalcin-dev/go-url-shortener/internal/model"
+)
+
+// entry is a cached link, a nil link records that the short ID does not exist
+type entry struct {
+	shortID   string
+	link      *model.Link
+	expiresAt time.Time
+}
+
+// lru is a size-bounded least recently used cache of links with per-entry expiry
+type lru struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List // Front is the most recently used entry
+	items    map[string]*list.Element
+	now      func() time.Time
+
+	// generation changes on every removal, so a value read from Redis before
+	// an invalidation is not cached after it
+	generation uint64
+}
+
+// newLRU creates a cache holding at most capacity links
+func newLRU(capacity int) *lru {
+	return &lru{
+		capacity: capacity,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+		now:      time.Now,
+	}
+}
+
+// get returns the cached entry of a short ID unless it expired
+func (c *lru) get(shortID string) (*entry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[shortID]
+	if !ok {
+		return nil, false
+	}
+	e := elem.Value.(*entry)
+	if !c.now().Before(e.expiresAt) {
+		c.removeElement(elem)
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return e, true
+}
+
+// currentGeneration returns the generation to pass to add after a read
+func (c *lru) currentGeneration() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generation
+}
+
+// add caches a link until expiresAt, unless entries were removed since the
+// generation was read. The least recently used entry is evicted when full.
+func (c *lru) add(shortID string, link *model.Link, expiresAt time.Time, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation || !c.now().Before(expiresAt) {
+		return
+	}
+
+	if elem, ok := c.items[shortID]; ok {
+		elem.Value = &entry{shortID: shortID, link: link, expiresAt: expiresAt}
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.items[shortID] = c.order.PushFront(&entry{shortID: shortID, link: link, expiresAt: expiresAt})
+	if c.order.Len() > c.capacity {
+		c.removeElement(c.order.Back())
+	}
+}
+
+// remove drops the entry of a short ID
+func (c *lru) remove(shortID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	if elem, ok := c.items[shortID]; ok {
+		c.removeElement(elem)
+	}
+}
+
+// purge drops all entries
+func (c *lru) purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	c.order.Init()
+	c.items = make(map[string]*list.Element)
+}
+
+// len returns the number of cached entries, expired ones included
+func (c *lru) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+func (c *lru) removeElement(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.items, elem.Value.(*entry).shortID)
+}
@@ -0,0 +1,88 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yasin-yalcin-dev/go-url-shortener/internal/model"
+)
+
+func TestLRU_Eviction(t *testing.T) {
+	c := newLRU(2)
+	expiresAt := time.Now().Add(time.Hour)
+
+	c.add("a", &model.Link{ShortID: "a"}, expiresAt, c.currentGeneration())
+	c.add("b", &model.Link{ShortID: "b"}, expiresAt, c.currentGeneration())
+	c.get("a") // b is now the least recently used
+	c.add("c", &model.Link{ShortID: "c"}, expiresAt, c.currentGeneration())
+
+	testCases := []struct {
+		shortID  string
+		expected bool
+	}{
+		{"a", true},
+		{"b", false},
+		{"c", true},
+	}
+	for _, tc := range testCases {
+		if _, ok := c.get(tc.shortID); ok != tc.expected {
+			t.Errorf("Expected %s cached to be %v", tc.shortID, tc.expected)
+		}
+	}
+	if c.len() != 2 {
+		t.Errorf("Expected 2 entries, got %d", c.len())
+	}
+}
+
+func TestLRU_Expiry(t *testing.T) {
+	c := newLRU(10)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	c.add("a", &model.Link{ShortID: "a"}, now.Add(time.Minute), c.currentGeneration())
+	if _, ok := c.get("a"); !ok {
+		t.Fatal("Expected a to be cached")
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok := c.get("a"); ok {
+		t.Error("Expected a to expire")
+	}
+	if c.len() != 0 {
+		t.Errorf("Expected the expired entry to be dropped, got %d entries", c.len())
+	}
+
+	// Already expired values are not cached
+	c.add("b", &model.Link{ShortID: "b"}, now, c.currentGeneration())
+	if _, ok := c.get("b"); ok {
+		t.Error("Expected an expired value not to be cached")
+	}
+}
+
+func TestLRU_Generation(t *testing.T) {
+	c := newLRU(10)
+	expiresAt := time.Now().Add(time.Hour)
+
+	// A value read before an invalidation is stale and not cached
+	generation := c.currentGeneration()
+	c.remove("a")
+	c.add("a", &model.Link{ShortID: "a", Original: "https://stale.example.com"}, expiresAt, generation)
+	if _, ok := c.get("a"); ok {
+		t.Error("Expected a value read before the invalidation to be dropped")
+	}
+
+	c.add("a", &model.Link{ShortID: "a"}, expiresAt, c.currentGeneration())
+	c.purge()
+	if _, ok := c.get("a"); ok {
+		t.Error("Expected purge to drop all entries")
+	}
+}
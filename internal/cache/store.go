@@ -0,0 +1,193 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/yasin-yalcin-dev/go-url-shortener/internal/model"
+	"github.com/yasin-yalcin-dev/go-url-shortener/internal/redis"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/metadata"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/metrics"
+)
+
+const (
+	defaultTTL         = time.Minute
+	defaultNegativeTTL = 5 * time.Second
+)
+
+// Store is a read-through cache of link records in front of a URLStore.
+// Only GetLink is cached, GetOriginalURL always reads the wrapped store so
+// short ID collision checks see links created by other replicas. Writes go
+// to the wrapped store and invalidate the link on all replicas.
+type Store struct {
+	redis.URLStore
+
+	links       *lru
+	ttl         time.Duration
+	negativeTTL time.Duration
+	invalidator *Invalidator
+	onError     func(error)
+	metrics     *metrics.Metrics
+}
+
+// Option configures optional Store behaviour
+type Option func(*Store)
+
+// WithTTL sets how long a link is cached at most, links expiring earlier are
+// cached until their expiry
+func WithTTL(ttl time.Duration) Option {
+	return func(s *Store) {
+		s.ttl = ttl
+	}
+}
+
+// WithNegativeTTL sets how long an unknown short ID is cached, 0 disables
+// negative caching
+func WithNegativeTTL(ttl time.Duration) Option {
+	return func(s *Store) {
+		s.negativeTTL = ttl
+	}
+}
+
+// WithInvalidator publishes changed links to other replicas. onError is
+// called when an invalidation could not be published.
+func WithInvalidator(invalidator *Invalidator, onError func(error)) Option {
+	return func(s *Store) {
+		s.invalidator = invalidator
+		s.onError = onError
+	}
+}
+
+// WithMetrics counts cache hits and misses
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(s *Store) {
+		s.metrics = m
+	}
+}
+
+// NewStore creates a cache of at most size links in front of store
+func NewStore(store redis.URLStore, size int, options ...Option) *Store {
+	s := &Store{
+		URLStore:    store,
+		links:       newLRU(size),
+		ttl:         defaultTTL,
+		negativeTTL: defaultNegativeTTL,
+	}
+	for _, opt := range options {
+		opt(s)
+	}
+	return s
+}
+
+// GetLink returns the cached link record, reading it from the wrapped store on a miss
+func (s *Store) GetLink(ctx context.Context, shortID string) (*model.Link, error) {
+	if e, ok := s.links.get(shortID); ok {
+		if e.link == nil {
+			s.metrics.CacheLookup(metrics.CacheNegativeHit)
+			return nil, fmt.Errorf("could not get original URL: %w", redis.ErrURLNotFound)
+		}
+		s.metrics.CacheLookup(metrics.CacheHit)
+		link := *e.link
+		return &link, nil
+	}
+	s.metrics.CacheLookup(metrics.CacheMiss)
+
+	generation := s.links.currentGeneration()
+	link, err := s.URLStore.GetLink(ctx, shortID)
+	if errors.Is(err, redis.ErrURLNotFound) {
+		if s.negativeTTL > 0 {
+			s.links.add(shortID, nil, s.links.now().Add(s.negativeTTL), generation)
+		}
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cached := *link
+	s.links.add(shortID, &cached, s.expiry(link), generation)
+	return link, nil
+}
+
+// expiry returns when a cached link must be read again
+func (s *Store) expiry(link *model.Link) time.Time {
+	expiresAt := s.links.now().Add(s.ttl)
+	if link.ExpiresAt != nil && link.ExpiresAt.Before(expiresAt) {
+		return *link.ExpiresAt
+	}
+	return expiresAt
+}
+
+// SaveShortenedURLWithTTL stores a shortened URL and invalidates its cached record
+func (s *Store) SaveShortenedURLWithTTL(ctx context.Context, shortID, originalURL string, ttl time.Duration) error {
+	if err := s.URLStore.SaveShortenedURLWithTTL(ctx, shortID, originalURL, ttl); err != nil {
+		return err
+	}
+	s.invalidate(ctx, shortID)
+	return nil
+}
+
+// SaveLinkWithTTL stores a link record and invalidates its cached record,
+// including a cached miss of a reused short ID
+func (s *Store) SaveLinkWithTTL(ctx context.Context, link *model.Link, ttl time.Duration) error {
+	if err := s.URLStore.SaveLinkWithTTL(ctx, link, ttl); err != nil {
+		return err
+	}
+	s.invalidate(ctx, link.ShortID)
+	return nil
+}
+
+// SaveLinkMetadata stores the page metadata of a link and invalidates its cached record
+func (s *Store) SaveLinkMetadata(ctx context.Context, shortID string, meta *metadata.Metadata) error {
+	if err := s.URLStore.SaveLinkMetadata(ctx, shortID, meta); err != nil {
+		return err
+	}
+	s.invalidate(ctx, shortID)
+	return nil
+}
+
+// DeleteLink removes a link and invalidates its cached record
+func (s *Store) DeleteLink(ctx context.Context, shortID string) error {
+	if err := s.URLStore.DeleteLink(ctx, shortID); err != nil {
+		return err
+	}
+	s.invalidate(ctx, shortID)
+	return nil
+}
+
+// invalidate drops a link from the local cache and from the caches of other
+// replicas. It runs after the write, so reads started before it are not cached.
+func (s *Store) invalidate(ctx context.Context, shortID string) {
+	s.links.remove(shortID)
+	if s.invalidator == nil {
+		return
+	}
+	if err := s.invalidator.Publish(ctx, shortID); err != nil && s.onError != nil {
+		s.onError(err)
+	}
+}
+
+// Listen applies invalidations published by other replicas until ctx is done.
+// Without an invalidator it returns immediately.
+func (s *Store) Listen(ctx context.Context, onError func(error)) {
+	if s.invalidator == nil {
+		return
+	}
+	s.invalidator.Listen(ctx, s.links.remove, s.links.purge, onError)
+}
+
+// Len returns the number of cached links
+func (s *Store) Len() int {
+	return s.links.len()
+}
@@ -0,0 +1,149 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/yasin-yalcin-dev/go-url-shortener/internal/model"
+	"github.com/yasin-yalcin-dev/go-url-shortener/internal/redis"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/metadata"
+)
+
+// setupStore creates a cache in front of a RedisStore backed by miniredis
+func setupStore(t *testing.T, options ...Option) (*miniredis.Miniredis, *Store) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return mr, NewStore(redis.NewRedisStore(client), 100, options...)
+}
+
+func TestStore_GetLink(t *testing.T) {
+	mr, store := setupStore(t)
+	ctx := context.Background()
+
+	if err := store.SaveLinkWithTTL(ctx, &model.Link{ShortID: "abc123", Original: "https://example.com"}, 0); err != nil {
+		t.Fatalf("Failed to save link: %v", err)
+	}
+
+	link, err := store.GetLink(ctx, "abc123")
+	if err != nil || link.Original != "https://example.com" {
+		t.Fatalf("Expected the stored link, got %+v (%v)", link, err)
+	}
+
+	// Hits do not reach Redis
+	commands := mr.CommandCount()
+	for i := 0; i < 10; i++ {
+		if _, err := store.GetLink(ctx, "abc123"); err != nil {
+			t.Fatalf("GetLink failed: %v", err)
+		}
+	}
+	if mr.CommandCount() != commands {
+		t.Errorf("Expected cached reads, Redis received %d commands", mr.CommandCount()-commands)
+	}
+
+	// Callers get their own copy
+	link.Original = "https://changed.example.com"
+	if cached, _ := store.GetLink(ctx, "abc123"); cached.Original != "https://example.com" {
+		t.Errorf("Expected the cached link to be unchanged, got %s", cached.Original)
+	}
+
+	// Writes invalidate the cached record
+	if err := store.SaveLinkMetadata(ctx, "abc123", &metadata.Metadata{Title: "Example"}); err != nil {
+		t.Fatalf("Failed to save metadata: %v", err)
+	}
+	link, _ = store.GetLink(ctx, "abc123")
+	if link.Metadata == nil || link.Metadata.Title != "Example" {
+		t.Errorf("Expected the updated link, got %+v", link.Metadata)
+	}
+}
+
+func TestStore_DeleteLink(t *testing.T) {
+	_, store := setupStore(t)
+	ctx := context.Background()
+
+	if err := store.SaveLinkWithTTL(ctx, &model.Link{ShortID: "abc123", Original: "https://example.com"}, 0); err != nil {
+		t.Fatalf("Failed to save link: %v", err)
+	}
+	if _, err := store.GetLink(ctx, "abc123"); err != nil {
+		t.Fatalf("GetLink failed: %v", err)
+	}
+
+	if err := store.DeleteLink(ctx, "abc123"); err != nil {
+		t.Fatalf("DeleteLink failed: %v", err)
+	}
+	if _, err := store.GetLink(ctx, "abc123"); !errors.Is(err, redis.ErrURLNotFound) {
+		t.Errorf("Expected the deleted link to be gone, got %v", err)
+	}
+}
+
+func TestStore_NegativeCaching(t *testing.T) {
+	testCases := []struct {
+		name          string
+		negativeTTL   time.Duration
+		expectedReads int
+	}{
+		{name: "Misses Cached", negativeTTL: time.Minute, expectedReads: 1},
+		{name: "Negative Caching Disabled", negativeTTL: 0, expectedReads: 3},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mr, store := setupStore(t, WithNegativeTTL(tc.negativeTTL))
+			ctx := context.Background()
+
+			// The connection handshake is not counted
+			store.GetLink(ctx, "warmup")
+
+			commands := mr.CommandCount()
+			for i := 0; i < 3; i++ {
+				if _, err := store.GetLink(ctx, "missing"); !errors.Is(err, redis.ErrURLNotFound) {
+					t.Fatalf("Expected ErrURLNotFound, got %v", err)
+				}
+			}
+			if reads := mr.CommandCount() - commands; reads != tc.expectedReads {
+				t.Errorf("Expected %d reads, got %d", tc.expectedReads, reads)
+			}
+
+			// A link created under the short ID replaces the cached miss
+			if err := store.SaveLinkWithTTL(ctx, &model.Link{ShortID: "missing", Original: "https://example.com"}, 0); err != nil {
+				t.Fatalf("Failed to save link: %v", err)
+			}
+			if _, err := store.GetLink(ctx, "missing"); err != nil {
+				t.Errorf("Expected the new link, got %v", err)
+			}
+		})
+	}
+}
+
+func TestStore_ExpiryBoundedByLink(t *testing.T) {
+	_, store := setupStore(t, WithTTL(time.Hour))
+	now := time.Now()
+	store.links.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	expiresAt := now.Add(time.Minute)
+	link := &model.Link{ShortID: "abc123", Original: "https://example.com", ExpiresAt: &expiresAt}
+	if err := store.SaveLinkWithTTL(ctx, link, time.Minute); err != nil {
+		t.Fatalf("Failed to save link: %v", err)
+	}
+	store.GetLink(ctx, "abc123")
+
+	now = now.Add(time.Minute)
+	if _, ok := store.links.get("abc123"); ok {
+		t.Error("Expected the link to leave the cache when it expires")
+	}
+}
@@ -13,14 +13,28 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/privacy"
+)
+
+// Redis deployment modes
+const (
+	RedisModeStandalone = "standalone" // A single Redis server
+	RedisModeSentinel   = "sentinel"   // A master monitored by Redis Sentinel
+	RedisModeCluster    = "cluster"    // A Redis Cluster
 )
 
 // RedisConfig represents the configuration for Redis connection
 type RedisConfig struct {
-	Address         string        // Redis server address (host:port)
+	Mode             string   // See RedisModeStandalone, RedisModeSentinel and RedisModeCluster
+	Address          string   // Redis server address (host:port)
+	Addresses        []string // Sentinel addresses, or cluster seed nodes
+	MasterName       string   // Name of the master monitored by Sentinel
+	SentinelPassword string   // Sentinel authentication password
+
 	Password        string        // Redis authentication password
 	DB              int           // Database number to use
 	PoolSize        int           // Connection pool size
@@ -31,15 +45,106 @@ type RedisConfig struct {
 	MaxRetries      int           // Maximum number of retries
 	MinRetryBackoff time.Duration // Minimum backoff time between retries
 	MaxRetryBackoff time.Duration // Maximum backoff time between retries
+
+	HealthCheckInterval time.Duration // Time between connection health checks
+	HealthCheckFailures int           // Failed health checks in a row before reconnecting
+	ReconnectMaxBackoff time.Duration // Maximum delay between reconnection attempts
+}
+
+// AnalyticsConfig represents the configuration for asynchronous analytics ingestion
+type AnalyticsConfig struct {
+	QueueSize      int           // Maximum number of buffered access events
+	Workers        int           // Number of batch writer goroutines
+	BatchSize      int           // Maximum number of events written in one Redis pipeline
+	FlushInterval  time.Duration // Maximum time an event waits in a partial batch
+	EnqueueTimeout time.Duration // Time to wait for queue space before dropping an event
+	StreamEnabled  bool          // Append raw access events to a Redis Stream
+	StreamName     string        // Redis Stream key for access events
+	StreamMaxLen   int           // Approximate maximum number of events kept in the stream
+	IPHashSalt     string        // Secret key used when hashing client IPs for the stream
+	BotPatternFile string        // File with bot User-Agent patterns, built-in list when empty
+	BotReload      time.Duration // Interval for checking the bot pattern file for changes
+	IPMode         string        // Client IP anonymization: none, truncate or hash
+	IPHashSecret   string        // Secret the daily IP hash salts are derived from
+	Retention      time.Duration // Expire analytics after this long without access (0 keeps them)
+}
+
+// PasswordConfig represents the throttling of password-protected link unlocks
+type PasswordConfig struct {
+	Attempts       int           // Password attempts allowed per client and link within Window
+	LinkAttempts   int           // Password attempts allowed per link from all clients within Window
+	Window         time.Duration // Time in which the attempts are refilled
+	TrustedProxies []string      // Proxies whose X-Forwarded-For header identifies the client
+}
+
+// MetadataConfig represents fetching the page metadata of new links
+type MetadataConfig struct {
+	Enabled     bool          // Fetch title, description and favicon after a link is created
+	Timeout     time.Duration // Time allowed for a whole fetch including redirects
+	MaxBodySize int64         // Bytes of a page read at most
+}
+
+// LogConfig represents the format, outputs and file rotation of logs
+type LogConfig struct {
+	Encoding           string   // json or console
+	Outputs            []string // stdout, stderr or file paths
+	SamplingInitial    int      // Entries per second and message kept before sampling, 0 disables sampling
+	SamplingThereafter int      // Every Nth entry kept once sampling started
+	MaxSizeMB          int      // Size in megabytes at which a log file is rotated
+	MaxAgeDays         int      // Days rotated log files are kept
+	MaxBackups         int      // Rotated log files kept
+	Compress           bool     // Gzip rotated log files
+	RedactQuery        bool     // Mask query values of logged URLs
+	RedactCredentials  bool     // Mask passwords and credential-like query parameters of logged URLs
+	RedactIPMode       string   // Client IPs in logs: none, truncate or hash
+}
+
+// CacheConfig represents the in-process cache of link records
+type CacheConfig struct {
+	Enabled     bool          // Cache link records in front of Redis
+	Size        int           // Maximum number of cached links
+	TTL         time.Duration // Time a link is cached at most
+	NegativeTTL time.Duration // Time an unknown short ID is cached, 0 disables negative caching
+	Channel     string        // Redis pub/sub channel for invalidations between replicas
+}
+
+// AuditConfig represents the audit log of link mutations
+type AuditConfig struct {
+	Enabled     bool   // Record link mutations
+	StreamName  string // Redis Stream key of the audit log
+	MaxLen      int64  // Approximate maximum stream length, 0 keeps every event
+	ActorHeader string // Header naming the actor, set by an authenticating gateway
+	IPMode      string // Source IP anonymization: none or truncate
+}
+
+// TracingConfig represents the export of OpenTelemetry spans
+type TracingConfig struct {
+	Exporter     string  // none, stdout or otlp
+	OTLPEndpoint string  // host:port of the OTLP/HTTP collector
+	ServiceName  string  // service.name reported with every span
+	SampleRatio  float64 // Fraction of new traces that are sampled
 }
 
 // Config holds the overall application configuration
 type Config struct {
-	RedisConfig   *RedisConfig
-	ServerPort    string
-	BaseURL       string
-	LogLevel      string
-	DefaultURLTTL time.Duration
+	RedisConfig     *RedisConfig
+	AnalyticsConfig *AnalyticsConfig
+	ServerPort      string
+	BaseURL         string
+	LogLevel        string
+	DefaultURLTTL   time.Duration
+	ShutdownTimeout time.Duration
+	// Time the server reports not ready before it stops accepting requests
+	ShutdownDrainDelay time.Duration
+	ReadinessTimeout   time.Duration // Time allowed for all readiness checks
+	PasswordConfig     *PasswordConfig
+	GeoIPFile          string // CSV country database for targeting rules and analytics
+	MetadataConfig     *MetadataConfig
+	TracingConfig      *TracingConfig
+	CacheConfig        *CacheConfig
+	LogConfig          *LogConfig
+	AuditConfig        *AuditConfig
+	AdminToken         string // Bearer token of the admin endpoints, which are disabled when empty
 }
 
 // Load Loads the .env file and environment variables
@@ -48,11 +153,61 @@ func Load() (*Config, error) {
 	godotenv.Load()
 
 	cfg := &Config{
-		RedisConfig:   defaultRedisConfig(),
-		ServerPort:    getEnv("SERVER_PORT", "8080"),
-		BaseURL:       getEnv("BASE_URL", "http://localhost:8080"),
-		LogLevel:      getEnv("LOG_LEVEL", "info"),
-		DefaultURLTTL: getDurationEnv("DEFAULT_URL_TTL", 24*time.Hour),
+		RedisConfig:        defaultRedisConfig(),
+		AnalyticsConfig:    defaultAnalyticsConfig(),
+		ServerPort:         getEnv("SERVER_PORT", "8080"),
+		BaseURL:            getEnv("BASE_URL", "http://localhost:8080"),
+		LogLevel:           getEnv("LOG_LEVEL", "info"),
+		DefaultURLTTL:      getDurationEnv("DEFAULT_URL_TTL", 24*time.Hour),
+		ShutdownTimeout:    getEnvAsDuration("SHUTDOWN_TIMEOUT", 15*time.Second),
+		ShutdownDrainDelay: getEnvAsDuration("SHUTDOWN_DRAIN_DELAY", 0),
+		ReadinessTimeout:   getEnvAsDuration("READINESS_TIMEOUT", 2*time.Second),
+		GeoIPFile:          getEnv("GEOIP_DB_FILE", ""),
+		PasswordConfig: &PasswordConfig{
+			Attempts:       getEnvAsInt("LINK_PASSWORD_ATTEMPTS", 5),
+			LinkAttempts:   getEnvAsInt("LINK_PASSWORD_LINK_ATTEMPTS", 50),
+			Window:         getEnvAsDuration("LINK_PASSWORD_WINDOW", time.Minute),
+			TrustedProxies: getEnvAsSlice("TRUSTED_PROXIES", nil),
+		},
+		MetadataConfig: &MetadataConfig{
+			Enabled:     getEnvAsBool("METADATA_FETCH_ENABLED", true),
+			Timeout:     getEnvAsDuration("METADATA_FETCH_TIMEOUT", 5*time.Second),
+			MaxBodySize: int64(getEnvAsInt("METADATA_MAX_BODY", 512<<10)),
+		},
+		LogConfig: &LogConfig{
+			Encoding:           getEnv("LOG_ENCODING", "json"),
+			Outputs:            getEnvAsSlice("LOG_OUTPUTS", []string{"stdout", "./logs/app.log"}),
+			SamplingInitial:    getEnvAsInt("LOG_SAMPLING_INITIAL", 0),
+			SamplingThereafter: getEnvAsInt("LOG_SAMPLING_THEREAFTER", 100),
+			MaxSizeMB:          getEnvAsInt("LOG_FILE_MAX_SIZE_MB", 100),
+			MaxAgeDays:         getEnvAsInt("LOG_FILE_MAX_AGE_DAYS", 28),
+			MaxBackups:         getEnvAsInt("LOG_FILE_MAX_BACKUPS", 5),
+			Compress:           getEnvAsBool("LOG_FILE_COMPRESS", false),
+			RedactQuery:        getEnvAsBool("LOG_REDACT_QUERY", true),
+			RedactCredentials:  getEnvAsBool("LOG_REDACT_CREDENTIALS", true),
+			RedactIPMode:       getEnv("LOG_REDACT_IP", "truncate"),
+		},
+		AdminToken: getEnv("ADMIN_TOKEN", ""),
+		CacheConfig: &CacheConfig{
+			Enabled:     getEnvAsBool("LINK_CACHE_ENABLED", true),
+			Size:        getEnvAsInt("LINK_CACHE_SIZE", 10000),
+			TTL:         getEnvAsDuration("LINK_CACHE_TTL", time.Minute),
+			NegativeTTL: getEnvAsDuration("LINK_CACHE_NEGATIVE_TTL", 5*time.Second),
+			Channel:     getEnv("LINK_CACHE_CHANNEL", "links:invalidate"),
+		},
+		AuditConfig: &AuditConfig{
+			Enabled:     getEnvAsBool("AUDIT_ENABLED", true),
+			StreamName:  getEnv("AUDIT_STREAM_NAME", "audit:links"),
+			MaxLen:      int64(getEnvAsInt("AUDIT_STREAM_MAXLEN", 100000)),
+			ActorHeader: getEnv("AUDIT_ACTOR_HEADER", ""),
+			IPMode:      getEnv("AUDIT_IP_MODE", "truncate"),
+		},
+		TracingConfig: &TracingConfig{
+			Exporter:     getEnv("OTEL_TRACES_EXPORTER", "none"),
+			OTLPEndpoint: getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", ""),
+			ServiceName:  getEnv("OTEL_SERVICE_NAME", "go-url-shortener"),
+			SampleRatio:  getEnvAsFloat("OTEL_TRACES_SAMPLER_ARG", 1),
+		},
 	}
 	// verify configuration
 	if err := validate(cfg); err != nil {
@@ -65,7 +220,12 @@ func Load() (*Config, error) {
 // defaultRedisConfig creates default Redis configuration values
 func defaultRedisConfig() *RedisConfig {
 	return &RedisConfig{
-		Address:         getEnv("REDIS_ADDR", "localhost:6379"),
+		Mode:             getEnv("REDIS_MODE", RedisModeStandalone),
+		Address:          getEnv("REDIS_ADDR", "localhost:6379"),
+		Addresses:        getEnvAsSlice("REDIS_ADDRS", nil),
+		MasterName:       getEnv("REDIS_MASTER_NAME", ""),
+		SentinelPassword: getEnv("REDIS_SENTINEL_PASSWORD", ""),
+
 		Password:        getEnv("REDIS_PASSWORD", ""),
 		DB:              getEnvAsInt("REDIS_DB", 0),
 		PoolSize:        getEnvAsInt("REDIS_POOL_SIZE", 10),
@@ -76,14 +236,174 @@ func defaultRedisConfig() *RedisConfig {
 		MaxRetries:      getEnvAsInt("REDIS_MAX_RETRIES", 3),
 		MinRetryBackoff: getEnvAsDuration("REDIS_MIN_RETRY_BACKOFF", 300*time.Millisecond),
 		MaxRetryBackoff: getEnvAsDuration("REDIS_MAX_RETRY_BACKOFF", 2*time.Second),
+
+		HealthCheckInterval: getEnvAsDuration("REDIS_HEALTH_CHECK_INTERVAL", 5*time.Second),
+		HealthCheckFailures: getEnvAsInt("REDIS_HEALTH_CHECK_FAILURES", 3),
+		ReconnectMaxBackoff: getEnvAsDuration("REDIS_RECONNECT_MAX_BACKOFF", 30*time.Second),
+	}
+}
+
+// defaultAnalyticsConfig creates default analytics ingestion configuration values
+func defaultAnalyticsConfig() *AnalyticsConfig {
+	return &AnalyticsConfig{
+		QueueSize:      getEnvAsInt("ANALYTICS_QUEUE_SIZE", 10000),
+		Workers:        getEnvAsInt("ANALYTICS_WORKERS", 4),
+		BatchSize:      getEnvAsInt("ANALYTICS_BATCH_SIZE", 100),
+		FlushInterval:  getEnvAsDuration("ANALYTICS_FLUSH_INTERVAL", time.Second),
+		EnqueueTimeout: getEnvAsDuration("ANALYTICS_ENQUEUE_TIMEOUT", 0),
+		StreamEnabled:  getEnvAsBool("ANALYTICS_STREAM_ENABLED", false),
+		StreamName:     getEnv("ANALYTICS_STREAM_NAME", "analytics:events"),
+		StreamMaxLen:   getEnvAsInt("ANALYTICS_STREAM_MAXLEN", 1000000),
+		IPHashSalt:     getEnv("ANALYTICS_IP_HASH_SALT", ""),
+		BotPatternFile: getEnv("BOT_PATTERN_FILE", ""),
+		BotReload:      getEnvAsDuration("BOT_PATTERN_RELOAD_INTERVAL", 30*time.Second),
+		IPMode:         getEnv("ANALYTICS_IP_MODE", "hash"),
+		IPHashSecret:   getEnv("ANALYTICS_IP_HASH_SECRET", ""),
+		Retention:      getEnvAsDuration("ANALYTICS_RETENTION", 0),
 	}
 }
 
 // validate checks if the configuration is valid
 func validate(cfg *Config) error {
-	// Validate Redis address
-	if cfg.RedisConfig.Address == "" {
-		return fmt.Errorf("REDIS_ADDR is required")
+	// Validate Redis addresses of the selected mode
+	switch cfg.RedisConfig.Mode {
+	case RedisModeStandalone, "":
+		if cfg.RedisConfig.Address == "" {
+			return fmt.Errorf("REDIS_ADDR is required")
+		}
+	case RedisModeSentinel:
+		if len(cfg.RedisConfig.Addresses) == 0 {
+			return fmt.Errorf("REDIS_ADDRS is required in sentinel mode")
+		}
+		if cfg.RedisConfig.MasterName == "" {
+			return fmt.Errorf("REDIS_MASTER_NAME is required in sentinel mode")
+		}
+	case RedisModeCluster:
+		if len(cfg.RedisConfig.Addresses) == 0 && cfg.RedisConfig.Address == "" {
+			return fmt.Errorf("REDIS_ADDRS is required in cluster mode")
+		}
+		if cfg.RedisConfig.DB != 0 {
+			return fmt.Errorf("REDIS_DB must be 0 in cluster mode")
+		}
+	default:
+		return fmt.Errorf("REDIS_MODE must be standalone, sentinel or cluster")
+	}
+
+	// Validate password throttling
+	if cfg.PasswordConfig != nil {
+		if cfg.PasswordConfig.Attempts <= 0 {
+			return fmt.Errorf("LINK_PASSWORD_ATTEMPTS must be positive")
+		}
+		if cfg.PasswordConfig.LinkAttempts <= 0 {
+			return fmt.Errorf("LINK_PASSWORD_LINK_ATTEMPTS must be positive")
+		}
+		if cfg.PasswordConfig.Window <= 0 {
+			return fmt.Errorf("LINK_PASSWORD_WINDOW must be positive")
+		}
+		if _, err := privacy.ParseProxies(cfg.PasswordConfig.TrustedProxies); err != nil {
+			return fmt.Errorf("TRUSTED_PROXIES: %v", err)
+		}
+	}
+
+	// Validate metadata fetching
+	if cfg.MetadataConfig != nil && cfg.MetadataConfig.Enabled {
+		if cfg.MetadataConfig.Timeout <= 0 {
+			return fmt.Errorf("METADATA_FETCH_TIMEOUT must be positive")
+		}
+		if cfg.MetadataConfig.MaxBodySize <= 0 {
+			return fmt.Errorf("METADATA_MAX_BODY must be positive")
+		}
+	}
+
+	// Validate logging
+	if cfg.LogConfig != nil {
+		if cfg.LogConfig.Encoding != "json" && cfg.LogConfig.Encoding != "console" {
+			return fmt.Errorf("LOG_ENCODING must be json or console")
+		}
+		if len(cfg.LogConfig.Outputs) == 0 {
+			return fmt.Errorf("LOG_OUTPUTS is required")
+		}
+		if cfg.LogConfig.SamplingInitial < 0 || cfg.LogConfig.SamplingThereafter < 0 {
+			return fmt.Errorf("LOG_SAMPLING_INITIAL and LOG_SAMPLING_THEREAFTER must not be negative")
+		}
+		switch cfg.LogConfig.RedactIPMode {
+		case "none", "truncate", "hash":
+		default:
+			return fmt.Errorf("LOG_REDACT_IP must be none, truncate or hash")
+		}
+	}
+
+	// Validate the link cache
+	if cfg.CacheConfig != nil && cfg.CacheConfig.Enabled {
+		if cfg.CacheConfig.Size <= 0 {
+			return fmt.Errorf("LINK_CACHE_SIZE must be positive")
+		}
+		if cfg.CacheConfig.TTL <= 0 {
+			return fmt.Errorf("LINK_CACHE_TTL must be positive")
+		}
+		if cfg.CacheConfig.NegativeTTL < 0 {
+			return fmt.Errorf("LINK_CACHE_NEGATIVE_TTL must not be negative")
+		}
+		if cfg.CacheConfig.Channel == "" {
+			return fmt.Errorf("LINK_CACHE_CHANNEL is required")
+		}
+	}
+
+	// Validate the audit log
+	if cfg.AuditConfig != nil && cfg.AuditConfig.Enabled {
+		if cfg.AuditConfig.StreamName == "" {
+			return fmt.Errorf("AUDIT_STREAM_NAME is required")
+		}
+		if cfg.AuditConfig.MaxLen < 0 {
+			return fmt.Errorf("AUDIT_STREAM_MAXLEN cannot be negative")
+		}
+		switch cfg.AuditConfig.IPMode {
+		case "none", "truncate":
+		default:
+			return fmt.Errorf("AUDIT_IP_MODE must be none or truncate")
+		}
+	}
+
+	// Validate tracing
+	if cfg.TracingConfig != nil {
+		switch cfg.TracingConfig.Exporter {
+		case "none", "stdout", "otlp":
+		default:
+			return fmt.Errorf("OTEL_TRACES_EXPORTER must be none, stdout or otlp")
+		}
+		if cfg.TracingConfig.SampleRatio < 0 || cfg.TracingConfig.SampleRatio > 1 {
+			return fmt.Errorf("OTEL_TRACES_SAMPLER_ARG must be between 0 and 1")
+		}
+	}
+
+	// Validate analytics pipeline
+	if cfg.AnalyticsConfig != nil {
+		if cfg.AnalyticsConfig.QueueSize <= 0 {
+			return fmt.Errorf("ANALYTICS_QUEUE_SIZE must be positive")
+		}
+		if cfg.AnalyticsConfig.Workers <= 0 {
+			return fmt.Errorf("ANALYTICS_WORKERS must be positive")
+		}
+		if cfg.AnalyticsConfig.BatchSize <= 0 {
+			return fmt.Errorf("ANALYTICS_BATCH_SIZE must be positive")
+		}
+		if cfg.AnalyticsConfig.StreamMaxLen < 0 {
+			return fmt.Errorf("ANALYTICS_STREAM_MAXLEN cannot be negative")
+		}
+		if cfg.AnalyticsConfig.StreamEnabled && cfg.AnalyticsConfig.IPHashSalt == "" {
+			return fmt.Errorf("ANALYTICS_IP_HASH_SALT is required when the event stream is enabled")
+		}
+		switch cfg.AnalyticsConfig.IPMode {
+		case "none", "truncate", "hash":
+		default:
+			return fmt.Errorf("ANALYTICS_IP_MODE must be none, truncate or hash")
+		}
+		if cfg.AnalyticsConfig.Retention < 0 {
+			return fmt.Errorf("ANALYTICS_RETENTION cannot be negative")
+		}
+		if cfg.AnalyticsConfig.BotPatternFile != "" && cfg.AnalyticsConfig.BotReload <= 0 {
+			return fmt.Errorf("BOT_PATTERN_RELOAD_INTERVAL must be positive")
+		}
 	}
 
 	// Validate server port
@@ -121,6 +441,52 @@ func getEnvAsInt(key string, defaultValue int) int {
 	return value
 }
 
+// getEnvAsBool converts environment variable to bool
+func getEnvAsBool(key string, defaultValue bool) bool {
+	valueStr := getEnv(key, "")
+	if valueStr == "" {
+		return defaultValue
+	}
+
+	value, err := strconv.ParseBool(valueStr)
+	if err != nil {
+		return defaultValue
+	}
+
+	return value
+}
+
+// getEnvAsFloat converts environment variable to float64
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	valueStr := getEnv(key, "")
+	if valueStr == "" {
+		return defaultValue
+	}
+
+	value, err := strconv.ParseFloat(valueStr, 64)
+	if err != nil {
+		return defaultValue
+	}
+
+	return value
+}
+
+// getEnvAsSlice splits a comma separated environment variable
+func getEnvAsSlice(key string, defaultValue []string) []string {
+	valueStr := getEnv(key, "")
+	if valueStr == "" {
+		return defaultValue
+	}
+
+	var values []string
+	for _, value := range strings.Split(valueStr, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
 // getEnvAsDuration converts environment variable to time.Duration
 func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
 	valueStr := getEnv(key, "")
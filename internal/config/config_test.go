@@ -182,6 +182,142 @@ func TestValidate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "Valid Sentinel Configuration",
+			config: &Config{
+				RedisConfig: &RedisConfig{
+					Mode:       RedisModeSentinel,
+					Addresses:  []string{"sentinel-1:26379", "sentinel-2:26379"},
+					MasterName: "mymaster",
+				},
+				ServerPort: "8080",
+				BaseURL:    "http://localhost:8080",
+			},
+			wantErr: false,
+		},
+		{
+			name: "Sentinel Without Master Name",
+			config: &Config{
+				RedisConfig: &RedisConfig{Mode: RedisModeSentinel, Addresses: []string{"sentinel-1:26379"}},
+				ServerPort:  "8080",
+				BaseURL:     "http://localhost:8080",
+			},
+			wantErr: true,
+		},
+		{
+			name: "Cluster With Database",
+			config: &Config{
+				RedisConfig: &RedisConfig{Mode: RedisModeCluster, Addresses: []string{"node-1:6379"}, DB: 2},
+				ServerPort:  "8080",
+				BaseURL:     "http://localhost:8080",
+			},
+			wantErr: true,
+		},
+		{
+			name: "Unknown Redis Mode",
+			config: &Config{
+				RedisConfig: &RedisConfig{Mode: "replica", Address: "localhost:6379"},
+				ServerPort:  "8080",
+				BaseURL:     "http://localhost:8080",
+			},
+			wantErr: true,
+		},
+		{
+			name: "Empty Link Cache",
+			config: &Config{
+				RedisConfig: &RedisConfig{Address: "localhost:6379"},
+				ServerPort:  "8080",
+				BaseURL:     "http://localhost:8080",
+				CacheConfig: &CacheConfig{Enabled: true, Size: 0, TTL: time.Minute, Channel: "links:invalidate"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Unknown Log Encoding",
+			config: &Config{
+				RedisConfig: &RedisConfig{Address: "localhost:6379"},
+				ServerPort:  "8080",
+				BaseURL:     "http://localhost:8080",
+				LogConfig:   &LogConfig{Encoding: "logfmt", Outputs: []string{"stdout"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Negative Audit Stream Length",
+			config: &Config{
+				RedisConfig: &RedisConfig{Address: "localhost:6379"},
+				ServerPort:  "8080",
+				BaseURL:     "http://localhost:8080",
+				AuditConfig: &AuditConfig{Enabled: true, StreamName: "audit:links", MaxLen: -1},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Unknown Audit IP Mode",
+			config: &Config{
+				RedisConfig: &RedisConfig{Address: "localhost:6379"},
+				ServerPort:  "8080",
+				BaseURL:     "http://localhost:8080",
+				AuditConfig: &AuditConfig{Enabled: true, StreamName: "audit:links", MaxLen: 100000, IPMode: "hash"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Unknown Log IP Redaction",
+			config: &Config{
+				RedisConfig: &RedisConfig{Address: "localhost:6379"},
+				ServerPort:  "8080",
+				BaseURL:     "http://localhost:8080",
+				LogConfig:   &LogConfig{Encoding: "json", Outputs: []string{"stdout"}, RedactIPMode: "mask"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Event Stream Without IP Hash Salt",
+			config: &Config{
+				RedisConfig: &RedisConfig{Address: "localhost:6379"},
+				ServerPort:  "8080",
+				BaseURL:     "http://localhost:8080",
+				AnalyticsConfig: &AnalyticsConfig{
+					QueueSize: 100, Workers: 1, BatchSize: 10, IPMode: "hash",
+					StreamEnabled: true, StreamName: "analytics:events",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Bot Pattern Reload Disabled",
+			config: &Config{
+				RedisConfig: &RedisConfig{Address: "localhost:6379"},
+				ServerPort:  "8080",
+				BaseURL:     "http://localhost:8080",
+				AnalyticsConfig: &AnalyticsConfig{
+					QueueSize: 100, Workers: 1, BatchSize: 10, IPMode: "hash",
+					BotPatternFile: "bots.txt", BotReload: 0,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Unknown Trace Exporter",
+			config: &Config{
+				RedisConfig:   &RedisConfig{Address: "localhost:6379"},
+				ServerPort:    "8080",
+				BaseURL:       "http://localhost:8080",
+				TracingConfig: &TracingConfig{Exporter: "zipkin", SampleRatio: 1},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Invalid Sample Ratio",
+			config: &Config{
+				RedisConfig:   &RedisConfig{Address: "localhost:6379"},
+				ServerPort:    "8080",
+				BaseURL:       "http://localhost:8080",
+				TracingConfig: &TracingConfig{Exporter: "otlp", SampleRatio: 1.5},
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -0,0 +1,38 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package handler
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	customerrors "github.com/yasin-yalcin-dev/go-url-shortener/pkg/errors"
+)
+
+// RequireToken rejects requests without the bearer token, it guards the
+// admin endpoints. An empty token rejects every request.
+func RequireToken(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || token == "" || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+				customerrors.New(
+					http.StatusUnauthorized,
+					"Unauthorized",
+					"A valid admin token is required",
+				).WriteResponse(w)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,165 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/logger"
+	"go.uber.org/zap"
+)
+
+func TestRequireToken_LogLevel(t *testing.T) {
+	appLogger, err := logger.NewTestLogger()
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	handler := RequireToken("secret")(appLogger.LevelHandler())
+
+	testCases := []struct {
+		name               string
+		authorization      string
+		expectedStatusCode int
+		expectDebug        bool
+	}{
+		{name: "Missing Token", expectedStatusCode: http.StatusUnauthorized},
+		{name: "Wrong Token", authorization: "Bearer guess", expectedStatusCode: http.StatusUnauthorized},
+		{name: "Basic Auth", authorization: "Basic c2VjcmV0", expectedStatusCode: http.StatusUnauthorized},
+		{name: "Valid Token", authorization: "Bearer secret", expectedStatusCode: http.StatusOK, expectDebug: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/admin/log-level", strings.NewReader(`{"level":"debug"}`))
+			req.Header.Set("Content-Type", "application/json")
+			if tc.authorization != "" {
+				req.Header.Set("Authorization", tc.authorization)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tc.expectedStatusCode {
+				t.Errorf("Expected status %d, got %d", tc.expectedStatusCode, w.Code)
+			}
+			if enabled := appLogger.Core().Enabled(zap.DebugLevel); enabled != tc.expectDebug {
+				t.Errorf("Expected debug logging enabled to be %v", tc.expectDebug)
+			}
+		})
+	}
+
+	// An empty token never authorizes
+	req := httptest.NewRequest(http.MethodGet, "/admin/log-level", nil)
+	req.Header.Set("Authorization", "Bearer ")
+	w := httptest.NewRecorder()
+	RequireToken("")(appLogger.LevelHandler()).ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected an empty token to be rejected, got %d", w.Code)
+	}
+}
+
+func TestRequireToken_PurgeURLAnalytics(t *testing.T) {
+	appLogger, err := logger.NewTestLogger()
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	store := &mockPurgeStore{}
+	shortenHandler := &ShortenHandler{Service: &mockURLService{}, Logger: appLogger, Analytics: store}
+
+	// Routes as registered in main
+	r := chi.NewRouter()
+	r.Get("/{shortened}/analytics", shortenHandler.GetURLAnalytics)
+	r.Group(func(r chi.Router) {
+		r.Use(RequireToken("secret"))
+		r.Delete("/admin/links/{shortened}/analytics", shortenHandler.PurgeURLAnalytics)
+	})
+
+	testCases := []struct {
+		name               string
+		path               string
+		authorization      string
+		expectedStatusCode int
+		expectedPurges     int
+	}{
+		{name: "Missing Token", path: "/admin/links/abc123/analytics", expectedStatusCode: http.StatusUnauthorized},
+		{name: "Wrong Token", path: "/admin/links/abc123/analytics", authorization: "Bearer guess", expectedStatusCode: http.StatusUnauthorized},
+		{name: "Public Path", path: "/abc123/analytics", expectedStatusCode: http.StatusMethodNotAllowed},
+		{name: "Valid Token", path: "/admin/links/abc123/analytics", authorization: "Bearer secret", expectedStatusCode: http.StatusNoContent, expectedPurges: 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store.purged = 0
+
+			req := httptest.NewRequest(http.MethodDelete, tc.path, nil)
+			if tc.authorization != "" {
+				req.Header.Set("Authorization", tc.authorization)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tc.expectedStatusCode {
+				t.Errorf("Expected status %d, got %d", tc.expectedStatusCode, w.Code)
+			}
+			if store.purged != tc.expectedPurges {
+				t.Errorf("Expected %d purges, got %d", tc.expectedPurges, store.purged)
+			}
+		})
+	}
+}
+
+func TestRequireToken_TopLinks(t *testing.T) {
+	appLogger, err := logger.NewTestLogger()
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	shortenHandler := &ShortenHandler{Logger: appLogger, Analytics: &mockLeaderboardStore{}}
+
+	// Routes as registered in main
+	r := chi.NewRouter()
+	r.Get("/{shortened}", shortenHandler.Redirect)
+	r.Group(func(r chi.Router) {
+		r.Use(RequireToken("secret"))
+		r.Get("/admin/analytics/top", shortenHandler.TopLinks)
+	})
+
+	testCases := []struct {
+		name               string
+		authorization      string
+		expectedStatusCode int
+	}{
+		{name: "Missing Token", expectedStatusCode: http.StatusUnauthorized},
+		{name: "Wrong Token", authorization: "Bearer guess", expectedStatusCode: http.StatusUnauthorized},
+		{name: "Valid Token", authorization: "Bearer secret", expectedStatusCode: http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin/analytics/top", nil)
+			if tc.authorization != "" {
+				req.Header.Set("Authorization", tc.authorization)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tc.expectedStatusCode {
+				t.Errorf("Expected status %d, got %d", tc.expectedStatusCode, w.Code)
+			}
+			if tc.expectedStatusCode != http.StatusOK && strings.Contains(w.Body.String(), "abc123") {
+				t.Errorf("Expected no short IDs without a token, got %s", w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yasin-yalcin-dev/go-url-shortener/internal/audit"
+	customerrors "github.com/yasin-yalcin-dev/go-url-shortener/pkg/errors"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/logger"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/tracing"
+
+	"go.uber.org/zap"
+)
+
+// streamIDPattern matches Redis Stream entry IDs used as cursors
+var streamIDPattern = regexp.MustCompile(`^\d+-\d+$`)
+
+// AuditQuerier reads recorded link mutations
+type AuditQuerier interface {
+	Query(ctx context.Context, filter audit.Filter) ([]audit.Event, string, error)
+}
+
+// AuditHandler serves the audit log of link mutations
+type AuditHandler struct {
+	Log    AuditQuerier
+	Logger *logger.Logger
+}
+
+// auditResponse is a page of audit events
+type auditResponse struct {
+	Events []audit.Event `json:"events"`
+	Next   string        `json:"next,omitempty"` // Cursor of the next page
+}
+
+// Query returns audit events, oldest first. Query parameters: link, actor,
+// from and to (RFC 3339 or YYYY-MM-DD), limit and cursor (next of the
+// previous page).
+func (h *AuditHandler) Query(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "AuditHandler.Query")
+	defer span.End()
+	r = r.WithContext(ctx)
+
+	query := r.URL.Query()
+	filter := audit.Filter{
+		ShortID: strings.TrimSpace(query.Get("link")),
+		Actor:   strings.TrimSpace(query.Get("actor")),
+		After:   query.Get("cursor"),
+	}
+
+	// parseTime parses an optional time parameter, writing the error response
+	parseTime := func(name string, parse func(string) (time.Time, error)) (time.Time, bool) {
+		value := query.Get(name)
+		if value == "" {
+			return time.Time{}, true
+		}
+		parsed, err := parse(value)
+		if err != nil {
+			customerrors.New(
+				http.StatusBadRequest,
+				fmt.Sprintf("Invalid '%s' parameter", name),
+				"Use an RFC 3339 timestamp or a YYYY-MM-DD date",
+			).WriteResponse(w)
+			return time.Time{}, false
+		}
+		return parsed, true
+	}
+	var ok bool
+	if filter.From, ok = parseTime("from", parseExportTime); !ok {
+		return
+	}
+	if filter.To, ok = parseTime("to", parseExportEnd); !ok {
+		return
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+		customerrors.New(
+			http.StatusBadRequest,
+			"Invalid audit range",
+			"'from' must be before 'to'",
+		).WriteResponse(w)
+		return
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		parsed, err := strconv.Atoi(limit)
+		if err != nil || parsed <= 0 || parsed > audit.MaxLimit {
+			customerrors.New(
+				http.StatusBadRequest,
+				"Invalid 'limit' parameter",
+				fmt.Sprintf("Limit must be between 1 and %d", audit.MaxLimit),
+			).WriteResponse(w)
+			return
+		}
+		filter.Limit = parsed
+	}
+	if filter.After != "" && !streamIDPattern.MatchString(filter.After) {
+		customerrors.New(
+			http.StatusBadRequest,
+			"Invalid 'cursor' parameter",
+			"Use the next cursor of the previous page",
+		).WriteResponse(w)
+		return
+	}
+
+	events, next, err := h.Log.Query(r.Context(), filter)
+	if err != nil {
+		h.Logger.FromContext(r.Context()).Error("Failed to query audit log",
+			zap.Error(err),
+			zap.String("shortID", filter.ShortID),
+		)
+		customerrors.ErrInternal.WriteResponse(w)
+		return
+	}
+	if events == nil {
+		events = []audit.Event{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(auditResponse{Events: events, Next: next})
+}
@@ -0,0 +1,138 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/yasin-yalcin-dev/go-url-shortener/internal/audit"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/logger"
+)
+
+// mockAuditLog records the filter of the last query
+type mockAuditLog struct {
+	filter audit.Filter
+	events []audit.Event
+	next   string
+	err    error
+}
+
+func (m *mockAuditLog) Query(ctx context.Context, filter audit.Filter) ([]audit.Event, string, error) {
+	m.filter = filter
+	return m.events, m.next, m.err
+}
+
+func TestAuditHandler_Query(t *testing.T) {
+	mockLogger, err := logger.NewTestLogger()
+	if err != nil {
+		t.Fatalf("Failed to create mock logger: %v", err)
+	}
+
+	testCases := []struct {
+		name               string
+		query              string
+		log                *mockAuditLog
+		expectedStatusCode int
+		checkFilter        func(t *testing.T, filter audit.Filter)
+	}{
+		{
+			name:               "All Filters",
+			query:              "?link=abc123&actor=jane&from=2024-01-01&to=2024-02-01T00:00:00Z&limit=10&cursor=1704067200000-0",
+			log:                &mockAuditLog{events: []audit.Event{{ShortID: "abc123"}}, next: "1704067200001-0"},
+			expectedStatusCode: http.StatusOK,
+			checkFilter: func(t *testing.T, filter audit.Filter) {
+				expected := audit.Filter{
+					ShortID: "abc123",
+					Actor:   "jane",
+					From:    time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+					To:      time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+					After:   "1704067200000-0",
+					Limit:   10,
+				}
+				if filter != expected {
+					t.Errorf("Expected filter %+v, got %+v", expected, filter)
+				}
+			},
+		},
+		{
+			name:               "No Events",
+			query:              "",
+			log:                &mockAuditLog{},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "Invalid From",
+			query:              "?from=yesterday",
+			log:                &mockAuditLog{},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Inverted Range",
+			query:              "?from=2024-02-01&to=2024-01-01",
+			log:                &mockAuditLog{},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Limit Too High",
+			query:              "?limit=5000",
+			log:                &mockAuditLog{},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Invalid Cursor",
+			query:              "?cursor=-",
+			log:                &mockAuditLog{},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Query Failed",
+			query:              "",
+			log:                &mockAuditLog{err: errors.New("stream unavailable")},
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := &AuditHandler{Log: tc.log, Logger: mockLogger}
+
+			req := httptest.NewRequest(http.MethodGet, "/admin/audit"+tc.query, nil)
+			w := httptest.NewRecorder()
+			handler.Query(w, req)
+
+			if w.Code != tc.expectedStatusCode {
+				t.Fatalf("Expected status %d, got %d: %s", tc.expectedStatusCode, w.Code, w.Body.String())
+			}
+			if tc.checkFilter != nil {
+				tc.checkFilter(t, tc.log.filter)
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			var response struct {
+				Events []audit.Event `json:"events"`
+				Next   string        `json:"next"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if response.Events == nil || len(response.Events) != len(tc.log.events) || response.Next != tc.log.next {
+				t.Errorf("Unexpected response %+v", response)
+			}
+		})
+	}
+}
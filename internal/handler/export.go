@@ -0,0 +1,325 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/analytics"
+	customerrors "github.com/yasin-yalcin-dev/go-url-shortener/pkg/errors"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/tracing"
+
+	"go.uber.org/zap"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	// defaultExportRange is the export window when "from" is not given
+	defaultExportRange = 30 * 24 * time.Hour
+
+	// maxExportRange is the longest window a single export may cover
+	maxExportRange = 366 * 24 * time.Hour
+
+	// exportFlushEvery is the number of rows written between flushes
+	exportFlushEvery = 100
+)
+
+// rowWriter writes export rows in a specific format
+type rowWriter interface {
+	WriteBucket(bucket analytics.ClickBucket) error
+	WriteEvent(event analytics.StreamEvent) error
+	Flush() error
+}
+
+// ExportURLAnalytics streams analytics rows of a URL as CSV or NDJSON
+func (h *ShortenHandler) ExportURLAnalytics(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "ShortenHandler.ExportURLAnalytics")
+	defer span.End()
+	r = r.WithContext(ctx)
+
+	shortID := chi.URLParam(r, "shortened")
+	query := r.URL.Query()
+
+	exporter, ok := h.Analytics.(analytics.Exporter)
+	if !ok {
+		customerrors.New(
+			http.StatusNotImplemented,
+			"Analytics export is not available",
+		).WriteResponse(w)
+		return
+	}
+
+	format := query.Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	if format != "csv" && format != "ndjson" {
+		customerrors.New(
+			http.StatusBadRequest,
+			"Invalid export format",
+			"Supported formats are csv and ndjson",
+		).WriteResponse(w)
+		return
+	}
+
+	rows := query.Get("rows")
+	if rows == "" {
+		rows = "buckets"
+	}
+	if rows != "buckets" && rows != "events" {
+		customerrors.New(
+			http.StatusBadRequest,
+			"Invalid export rows",
+			"Supported rows are buckets and events",
+		).WriteResponse(w)
+		return
+	}
+
+	from, to, apiErr := parseExportRange(query.Get("from"), query.Get("to"))
+	if apiErr != nil {
+		apiErr.WriteResponse(w)
+		return
+	}
+
+	// Headers are sent with the first row, errors after that can only be logged
+	contentType := "text/csv; charset=utf-8"
+	if format == "ndjson" {
+		contentType = "application/x-ndjson"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", exportDisposition(shortID, rows, format))
+
+	writer := newRowWriter(w, format, rows)
+	written := 0
+	flush := func() error {
+		written++
+		if written%exportFlushEvery != 0 {
+			return nil
+		}
+		return writer.Flush()
+	}
+
+	var err error
+	if rows == "events" {
+		err = exporter.ExportEvents(r.Context(), shortID, from, to, func(event analytics.StreamEvent) error {
+			if err := writer.WriteEvent(event); err != nil {
+				return err
+			}
+			return flush()
+		})
+	} else {
+		err = exporter.ExportBuckets(r.Context(), shortID, from, to, func(bucket analytics.ClickBucket) error {
+			if err := writer.WriteBucket(bucket); err != nil {
+				return err
+			}
+			return flush()
+		})
+	}
+
+	if errors.Is(err, analytics.ErrStreamDisabled) && written == 0 {
+		w.Header().Del("Content-Disposition")
+		customerrors.New(
+			http.StatusNotImplemented,
+			"Event export is not enabled",
+			"Enable the analytics event stream to export individual events",
+		).WriteResponse(w)
+		return
+	}
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err != nil {
+		h.Logger.FromContext(r.Context()).Error("Analytics export failed",
+			zap.Error(err),
+			zap.String("shortID", shortID),
+			zap.Int("rows", written),
+		)
+	}
+}
+
+// parseExportRange parses the from/to query parameters, defaulting to the last 30 days
+func parseExportRange(fromParam, toParam string) (time.Time, time.Time, *customerrors.APIError) {
+	to := time.Now().UTC()
+	if toParam != "" {
+		parsed, err := parseExportEnd(toParam)
+		if err != nil {
+			return time.Time{}, time.Time{}, customerrors.New(
+				http.StatusBadRequest,
+				"Invalid 'to' parameter",
+				"Use an RFC 3339 timestamp or a YYYY-MM-DD date",
+			)
+		}
+		to = parsed
+	}
+
+	from := to.Add(-defaultExportRange)
+	if fromParam != "" {
+		parsed, err := parseExportTime(fromParam)
+		if err != nil {
+			return time.Time{}, time.Time{}, customerrors.New(
+				http.StatusBadRequest,
+				"Invalid 'from' parameter",
+				"Use an RFC 3339 timestamp or a YYYY-MM-DD date",
+			)
+		}
+		from = parsed
+	}
+
+	if !from.Before(to) {
+		return time.Time{}, time.Time{}, customerrors.New(
+			http.StatusBadRequest,
+			"Invalid export range",
+			"'from' must be before 'to'",
+		)
+	}
+	if to.Sub(from) > maxExportRange {
+		return time.Time{}, time.Time{}, customerrors.New(
+			http.StatusBadRequest,
+			"Export range is too long",
+			"A single export may cover at most 366 days",
+		)
+	}
+
+	return from, to, nil
+}
+
+// parseExportTime accepts RFC 3339 timestamps and plain dates
+func parseExportTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// parseExportEnd parses the end of a range. A plain date includes that whole day.
+func parseExportEnd(value string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t.AddDate(0, 0, 1), nil
+	}
+	return parseExportTime(value)
+}
+
+// newRowWriter creates a row writer for the format and row type
+func newRowWriter(w http.ResponseWriter, format, rows string) rowWriter {
+	flusher, _ := w.(http.Flusher)
+
+	if format == "ndjson" {
+		return &ndjsonRowWriter{encoder: json.NewEncoder(w), flusher: flusher}
+	}
+
+	writer := &csvRowWriter{writer: csv.NewWriter(w), flusher: flusher}
+	if rows == "events" {
+		writer.header = []string{"id", "short_id", "timestamp", "ip_hash", "user_agent", "referrer", "country", "bot", "variant"}
+	} else {
+		writer.header = []string{"bucket_start", "clicks"}
+	}
+	return writer
+}
+
+// csvRowWriter writes rows as CSV with a header line
+type csvRowWriter struct {
+	writer        *csv.Writer
+	flusher       http.Flusher
+	header        []string
+	headerWritten bool
+}
+
+func (c *csvRowWriter) write(record []string) error {
+	if !c.headerWritten {
+		c.headerWritten = true
+		if err := c.writer.Write(c.header); err != nil {
+			return err
+		}
+	}
+	return c.writer.Write(record)
+}
+
+func (c *csvRowWriter) WriteBucket(bucket analytics.ClickBucket) error {
+	return c.write([]string{
+		bucket.Start.Format(time.RFC3339),
+		strconv.FormatInt(bucket.Clicks, 10),
+	})
+}
+
+func (c *csvRowWriter) WriteEvent(event analytics.StreamEvent) error {
+	return c.write([]string{
+		event.ID,
+		csvCell(event.ShortID),
+		event.Timestamp.Format(time.RFC3339Nano),
+		event.IPHash,
+		csvCell(event.UserAgent),
+		csvCell(event.Referrer),
+		csvCell(event.Country),
+		strconv.FormatBool(event.Bot),
+		csvCell(event.Variant),
+	})
+}
+
+// csvCell neutralizes client-supplied values spreadsheets would evaluate as
+// formulas by prefixing them with a quote
+func csvCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// exportDisposition returns the attachment header of an export, the short ID
+// is quoted or encoded so it cannot add header parameters
+func exportDisposition(shortID, rows, format string) string {
+	return mime.FormatMediaType("attachment", map[string]string{
+		"filename": fmt.Sprintf("%s-%s.%s", shortID, rows, format),
+	})
+}
+
+func (c *csvRowWriter) Flush() error {
+	// An empty export still gets its header line
+	if !c.headerWritten {
+		c.headerWritten = true
+		if err := c.writer.Write(c.header); err != nil {
+			return err
+		}
+	}
+	c.writer.Flush()
+	if c.flusher != nil {
+		c.flusher.Flush()
+	}
+	return c.writer.Error()
+}
+
+// ndjsonRowWriter writes one JSON object per line
+type ndjsonRowWriter struct {
+	encoder *json.Encoder
+	flusher http.Flusher
+}
+
+func (n *ndjsonRowWriter) WriteBucket(bucket analytics.ClickBucket) error {
+	return n.encoder.Encode(bucket)
+}
+
+func (n *ndjsonRowWriter) WriteEvent(event analytics.StreamEvent) error {
+	return n.encoder.Encode(event)
+}
+
+func (n *ndjsonRowWriter) Flush() error {
+	if n.flusher != nil {
+		n.flusher.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,319 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package handler
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/analytics"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/logger"
+)
+
+// mockExportStore adds export support to the mock analytics store
+type mockExportStore struct {
+	mockAnalyticsStore
+	buckets []analytics.ClickBucket
+	events  []analytics.StreamEvent
+}
+
+// ExportBuckets implements the bucket export method for the mock store
+func (m *mockExportStore) ExportBuckets(ctx context.Context, shortID string, from, to time.Time, fn func(analytics.ClickBucket) error) error {
+	for _, bucket := range m.buckets {
+		if err := fn(bucket); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExportEvents implements the event export method for the mock store
+func (m *mockExportStore) ExportEvents(ctx context.Context, shortID string, from, to time.Time, fn func(analytics.StreamEvent) error) error {
+	if m.events == nil {
+		return analytics.ErrStreamDisabled
+	}
+	for _, event := range m.events {
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestShortenHandler_ExportURLAnalytics(t *testing.T) {
+	setUp(t)
+
+	start := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	store := &mockExportStore{
+		buckets: []analytics.ClickBucket{
+			{Start: start, Clicks: 3},
+			{Start: start.Add(time.Hour), Clicks: 1},
+		},
+	}
+
+	testCases := []struct {
+		name               string
+		query              string
+		expectedStatusCode int
+		expectedType       string
+		expectedBody       []string
+	}{
+		{
+			name:               "CSV Buckets",
+			query:              "?format=csv&from=2025-03-01&to=2025-03-02",
+			expectedStatusCode: http.StatusOK,
+			expectedType:       "text/csv; charset=utf-8",
+			expectedBody: []string{
+				"bucket_start,clicks",
+				"2025-03-01T10:00:00Z,3",
+				"2025-03-01T11:00:00Z,1",
+			},
+		},
+		{
+			name:               "NDJSON Buckets",
+			query:              "?format=ndjson&from=2025-03-01&to=2025-03-02",
+			expectedStatusCode: http.StatusOK,
+			expectedType:       "application/x-ndjson",
+			expectedBody: []string{
+				`{"start":"2025-03-01T10:00:00Z","clicks":3}`,
+				`{"start":"2025-03-01T11:00:00Z","clicks":1}`,
+			},
+		},
+		{
+			name:               "Events Without Stream",
+			query:              "?rows=events",
+			expectedStatusCode: http.StatusNotImplemented,
+		},
+		{
+			name:               "Invalid Format",
+			query:              "?format=xml",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Reversed Range",
+			query:              "?from=2025-03-02&to=2025-03-01",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockLogger, err := logger.NewTestLogger()
+			if err != nil {
+				t.Fatalf("Failed to create mock logger: %v", err)
+			}
+
+			handler := &ShortenHandler{
+				Logger:    mockLogger,
+				Analytics: store,
+			}
+
+			r := chi.NewRouter()
+			r.Get("/{shortened}/analytics/export", handler.ExportURLAnalytics)
+
+			req := httptest.NewRequest("GET", "/abc123/analytics/export"+tc.query, nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tc.expectedStatusCode {
+				t.Fatalf("Expected status %d, got %d: %s", tc.expectedStatusCode, w.Code, w.Body.String())
+			}
+			if tc.expectedStatusCode != http.StatusOK {
+				return
+			}
+
+			if contentType := w.Header().Get("Content-Type"); contentType != tc.expectedType {
+				t.Errorf("Expected content type %s, got %s", tc.expectedType, contentType)
+			}
+			if disposition := w.Header().Get("Content-Disposition"); !strings.HasPrefix(disposition, "attachment;") {
+				t.Errorf("Expected attachment disposition, got %q", disposition)
+			}
+
+			lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+			if len(lines) != len(tc.expectedBody) {
+				t.Fatalf("Expected %d lines, got %d: %q", len(tc.expectedBody), len(lines), lines)
+			}
+			for i, line := range lines {
+				if strings.TrimSpace(line) != tc.expectedBody[i] {
+					t.Errorf("Line %d: expected %s, got %s", i, tc.expectedBody[i], line)
+				}
+			}
+		})
+	}
+}
+
+func TestShortenHandler_ExportURLAnalytics_Events(t *testing.T) {
+	setUp(t)
+
+	store := &mockExportStore{
+		events: []analytics.StreamEvent{
+			{ID: "1-0", ShortID: "abc123", Timestamp: time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC), Country: "DE"},
+		},
+	}
+
+	mockLogger, err := logger.NewTestLogger()
+	if err != nil {
+		t.Fatalf("Failed to create mock logger: %v", err)
+	}
+	handler := &ShortenHandler{Logger: mockLogger, Analytics: store}
+
+	r := chi.NewRouter()
+	r.Get("/{shortened}/analytics/export", handler.ExportURLAnalytics)
+
+	req := httptest.NewRequest("GET", "/abc123/analytics/export?format=ndjson&rows=events", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var event analytics.StreamEvent
+	if err := json.Unmarshal(w.Body.Bytes(), &event); err != nil {
+		t.Fatalf("Failed to parse event: %v", err)
+	}
+	if event.ID != "1-0" || event.Country != "DE" {
+		t.Errorf("Unexpected event: %+v", event)
+	}
+}
+
+func TestParseExportRange(t *testing.T) {
+	testCases := []struct {
+		name         string
+		from         string
+		to           string
+		expectedFrom time.Time
+		expectedTo   time.Time
+		expectError  bool
+	}{
+		{
+			name:         "Dates Include The Last Day",
+			from:         "2025-03-01",
+			to:           "2025-03-31",
+			expectedFrom: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+			expectedTo:   time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:         "Single Day",
+			from:         "2025-03-01",
+			to:           "2025-03-01",
+			expectedFrom: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+			expectedTo:   time.Date(2025, 3, 2, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:         "Timestamps Are Exact",
+			from:         "2025-03-01T10:00:00Z",
+			to:           "2025-03-01T14:00:00+02:00",
+			expectedFrom: time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC),
+			expectedTo:   time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name:         "Date Until Timestamp",
+			from:         "2025-03-01",
+			to:           "2025-03-01T12:00:00Z",
+			expectedFrom: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+			expectedTo:   time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name:        "Reversed Dates",
+			from:        "2025-03-02",
+			to:          "2025-03-01",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			from, to, apiErr := parseExportRange(tc.from, tc.to)
+			if tc.expectError {
+				if apiErr == nil {
+					t.Errorf("Expected an error, got %v - %v", from, to)
+				}
+				return
+			}
+			if apiErr != nil {
+				t.Fatalf("Unexpected error: %v", apiErr)
+			}
+			if !from.Equal(tc.expectedFrom) || !to.Equal(tc.expectedTo) {
+				t.Errorf("Expected %v - %v, got %v - %v", tc.expectedFrom, tc.expectedTo, from, to)
+			}
+		})
+	}
+}
+
+func TestCSVRowWriter_FormulaInjection(t *testing.T) {
+	w := httptest.NewRecorder()
+	writer := newRowWriter(w, "csv", "events")
+
+	err := writer.WriteEvent(analytics.StreamEvent{
+		ID:        "1-0",
+		ShortID:   "abc123",
+		UserAgent: `=HYPERLINK("https://evil.example","click")`,
+		Referrer:  "@SUM(1+1)",
+		Country:   "DE",
+		Variant:   "-2+3",
+	})
+	if err != nil {
+		t.Fatalf("WriteEvent failed: %v", err)
+	}
+	if err := writer.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	records, err := csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+	if err != nil || len(records) != 2 {
+		t.Fatalf("Failed to parse CSV: %v %q", err, w.Body.String())
+	}
+	row := records[1]
+	expected := map[int]string{
+		1: "abc123",
+		4: `'=HYPERLINK("https://evil.example","click")`,
+		5: "'@SUM(1+1)",
+		6: "DE",
+		8: "'-2+3",
+	}
+	for column, value := range expected {
+		if row[column] != value {
+			t.Errorf("Column %d: expected %q, got %q", column, value, row[column])
+		}
+	}
+}
+
+func TestExportDisposition(t *testing.T) {
+	testCases := []struct {
+		name     string
+		shortID  string
+		expected string
+	}{
+		{name: "Plain ID", shortID: "abc123", expected: "abc123-events.csv"},
+		{name: "Quote And Semicolon", shortID: `a"b;c`, expected: `a"b;c-events.csv`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			header := exportDisposition(tc.shortID, "events", "csv")
+			disposition, params, err := mime.ParseMediaType(header)
+			if err != nil {
+				t.Fatalf("Invalid header %q: %v", header, err)
+			}
+			if disposition != "attachment" || len(params) != 1 || params["filename"] != tc.expected {
+				t.Errorf("Unexpected header %q", header)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/analytics"
+)
+
+const (
+	// defaultReadinessTimeout bounds all readiness checks together
+	defaultReadinessTimeout = 2 * time.Second
+
+	statusOK   = "ok"
+	statusFail = "fail"
+)
+
+// errShuttingDown is reported by the readiness probe during graceful shutdown
+var errShuttingDown = errors.New("server is shutting down")
+
+// HealthCheck reports an error when a component is not ready
+type HealthCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// HealthHandler serves the liveness and readiness probes
+type HealthHandler struct {
+	Checks  []HealthCheck
+	Timeout time.Duration // Defaults to 2s
+
+	shuttingDown atomic.Bool
+}
+
+// componentStatus is the result of a single check
+type componentStatus struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// healthResponse is the body of both probes
+type healthResponse struct {
+	Status     string                     `json:"status"`
+	Components map[string]componentStatus `json:"components,omitempty"`
+}
+
+// SetShuttingDown marks the server as not ready, so load balancers stop
+// sending new requests while in-flight ones finish
+func (h *HealthHandler) SetShuttingDown() {
+	h.shuttingDown.Store(true)
+}
+
+// Liveness reports that the process is running and serving requests
+func (h *HealthHandler) Liveness(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, http.StatusOK, healthResponse{Status: statusOK})
+}
+
+// Readiness runs all checks concurrently and reports whether the server
+// should receive traffic
+func (h *HealthHandler) Readiness(w http.ResponseWriter, r *http.Request) {
+	timeout := h.Timeout
+	if timeout <= 0 {
+		timeout = defaultReadinessTimeout
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+
+	checks := append([]HealthCheck{{
+		Name: "server",
+		Check: func(ctx context.Context) error {
+			if h.shuttingDown.Load() {
+				return errShuttingDown
+			}
+			return nil
+		},
+	}}, h.Checks...)
+
+	results := make([]error, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = runCheck(ctx, check)
+		}()
+	}
+	wg.Wait()
+
+	response := healthResponse{
+		Status:     statusOK,
+		Components: make(map[string]componentStatus, len(checks)),
+	}
+	status := http.StatusOK
+	for i, check := range checks {
+		if results[i] != nil {
+			response.Status = statusFail
+			response.Components[check.Name] = componentStatus{Status: statusFail, Error: results[i].Error()}
+			status = http.StatusServiceUnavailable
+			continue
+		}
+		response.Components[check.Name] = componentStatus{Status: statusOK}
+	}
+	writeHealth(w, status, response)
+}
+
+// runCheck runs a check and gives up once the context is done, so a hanging
+// dependency cannot block the probe
+func runCheck(ctx context.Context, check HealthCheck) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- check.Check(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("check timed out: %v", ctx.Err())
+	}
+}
+
+// writeHealth writes a probe response, probes must never be cached
+func writeHealth(w http.ResponseWriter, status int, response healthResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// RedisCheck reports Redis as not ready when it does not answer a ping
+func RedisCheck(client interface {
+	IsHealthy(ctx context.Context) bool
+}) HealthCheck {
+	return HealthCheck{
+		Name: "redis",
+		Check: func(ctx context.Context) error {
+			if !client.IsHealthy(ctx) {
+				return errors.New("ping failed")
+			}
+			return nil
+		},
+	}
+}
+
+// AnalyticsQueueCheck reports the analytics pipeline as not ready once its
+// queue is filled to maxUsage (0-1), new events would soon be dropped
+func AnalyticsQueueCheck(stats func() analytics.PipelineStats, maxUsage float64) HealthCheck {
+	return HealthCheck{
+		Name: "analytics_queue",
+		Check: func(ctx context.Context) error {
+			s := stats()
+			if s.QueueCapacity > 0 && float64(s.QueueDepth) >= maxUsage*float64(s.QueueCapacity) {
+				return fmt.Errorf("queue is saturated: %d of %d events", s.QueueDepth, s.QueueCapacity)
+			}
+			return nil
+		},
+	}
+}
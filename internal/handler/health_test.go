@@ -0,0 +1,130 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/analytics"
+)
+
+// mockPinger simulates the Redis client health check
+type mockPinger struct {
+	healthy bool
+	delay   time.Duration
+}
+
+func (m *mockPinger) IsHealthy(ctx context.Context) bool {
+	time.Sleep(m.delay)
+	return m.healthy
+}
+
+func TestHealthHandler_Liveness(t *testing.T) {
+	handler := &HealthHandler{Checks: []HealthCheck{RedisCheck(&mockPinger{healthy: false})}}
+
+	w := httptest.NewRecorder()
+	handler.Liveness(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	// Liveness never depends on other components
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+}
+
+func TestHealthHandler_Readiness(t *testing.T) {
+	queue := func(depth int) func() analytics.PipelineStats {
+		return func() analytics.PipelineStats {
+			return analytics.PipelineStats{QueueDepth: depth, QueueCapacity: 100}
+		}
+	}
+
+	testCases := []struct {
+		name               string
+		pinger             *mockPinger
+		queueDepth         int
+		shuttingDown       bool
+		expectedStatusCode int
+		expectedComponents map[string]string
+	}{
+		{
+			name:               "Ready",
+			pinger:             &mockPinger{healthy: true},
+			queueDepth:         10,
+			expectedStatusCode: http.StatusOK,
+			expectedComponents: map[string]string{"server": statusOK, "redis": statusOK, "analytics_queue": statusOK},
+		},
+		{
+			name:               "Redis Down",
+			pinger:             &mockPinger{healthy: false},
+			expectedStatusCode: http.StatusServiceUnavailable,
+			expectedComponents: map[string]string{"server": statusOK, "redis": statusFail, "analytics_queue": statusOK},
+		},
+		{
+			name:               "Redis Timeout",
+			pinger:             &mockPinger{healthy: true, delay: time.Second},
+			expectedStatusCode: http.StatusServiceUnavailable,
+			expectedComponents: map[string]string{"redis": statusFail},
+		},
+		{
+			name:               "Queue Saturated",
+			pinger:             &mockPinger{healthy: true},
+			queueDepth:         95,
+			expectedStatusCode: http.StatusServiceUnavailable,
+			expectedComponents: map[string]string{"redis": statusOK, "analytics_queue": statusFail},
+		},
+		{
+			name:               "Shutting Down",
+			pinger:             &mockPinger{healthy: true},
+			shuttingDown:       true,
+			expectedStatusCode: http.StatusServiceUnavailable,
+			expectedComponents: map[string]string{"server": statusFail, "redis": statusOK},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := &HealthHandler{
+				Checks: []HealthCheck{
+					RedisCheck(tc.pinger),
+					AnalyticsQueueCheck(queue(tc.queueDepth), 0.9),
+				},
+				Timeout: 50 * time.Millisecond,
+			}
+			if tc.shuttingDown {
+				handler.SetShuttingDown()
+			}
+
+			w := httptest.NewRecorder()
+			handler.Readiness(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			if w.Code != tc.expectedStatusCode {
+				t.Errorf("Expected status %d, got %d", tc.expectedStatusCode, w.Code)
+			}
+
+			var response healthResponse
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			for name, status := range tc.expectedComponents {
+				if response.Components[name].Status != status {
+					t.Errorf("Expected %s to be %s, got %+v", name, status, response.Components[name])
+				}
+			}
+			if (response.Status == statusOK) != (tc.expectedStatusCode == http.StatusOK) {
+				t.Errorf("Unexpected overall status %q", response.Status)
+			}
+		})
+	}
+}
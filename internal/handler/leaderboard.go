@@ -0,0 +1,134 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/analytics"
+	customerrors "github.com/yasin-yalcin-dev/go-url-shortener/pkg/errors"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/tracing"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultTopLimit = 10
+	maxTopLimit     = 100
+)
+
+// TopLinks returns the most clicked links globally or per owner, tag or domain.
+// Query parameters: scope (global|owner|tag|domain), value, days (window
+// ending today) and limit.
+func (h *ShortenHandler) TopLinks(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "ShortenHandler.TopLinks")
+	defer span.End()
+	r = r.WithContext(ctx)
+
+	leaderboard, ok := h.Analytics.(analytics.Leaderboard)
+	if !ok {
+		customerrors.New(
+			http.StatusNotImplemented,
+			"Leaderboards are not available",
+		).WriteResponse(w)
+		return
+	}
+
+	query := r.URL.Query()
+
+	board := analytics.Board{
+		Scope: query.Get("scope"),
+		Value: strings.TrimSpace(query.Get("value")),
+	}
+	if board.Scope == "" {
+		board.Scope = analytics.ScopeGlobal
+	}
+	switch board.Scope {
+	case analytics.ScopeGlobal:
+		board.Value = ""
+	case analytics.ScopeOwner, analytics.ScopeTag, analytics.ScopeDomain:
+		if board.Value == "" {
+			customerrors.New(
+				http.StatusBadRequest,
+				"Missing leaderboard value",
+				fmt.Sprintf("The %s scope requires a value", board.Scope),
+			).WriteResponse(w)
+			return
+		}
+		if board.Scope != analytics.ScopeOwner {
+			board.Value = strings.ToLower(board.Value)
+		}
+	default:
+		customerrors.New(
+			http.StatusBadRequest,
+			"Invalid leaderboard scope",
+			"Supported scopes are global, owner, tag and domain",
+		).WriteResponse(w)
+		return
+	}
+
+	days, apiErr := parseBoundedInt(query.Get("days"), 1, analytics.MaxLeaderboardDays, "days")
+	if apiErr != nil {
+		apiErr.WriteResponse(w)
+		return
+	}
+	limit, apiErr := parseBoundedInt(query.Get("limit"), defaultTopLimit, maxTopLimit, "limit")
+	if apiErr != nil {
+		apiErr.WriteResponse(w)
+		return
+	}
+
+	top, err := leaderboard.TopLinks(r.Context(), board, days, limit)
+	if err != nil {
+		h.Logger.FromContext(r.Context()).Error("Failed to get top links",
+			zap.Error(err),
+			zap.String("scope", board.Scope),
+			zap.String("value", board.Value),
+		)
+		customerrors.ErrInternal.WriteResponse(w)
+		return
+	}
+
+	response := struct {
+		Scope string              `json:"scope"`
+		Value string              `json:"value,omitempty"`
+		Days  int                 `json:"days"`
+		Links []analytics.TopLink `json:"links"`
+	}{
+		Scope: board.Scope,
+		Value: board.Value,
+		Days:  days,
+		Links: top,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// parseBoundedInt parses a positive integer query parameter with a default and an upper bound
+func parseBoundedInt(value string, defaultValue, max int, name string) (int, *customerrors.APIError) {
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 || n > max {
+		return 0, customerrors.New(
+			http.StatusBadRequest,
+			fmt.Sprintf("Invalid '%s' parameter", name),
+			fmt.Sprintf("'%s' must be between 1 and %d", name, max),
+		)
+	}
+	return n, nil
+}
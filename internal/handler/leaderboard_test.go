@@ -0,0 +1,119 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/analytics"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/logger"
+)
+
+// mockLeaderboardStore adds leaderboard support to the mock analytics store
+type mockLeaderboardStore struct {
+	mockAnalyticsStore
+	board analytics.Board
+	days  int
+	limit int
+}
+
+// TopLinks implements the leaderboard method for the mock store
+func (m *mockLeaderboardStore) TopLinks(ctx context.Context, board analytics.Board, days int, limit int) ([]analytics.TopLink, error) {
+	m.board, m.days, m.limit = board, days, limit
+	return []analytics.TopLink{
+		{ShortID: "abc123", Clicks: 42, Analytics: &analytics.URLAnalytics{TotalClicks: 100}},
+	}, nil
+}
+
+func TestShortenHandler_TopLinks(t *testing.T) {
+	setUp(t)
+
+	testCases := []struct {
+		name               string
+		query              string
+		expectedStatusCode int
+		expectedBoard      analytics.Board
+		expectedDays       int
+		expectedLimit      int
+	}{
+		{
+			name:               "Global Defaults",
+			query:              "",
+			expectedStatusCode: http.StatusOK,
+			expectedBoard:      analytics.Board{Scope: analytics.ScopeGlobal},
+			expectedDays:       1,
+			expectedLimit:      defaultTopLimit,
+		},
+		{
+			name:               "Tag Scope",
+			query:              "?scope=tag&value=Launch&days=7&limit=5",
+			expectedStatusCode: http.StatusOK,
+			expectedBoard:      analytics.Board{Scope: analytics.ScopeTag, Value: "launch"},
+			expectedDays:       7,
+			expectedLimit:      5,
+		},
+		{
+			name:               "Owner Scope Without Value",
+			query:              "?scope=owner",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Unknown Scope",
+			query:              "?scope=planet&value=mars",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Limit Too High",
+			query:              "?limit=1000",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockLogger, err := logger.NewTestLogger()
+			if err != nil {
+				t.Fatalf("Failed to create mock logger: %v", err)
+			}
+
+			store := &mockLeaderboardStore{}
+			handler := &ShortenHandler{Logger: mockLogger, Analytics: store}
+
+			req := httptest.NewRequest("GET", "/admin/analytics/top"+tc.query, nil)
+			w := httptest.NewRecorder()
+			handler.TopLinks(w, req)
+
+			if w.Code != tc.expectedStatusCode {
+				t.Fatalf("Expected status %d, got %d: %s", tc.expectedStatusCode, w.Code, w.Body.String())
+			}
+			if tc.expectedStatusCode != http.StatusOK {
+				return
+			}
+
+			if store.board != tc.expectedBoard || store.days != tc.expectedDays || store.limit != tc.expectedLimit {
+				t.Errorf("Unexpected query: board %+v, days %d, limit %d", store.board, store.days, store.limit)
+			}
+
+			var response struct {
+				Links []analytics.TopLink `json:"links"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to parse response: %v", err)
+			}
+			if len(response.Links) != 1 || response.Links[0].Clicks != 42 {
+				t.Errorf("Unexpected links: %+v", response.Links)
+			}
+		})
+	}
+}
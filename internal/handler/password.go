@@ -0,0 +1,144 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package handler
+
+import (
+	"html/template"
+	"net/http"
+	"strings"
+
+	"github.com/yasin-yalcin-dev/go-url-shortener/internal/model"
+	"github.com/yasin-yalcin-dev/go-url-shortener/internal/service"
+	customerrors "github.com/yasin-yalcin-dev/go-url-shortener/pkg/errors"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/privacy"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// PasswordHeader carries the password of a protected link for API clients
+	PasswordHeader = "X-Link-Password"
+
+	// maxPasswordFormSize limits the body of a password form submission
+	maxPasswordFormSize = 4 << 10
+)
+
+// passwordForm is served for protected links opened without a password,
+// an empty action posts back to the same URL including its query
+var passwordForm = template.Must(template.New("password").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>Password required</title>
+</head>
+<body>
+<form method="post" action="">
+<p>This link is password protected.</p>
+{{if .Error}}<p role="alert">{{.Error}}</p>{{end}}
+<input type="password" name="password" autocomplete="current-password" required autofocus>
+<button type="submit">Continue</button>
+</form>
+</body>
+</html>
+`))
+
+// unlock checks the password of a protected link and writes the response
+// when the redirect may not proceed
+func (h *ShortenHandler) unlock(w http.ResponseWriter, r *http.Request, link *model.Link) bool {
+	password := r.Header.Get(PasswordHeader)
+	fromForm := false
+	if password == "" && r.Method == http.MethodPost {
+		r.Body = http.MaxBytesReader(w, r.Body, maxPasswordFormSize)
+		password = r.PostFormValue("password")
+		fromForm = true
+	}
+
+	if password == "" {
+		if fromForm || acceptsHTML(r) {
+			writePasswordForm(w, http.StatusUnauthorized, "")
+			return false
+		}
+		customerrors.New(
+			http.StatusUnauthorized,
+			"Password required",
+			"Send the link password in the "+PasswordHeader+" header",
+		).WriteResponse(w)
+		return false
+	}
+
+	// Throttle per client and link, checked before the costly hash comparison.
+	// The client is identified by its connection, a spoofed X-Forwarded-For
+	// header does not give it a fresh budget, and the link budget caps
+	// attempts spread over many addresses.
+	if !h.allowPasswordAttempt(r, link.ShortID) {
+		h.Logger.FromContext(r.Context()).Warn("Password attempts throttled",
+			zap.String("shortID", link.ShortID),
+		)
+		if fromForm {
+			writePasswordForm(w, http.StatusTooManyRequests, "Too many attempts, please try again later.")
+			return false
+		}
+		customerrors.New(
+			http.StatusTooManyRequests,
+			"Too many password attempts",
+			"Please try again later",
+		).WriteResponse(w)
+		return false
+	}
+
+	if !service.VerifyPassword(link, password) {
+		h.Logger.FromContext(r.Context()).Warn("Invalid link password",
+			zap.String("shortID", link.ShortID),
+		)
+		if fromForm {
+			writePasswordForm(w, http.StatusUnauthorized, "Incorrect password.")
+			return false
+		}
+		customerrors.New(
+			http.StatusUnauthorized,
+			"Invalid password",
+			"The password for this link is incorrect",
+		).WriteResponse(w)
+		return false
+	}
+
+	return true
+}
+
+// allowPasswordAttempt takes a password attempt from the client and link budgets
+func (h *ShortenHandler) allowPasswordAttempt(r *http.Request, shortID string) bool {
+	if h.PasswordLimiter != nil && !h.PasswordLimiter.Allow(privacy.TrustedClientIP(r, h.TrustedProxies)+"|"+shortID) {
+		return false
+	}
+	return h.PasswordLinkLimiter == nil || h.PasswordLinkLimiter.Allow(shortID)
+}
+
+// writePasswordForm renders the password form with an optional error message
+func writePasswordForm(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.WriteHeader(status)
+	passwordForm.Execute(w, struct{ Error string }{Error: message})
+}
+
+// unlockedByForm reports whether a protected link was unlocked by a form
+// submission, which must be followed by a GET so the password is not re-posted
+func unlockedByForm(r *http.Request, link *model.Link) bool {
+	return link.Protected() && r.Method == http.MethodPost && r.Header.Get(PasswordHeader) == ""
+}
+
+// acceptsHTML reports whether the client asked for an HTML response
+func acceptsHTML(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/html")
+}
@@ -0,0 +1,271 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/yasin-yalcin-dev/go-url-shortener/internal/model"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/logger"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/privacy"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/ratelimiter"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// newProtectedRouter serves a single link protected by the password "secret"
+func newProtectedRouter(t *testing.T, limiter *ratelimiter.RateLimiter) http.Handler {
+	return newProtectedRouterWith(t, func(h *ShortenHandler) { h.PasswordLimiter = limiter })
+}
+
+// newProtectedRouterWith serves the protected link with a customized handler
+func newProtectedRouterWith(t *testing.T, configure func(*ShortenHandler)) http.Handler {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("Failed to hash password: %v", err)
+	}
+
+	mockLogger, err := logger.NewTestLogger()
+	if err != nil {
+		t.Fatalf("Failed to create mock logger: %v", err)
+	}
+
+	handler := &ShortenHandler{
+		Service: &mockURLService{
+			getLinkFunc: func(ctx context.Context, shortID string) (*model.Link, error) {
+				return &model.Link{
+					ShortID:      shortID,
+					Original:     "https://example.com/internal",
+					RedirectCode: http.StatusTemporaryRedirect,
+					PasswordHash: string(hash),
+				}, nil
+			},
+		},
+		Logger: mockLogger,
+	}
+	configure(handler)
+
+	r := chi.NewRouter()
+	r.Get("/{shortened}", handler.Redirect)
+	r.Post("/{shortened}", handler.Redirect)
+	return r
+}
+
+func TestShortenHandler_RedirectPassword(t *testing.T) {
+	// Prepare test environment
+	setUp(t)
+
+	testCases := []struct {
+		name                string
+		method              string
+		header              string
+		accept              string
+		form                string
+		expectedStatusCode  int
+		expectedLocation    string
+		expectedContentType string
+	}{
+		{
+			name:                "Browser Gets Form",
+			method:              "GET",
+			accept:              "text/html,application/xhtml+xml",
+			expectedStatusCode:  http.StatusUnauthorized,
+			expectedContentType: "text/html; charset=utf-8",
+		},
+		{
+			name:                "API Client Gets Error",
+			method:              "GET",
+			accept:              "application/json",
+			expectedStatusCode:  http.StatusUnauthorized,
+			expectedContentType: "application/json",
+		},
+		{
+			name:               "Correct Header",
+			method:             "GET",
+			header:             "secret",
+			expectedStatusCode: http.StatusTemporaryRedirect,
+			expectedLocation:   "https://example.com/internal",
+		},
+		{
+			name:                "Wrong Header",
+			method:              "GET",
+			header:              "guess",
+			expectedStatusCode:  http.StatusUnauthorized,
+			expectedContentType: "application/json",
+		},
+		{
+			name:               "Correct Form Redirects With GET",
+			method:             "POST",
+			form:               "secret",
+			expectedStatusCode: http.StatusSeeOther,
+			expectedLocation:   "https://example.com/internal",
+		},
+		{
+			name:                "Wrong Form",
+			method:              "POST",
+			form:                "guess",
+			expectedStatusCode:  http.StatusUnauthorized,
+			expectedContentType: "text/html; charset=utf-8",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			router := newProtectedRouter(t, nil)
+
+			var req *http.Request
+			if tc.form != "" {
+				body := url.Values{"password": {tc.form}}.Encode()
+				req = httptest.NewRequest(tc.method, "/abc123", strings.NewReader(body))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			} else {
+				req = httptest.NewRequest(tc.method, "/abc123", nil)
+			}
+			if tc.header != "" {
+				req.Header.Set(PasswordHeader, tc.header)
+			}
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.expectedStatusCode {
+				t.Errorf("Expected status %d, got %d", tc.expectedStatusCode, w.Code)
+			}
+			if location := w.Header().Get("Location"); location != tc.expectedLocation {
+				t.Errorf("Expected location %q, got %q", tc.expectedLocation, location)
+			}
+			if tc.expectedContentType != "" && w.Header().Get("Content-Type") != tc.expectedContentType {
+				t.Errorf("Expected content type %q, got %q", tc.expectedContentType, w.Header().Get("Content-Type"))
+			}
+			if cacheControl := w.Header().Get("Cache-Control"); cacheControl != "no-store" && tc.expectedLocation != "" {
+				t.Errorf("Expected protected redirect not to be cached, got %q", cacheControl)
+			}
+		})
+	}
+}
+
+func TestShortenHandler_RedirectPasswordThrottled(t *testing.T) {
+	// Prepare test environment
+	setUp(t)
+
+	router := newProtectedRouter(t, ratelimiter.NewRateLimiter(0.001, 3))
+
+	codes := make([]int, 0, 5)
+	for i := 0; i < 5; i++ {
+		req := httptest.NewRequest("GET", "/abc123", nil)
+		req.Header.Set(PasswordHeader, "guess")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		codes = append(codes, w.Code)
+	}
+
+	for i, code := range codes {
+		expected := http.StatusUnauthorized
+		if i >= 3 {
+			expected = http.StatusTooManyRequests
+		}
+		if code != expected {
+			t.Errorf("Attempt %d: expected status %d, got %d", i+1, expected, code)
+		}
+	}
+
+	// The correct password is throttled as well once the attempts are used up
+	req := httptest.NewRequest("GET", "/abc123", nil)
+	req.Header.Set(PasswordHeader, "secret")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected status %d, got %d", http.StatusTooManyRequests, w.Code)
+	}
+}
+
+func TestShortenHandler_RedirectPasswordSpoofedClient(t *testing.T) {
+	// Prepare test environment
+	setUp(t)
+
+	trusted, err := privacy.ParseProxies([]string{"10.0.0.1"})
+	if err != nil {
+		t.Fatalf("ParseProxies failed: %v", err)
+	}
+
+	testCases := []struct {
+		name       string
+		remoteAddr func(i int) string
+		configure  func(h *ShortenHandler)
+	}{
+		{
+			// A rotating X-Forwarded-For header does not reset the client budget
+			name:       "Rotated Forwarded Header",
+			remoteAddr: func(i int) string { return "203.0.113.7:1234" },
+			configure: func(h *ShortenHandler) {
+				h.PasswordLimiter = ratelimiter.NewRateLimiter(0.001, 3)
+				h.TrustedProxies = trusted
+			},
+		},
+		{
+			// Attempts spread over many addresses are capped per link
+			name:       "Rotated Client Address",
+			remoteAddr: func(i int) string { return fmt.Sprintf("203.0.113.%d:1234", i+1) },
+			configure: func(h *ShortenHandler) {
+				h.PasswordLimiter = ratelimiter.NewRateLimiter(0.001, 3)
+				h.PasswordLinkLimiter = ratelimiter.NewRateLimiter(0.001, 3)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			router := newProtectedRouterWith(t, tc.configure)
+
+			for i := 0; i < 5; i++ {
+				req := httptest.NewRequest("GET", "/abc123", nil)
+				req.RemoteAddr = tc.remoteAddr(i)
+				req.Header.Set("X-Forwarded-For", fmt.Sprintf("198.51.100.%d", i+1))
+				req.Header.Set(PasswordHeader, "guess")
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				expected := http.StatusUnauthorized
+				if i >= 3 {
+					expected = http.StatusTooManyRequests
+				}
+				if w.Code != expected {
+					t.Errorf("Attempt %d: expected status %d, got %d", i+1, expected, w.Code)
+				}
+			}
+		})
+	}
+
+	// Behind a trusted proxy clients are told apart by the forwarded address
+	router := newProtectedRouterWith(t, func(h *ShortenHandler) {
+		h.PasswordLimiter = ratelimiter.NewRateLimiter(0.001, 1)
+		h.TrustedProxies = trusted
+	})
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest("GET", "/abc123", nil)
+		req.RemoteAddr = "10.0.0.1:80"
+		req.Header.Set("X-Forwarded-For", fmt.Sprintf("198.51.100.%d", i+1))
+		req.Header.Set(PasswordHeader, "guess")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("Client %d: expected status %d, got %d", i+1, http.StatusUnauthorized, w.Code)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package handler
+
+import (
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/yasin-yalcin-dev/go-url-shortener/internal/model"
+)
+
+const (
+	// previewSuffix appended to a short ID, as in /abc123+, shows the preview page
+	previewSuffix = "+"
+
+	// previewParam set to 1 shows the preview page as well
+	previewParam = "preview"
+)
+
+// previewPage shows where a link leads before the visitor continues.
+// html/template escapes every value and drops unsafe URLs like javascript:.
+var previewPage = template.Must(template.New("preview").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>{{if .Title}}{{.Title}}{{else}}Link preview{{end}}</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 40rem; margin: 4rem auto; padding: 0 1rem; line-height: 1.5; }
+.host { font-size: 1.25rem; font-weight: bold; }
+.destination { word-break: break-all; color: #555; }
+.continue { display: inline-block; margin-top: 1rem; padding: .5rem 1rem; background: #0b57d0; color: #fff; text-decoration: none; border-radius: 4px; }
+</style>
+</head>
+<body>
+{{if .Destination}}<p>This link leads to</p>
+<p class="host">{{.Host}}</p>
+{{else}}<p>The destination of this link is shown when you continue.</p>
+{{end}}{{if .Title}}<p><strong>{{.Title}}</strong></p>{{end}}
+{{if .Description}}<p>{{.Description}}</p>{{end}}
+{{if .Destination}}<p class="destination">{{.Destination}}</p>{{end}}
+<a class="continue" href="{{.ContinueURL}}" rel="noopener noreferrer">Continue</a>
+</body>
+</html>
+`))
+
+// previewData is the content of the preview page
+type previewData struct {
+	Title       string
+	Description string
+	Host        string
+	Destination string
+	ContinueURL string
+}
+
+// previewRequested reports whether the request asks for the preview page
+// and returns the short ID without the preview suffix
+func previewRequested(r *http.Request, shortID string) (string, bool) {
+	if trimmed, found := strings.CutSuffix(shortID, previewSuffix); found {
+		return trimmed, true
+	}
+	return shortID, r.URL.Query().Get(previewParam) == "1"
+}
+
+// withoutPreview returns the query without the preview parameter
+func withoutPreview(query url.Values) url.Values {
+	if _, found := query[previewParam]; !found {
+		return query
+	}
+	cleaned := make(url.Values, len(query))
+	for key, values := range query {
+		if key != previewParam {
+			cleaned[key] = values
+		}
+	}
+	return cleaned
+}
+
+// previewContinueURL returns the request URL without preview markers, so
+// continuing from an explicit preview follows the link normally
+func previewContinueURL(r *http.Request, shortID string) string {
+	continueURL := url.URL{
+		Path:     strings.Replace(r.URL.Path, shortID+previewSuffix, shortID, 1),
+		RawQuery: withoutPreview(r.URL.Query()).Encode(),
+	}
+	return continueURL.String()
+}
+
+// writePreview renders the preview page for a destination. An empty
+// destination hides it together with the metadata fetched from it.
+func writePreview(w http.ResponseWriter, link *model.Link, destination, continueURL string) {
+	host := destination
+	if parsed, err := url.Parse(destination); err == nil && parsed.Host != "" {
+		host = parsed.Hostname()
+	}
+
+	// The owner's title wins over the fetched page metadata
+	data := previewData{
+		Title:       link.Title,
+		Host:        host,
+		Destination: destination,
+		ContinueURL: continueURL,
+	}
+	if link.Metadata != nil && destination != "" {
+		if data.Title == "" {
+			data.Title = link.Metadata.Title
+		}
+		data.Description = link.Metadata.Description
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'")
+	w.WriteHeader(http.StatusOK)
+	previewPage.Execute(w, data)
+}
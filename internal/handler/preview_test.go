@@ -0,0 +1,186 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/yasin-yalcin-dev/go-url-shortener/internal/model"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/analytics"
+	customerrors "github.com/yasin-yalcin-dev/go-url-shortener/pkg/errors"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/logger"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/metadata"
+)
+
+func TestShortenHandler_Preview(t *testing.T) {
+	// Prepare test environment
+	setUp(t)
+
+	testCases := []struct {
+		name               string
+		link               model.Link
+		path               string
+		expectedStatusCode int
+		expectedBody       []string
+		unexpectedBody     []string
+		clicksUsedUp       bool
+		expectedCounted    bool
+	}{
+		{
+			name:               "Preview Suffix",
+			link:               model.Link{Original: "https://example.com/docs", Title: "Docs"},
+			path:               "/abc123+",
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       []string{"example.com", "https://example.com/docs", "<strong>Docs</strong>", `href="/abc123"`},
+		},
+		{
+			name:               "Preview Parameter",
+			link:               model.Link{Original: "https://example.com/docs", QueryPassthrough: model.QueryPassthroughKeep},
+			path:               "/abc123?preview=1&utm_source=mail",
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       []string{"https://example.com/docs?utm_source=mail", `href="/abc123?utm_source=mail"`},
+			unexpectedBody:     []string{"preview=1"},
+		},
+		{
+			name:               "Escaped Content",
+			link:               model.Link{Original: "https://example.com/?q=<script>", Title: `<img src=x onerror="alert(1)">`},
+			path:               "/abc123+",
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       []string{"&lt;img src=x onerror=&#34;alert(1)&#34;&gt;"},
+			unexpectedBody:     []string{"<script>", "<img"},
+		},
+		{
+			name: "Fetched Metadata",
+			link: model.Link{Original: "https://example.com/docs", Metadata: &metadata.Metadata{
+				Title:       "Example Docs",
+				Description: "Guides & <b>references</b>",
+			}},
+			path:               "/abc123+",
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       []string{"<strong>Example Docs</strong>", "Guides &amp; &lt;b&gt;references&lt;/b&gt;"},
+		},
+		{
+			name:               "Owner Title Wins",
+			link:               model.Link{Original: "https://example.com/docs", Title: "Docs", Metadata: &metadata.Metadata{Title: "Example Docs"}},
+			path:               "/abc123+",
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       []string{"<strong>Docs</strong>"},
+			unexpectedBody:     []string{"Example Docs"},
+		},
+		{
+			name:               "Interstitial",
+			link:               model.Link{Original: "https://example.com/docs", Interstitial: true},
+			path:               "/abc123",
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       []string{`href="https://example.com/docs"`},
+			expectedCounted:    true,
+		},
+		{
+			name: "Click-Limited Preview",
+			link: model.Link{Original: "https://example.com/download", Title: "Download", MaxClicks: 1, Metadata: &metadata.Metadata{
+				Description: "Your private file",
+			}},
+			path:               "/abc123+",
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       []string{"<strong>Download</strong>", `href="/abc123"`},
+			unexpectedBody:     []string{"example.com", "Your private file"},
+		},
+		{
+			name:               "Used Up Preview",
+			link:               model.Link{Original: "https://example.com/download", MaxClicks: 1},
+			path:               "/abc123?preview=1",
+			clicksUsedUp:       true,
+			expectedStatusCode: http.StatusGone,
+			unexpectedBody:     []string{"example.com"},
+		},
+		{
+			name:               "Protected Preview Needs Password",
+			link:               model.Link{Original: "https://example.com/docs", PasswordHash: "$2a$10$invalid"},
+			path:               "/abc123+",
+			expectedStatusCode: http.StatusUnauthorized,
+			unexpectedBody:     []string{"example.com/docs"},
+		},
+	}
+
+	mockLogger, err := logger.NewTestLogger()
+	if err != nil {
+		t.Fatalf("Failed to create mock logger: %v", err)
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			counted := false
+			recorded := false
+			handler := &ShortenHandler{
+				Service: &mockURLService{
+					getLinkFunc: func(ctx context.Context, shortID string) (*model.Link, error) {
+						if shortID != "abc123" {
+							t.Errorf("Expected short ID abc123, got %s", shortID)
+						}
+						link := tc.link
+						link.ShortID = shortID
+						return &link, nil
+					},
+					consumeFunc: func(ctx context.Context, link *model.Link) error {
+						counted = true
+						return nil
+					},
+					checkFunc: func(ctx context.Context, link *model.Link) error {
+						if tc.clicksUsedUp {
+							return customerrors.New(http.StatusGone, "Link is no longer available")
+						}
+						return nil
+					},
+				},
+				Logger: mockLogger,
+				Analytics: &mockAnalyticsStore{
+					recordEventFunc: func(ctx context.Context, event analytics.AccessEvent) error {
+						recorded = true
+						return nil
+					},
+				},
+			}
+
+			r := chi.NewRouter()
+			r.Get("/{shortened}", handler.Redirect)
+
+			req := httptest.NewRequest("GET", tc.path, nil)
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			if w.Code != tc.expectedStatusCode {
+				t.Errorf("Expected status %d, got %d", tc.expectedStatusCode, w.Code)
+			}
+			body := w.Body.String()
+			for _, expected := range tc.expectedBody {
+				if !strings.Contains(body, expected) {
+					t.Errorf("Expected body to contain %q, got %s", expected, body)
+				}
+			}
+			for _, unexpected := range tc.unexpectedBody {
+				if strings.Contains(body, unexpected) {
+					t.Errorf("Expected body not to contain %q", unexpected)
+				}
+			}
+			if counted != tc.expectedCounted || recorded != tc.expectedCounted {
+				t.Errorf("Expected click counted %v, got consumed %v and recorded %v", tc.expectedCounted, counted, recorded)
+			}
+			if w.Header().Get("Location") != "" {
+				t.Errorf("Expected no redirect, got %s", w.Header().Get("Location"))
+			}
+		})
+	}
+}
@@ -10,46 +10,91 @@
 package handler
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
+	"math"
 	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/yasin-yalcin-dev/go-url-shortener/internal/model"
 	"github.com/yasin-yalcin-dev/go-url-shortener/internal/service"
 	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/analytics"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/botfilter"
 	customerrors "github.com/yasin-yalcin-dev/go-url-shortener/pkg/errors"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/geoip"
 	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/logger"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/metrics"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/privacy"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/ratelimiter"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/targeting"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/tracing"
 
+	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/zap"
 
 	"github.com/go-chi/chi/v5"
 )
 
+const (
+	// permanentRedirectMaxAge is how long clients may cache a permanent redirect
+	permanentRedirectMaxAge = 24 * time.Hour
+
+	// variantCookiePrefix prefixes the cookie keeping a visitor on its A/B variant
+	variantCookiePrefix = "ab_"
+
+	// variantCookieMaxAge is how long a visitor keeps its A/B variant
+	variantCookieMaxAge = 30 * 24 * time.Hour
+)
+
 type ShortenHandler struct {
-	Service   service.URLShorteningService
-	Logger    *logger.Logger
-	Analytics analytics.AnalyticsStoreInterface
+	Service       service.URLShorteningService
+	Logger        *logger.Logger
+	Analytics     analytics.AnalyticsStoreInterface
+	BotClassifier *botfilter.Classifier // Optional, bot hits are counted separately
+	// Optional, throttles password attempts per client and link
+	PasswordLimiter *ratelimiter.RateLimiter
+	// Optional, throttles password attempts per link across all clients
+	PasswordLinkLimiter *ratelimiter.RateLimiter
+	// Proxies whose X-Forwarded-For header identifies the client for password throttling
+	TrustedProxies []netip.Prefix
+	// Optional, resolves countries when no proxy header is set
+	GeoIP geoip.Resolver
+	// Optional, counts redirect hits and misses
+	Metrics *metrics.Metrics
 }
 
 // ShortenURL will create a shortened URL
 func (h *ShortenHandler) ShortenURL(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "ShortenHandler.ShortenURL")
+	defer span.End()
+	r = r.WithContext(ctx)
+
 	var urlRequest struct {
-		Original string        `json:"original"`
-		TTL      time.Duration `json:"ttl,omitempty"`
+		Original         string              `json:"original"`
+		TTL              model.Duration      `json:"ttl,omitempty"`
+		ExpiresAt        *time.Time          `json:"expires_at,omitempty"`
+		NotBefore        *time.Time          `json:"not_before,omitempty"`
+		Owner            string              `json:"owner,omitempty"`
+		Tags             []string            `json:"tags,omitempty"`
+		NoTracking       bool                `json:"no_tracking,omitempty"`
+		RedirectCode     int                 `json:"redirect_code,omitempty"`
+		QueryPassthrough string              `json:"query_passthrough,omitempty"`
+		PathPassthrough  bool                `json:"path_passthrough,omitempty"`
+		Password         string              `json:"password,omitempty"`
+		MaxClicks        int64               `json:"max_clicks,omitempty"`
+		Rules            []targeting.Rule    `json:"rules,omitempty"`
+		Variants         []targeting.Variant `json:"variants,omitempty"`
+		Title            string              `json:"title,omitempty"`
+		Interstitial     bool                `json:"interstitial,omitempty"`
 	}
 
-	// Log incoming request
-	h.Logger.Info("Received URL shortening request",
-		zap.String("method", r.Method),
-		zap.String("path", r.URL.Path),
-	)
-
 	// Decode request body
 	if err := json.NewDecoder(r.Body).Decode(&urlRequest); err != nil {
-		h.Logger.Error("Failed to decode request body",
+		h.Logger.FromContext(r.Context()).Error("Failed to decode request body",
 			zap.Error(err),
-			zap.String("body", fmt.Sprintf("%+v", r.Body)),
 		)
 		apiErr := customerrors.New(
 			http.StatusBadRequest,
@@ -61,21 +106,57 @@ func (h *ShortenHandler) ShortenURL(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Shorten URL
-	var shortenedURL string
-	var err error
-	if urlRequest.TTL > 0 {
-		shortenedURL, err = h.Service.ShortenURL(
-			r.Context(),
-			urlRequest.Original,
-			service.WithTTL(urlRequest.TTL),
-		)
-	} else {
-		shortenedURL, err = h.Service.ShortenURL(r.Context(), urlRequest.Original)
+	var options []service.URLShortenOption
+	if urlRequest.TTL != 0 {
+		options = append(options, service.WithTTL(time.Duration(urlRequest.TTL)))
+	}
+	if urlRequest.ExpiresAt != nil {
+		options = append(options, service.WithExpiresAt(*urlRequest.ExpiresAt))
+	}
+	if urlRequest.NotBefore != nil {
+		options = append(options, service.WithNotBefore(*urlRequest.NotBefore))
+	}
+	if urlRequest.Owner != "" {
+		options = append(options, service.WithOwner(urlRequest.Owner))
+	}
+	if len(urlRequest.Tags) > 0 {
+		options = append(options, service.WithTags(urlRequest.Tags...))
+	}
+	if urlRequest.NoTracking {
+		options = append(options, service.WithNoTracking())
+	}
+	if urlRequest.RedirectCode != 0 {
+		options = append(options, service.WithRedirectCode(urlRequest.RedirectCode))
+	}
+	if urlRequest.QueryPassthrough != "" {
+		options = append(options, service.WithQueryPassthrough(urlRequest.QueryPassthrough))
+	}
+	if urlRequest.PathPassthrough {
+		options = append(options, service.WithPathPassthrough())
 	}
+	if urlRequest.Password != "" {
+		options = append(options, service.WithPassword(urlRequest.Password))
+	}
+	if urlRequest.MaxClicks != 0 {
+		options = append(options, service.WithMaxClicks(urlRequest.MaxClicks))
+	}
+	if len(urlRequest.Rules) > 0 {
+		options = append(options, service.WithRules(urlRequest.Rules...))
+	}
+	if len(urlRequest.Variants) > 0 {
+		options = append(options, service.WithVariants(urlRequest.Variants...))
+	}
+	if urlRequest.Title != "" {
+		options = append(options, service.WithTitle(urlRequest.Title))
+	}
+	if urlRequest.Interstitial {
+		options = append(options, service.WithInterstitial())
+	}
+	shortenedURL, err := h.Service.ShortenURL(r.Context(), urlRequest.Original, options...)
 	if err != nil {
-		h.Logger.Error("URL shortening failed",
+		h.Logger.FromContext(r.Context()).Error("URL shortening failed",
 			zap.Error(err),
-			zap.String("originalURL", urlRequest.Original),
+			zap.String("originalURL", h.Logger.RedactURL(urlRequest.Original)),
 		)
 		// Check if it's an APIError
 		if apiErr, ok := err.(*customerrors.APIError); ok {
@@ -87,12 +168,6 @@ func (h *ShortenHandler) ShortenURL(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Log successful shortening
-	h.Logger.Info("URL successfully shortened",
-		zap.String("originalURL", urlRequest.Original),
-		zap.String("shortenedURL", shortenedURL),
-	)
-
 	response := map[string]string{
 		"original":  urlRequest.Original,
 		"shortened": shortenedURL,
@@ -105,22 +180,24 @@ func (h *ShortenHandler) ShortenURL(w http.ResponseWriter, r *http.Request) {
 
 // Redirect will handle redirection from short URL to the original URL
 func (h *ShortenHandler) Redirect(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "ShortenHandler.Redirect")
+	defer span.End()
+	r = r.WithContext(ctx)
+
 	// Get the short ID from the URL
-	shortID := chi.URLParam(r, "shortened")
+	shortID, preview := previewRequested(r, chi.URLParam(r, "shortened"))
+	span.SetAttributes(attribute.String("short_id", shortID))
 
-	// Log redirect attempt
-	h.Logger.Info("Redirect attempt",
-		zap.String("shortID", shortID),
-	)
-	// Fetch original URL from Redis
-	originalURL, err := h.Service.GetOriginalURL(r.Context(), shortID)
+	// Fetch link from Redis
+	link, err := h.Service.GetLink(r.Context(), shortID)
 	if err != nil {
-		h.Logger.Error("URL redirect failed",
+		h.Logger.FromContext(r.Context()).Error("URL redirect failed",
 			zap.Error(err),
 			zap.String("shortID", shortID),
 		)
 
 		// Handle URL not found
+		h.Metrics.Redirect(metrics.RedirectMiss)
 		apiErr := customerrors.New(
 			http.StatusNotFound,
 			"Short URL not found",
@@ -129,35 +206,145 @@ func (h *ShortenHandler) Redirect(w http.ResponseWriter, r *http.Request) {
 		apiErr.WriteResponse(w)
 		return
 	}
-	// Save analytics
-	go func() {
-		// Get client IP
-		ip := getClientIP(r)
-
-		// Save URL access analytics
-		if err := h.Analytics.RecordURLAccess(context.Background(), shortID, ip); err != nil {
-			h.Logger.Error("Failed to record URL access",
-				zap.Error(err),
-				zap.String("shortID", shortID),
-			)
+	// Scheduled links are not served before their activation time
+	now := time.Now()
+	if link.Expired(now) {
+		h.Metrics.Redirect(metrics.RedirectMiss)
+		customerrors.New(
+			http.StatusNotFound,
+			"Short URL not found",
+			"The requested short URL does not exist",
+		).WriteResponse(w)
+		return
+	}
+	if !link.Active(now) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(link.NotBefore.Sub(now).Seconds()))))
+		customerrors.New(
+			http.StatusTooEarly,
+			"Link is not active yet",
+			fmt.Sprintf("The link becomes active at %s", link.NotBefore.UTC().Format(time.RFC3339)),
+		).WriteResponse(w)
+		return
+	}
+	// Wildcard paths only resolve for links with path passthrough
+	suffix := chi.URLParam(r, "*")
+	if suffix != "" && !link.PathPassthrough {
+		h.Metrics.Redirect(metrics.RedirectMiss)
+		customerrors.New(
+			http.StatusNotFound,
+			"Short URL not found",
+			"The requested short URL does not exist",
+		).WriteResponse(w)
+		return
+	}
+	// Targeting rules pick the destination per visitor
+	target, matched := link.Original, false
+	if len(link.Rules) > 0 {
+		target, matched = link.Target(targeting.NewVisitor(r, h.clientCountry(r), now))
+	}
+	// Visitors no rule matched are split between the A/B variants
+	var variant string
+	if !matched && len(link.Variants) > 0 {
+		if chosen, ok := h.chooseVariant(w, r, link); ok {
+			target, variant = chosen.Target, chosen.Name
 		}
-	}()
-	// Log successful redirect
-	h.Logger.Info("Successful redirect",
-		zap.String("shortID", shortID),
-		zap.String("originalURL", originalURL),
-	)
+	}
+	query := r.URL.Query()
+	if preview {
+		query = withoutPreview(query)
+	}
+	destination, err := link.Destination(target, suffix, query)
+	if err != nil {
+		h.Logger.FromContext(r.Context()).Error("Failed to build redirect destination",
+			zap.Error(err),
+			zap.String("shortID", shortID),
+		)
+		customerrors.ErrInternal.WriteResponse(w)
+		return
+	}
+	// Protected links redirect only once the password is verified
+	if link.Protected() && !h.unlock(w, r, link) {
+		return
+	}
+	// Explicit previews do not count a click. Used up links are gone, and
+	// the destination of click-limited or protected links is only shown
+	// when the link is followed.
+	if preview {
+		if err := h.Service.CheckClicks(r.Context(), link); err != nil {
+			h.writeClickRefused(w, r, shortID, err)
+			return
+		}
+		if link.MaxClicks > 0 || link.Protected() {
+			destination = ""
+		}
+		writePreview(w, link, destination, previewContinueURL(r, shortID))
+		return
+	}
+	// HEAD requests and bots do not use up clicks, so click-limited links
+	// answer them without revealing the destination. The bot signals are
+	// set by the client and must never unlock the target.
+	bot := h.isBot(r)
+	if bot && link.MaxClicks > 0 {
+		if err := h.Service.CheckClicks(r.Context(), link); err != nil {
+			h.writeClickRefused(w, r, shortID, err)
+			return
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	// Click-limited links count the redirect before it is served
+	if err := h.Service.ConsumeClick(r.Context(), link); err != nil {
+		h.writeClickRefused(w, r, shortID, err)
+		return
+	}
+	h.Metrics.Redirect(metrics.RedirectHit)
+	// Queue analytics, the analytics store is expected to be non-blocking
+	if h.Analytics != nil && !link.NoTracking {
+		h.recordAccess(r, link, variant, bot)
+	}
+	// Interstitial links always show the destination first
+	if link.Interstitial {
+		writePreview(w, link, destination, destination)
+		return
+	}
+	// Permanent redirects may be cached by clients and proxies, later clicks
+	// then skip the redirect and are not counted in analytics
+	switch {
+	case link.Protected(), link.MaxClicks > 0:
+		w.Header().Set("Cache-Control", "no-store")
+	case len(link.Rules) > 0, len(link.Variants) > 0:
+		// The destination differs per visitor, shared caches must not store it
+		w.Header().Set("Cache-Control", "private, no-cache")
+	case link.IsPermanent():
+		// Never cache beyond the expiry of the link
+		maxAge := permanentRedirectMaxAge
+		if link.ExpiresAt != nil && link.ExpiresAt.Sub(now) < maxAge {
+			maxAge = link.ExpiresAt.Sub(now)
+		}
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds())))
+	default:
+		w.Header().Set("Cache-Control", "private, no-cache")
+	}
+	status := link.RedirectStatus()
+	if unlockedByForm(r, link) {
+		status = http.StatusSeeOther
+	}
 	// Redirect to the original URL
-	http.Redirect(w, r, originalURL, http.StatusFound)
+	http.Redirect(w, r, destination, status)
 }
 
 func (h *ShortenHandler) GetURLAnalytics(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "ShortenHandler.GetURLAnalytics")
+	defer span.End()
+	r = r.WithContext(ctx)
+
 	shortID := chi.URLParam(r, "shortened")
 
 	// get analytics from the store
 	analytics, err := h.Analytics.GetURLAnalytics(r.Context(), shortID)
 	if err != nil {
-		h.Logger.Error("Failed to get URL analytics",
+		h.Logger.FromContext(r.Context()).Error("Failed to get URL analytics",
 			zap.Error(err),
 			zap.String("shortID", shortID),
 		)
@@ -169,15 +356,143 @@ func (h *ShortenHandler) GetURLAnalytics(w http.ResponseWriter, r *http.Request)
 	json.NewEncoder(w).Encode(analytics)
 }
 
-// getClientIP get the client IP address from the request
-func getClientIP(r *http.Request) string {
-	// Use X-Forwarded-For or X-Real-IP if available
-	ip := r.Header.Get("X-Forwarded-For")
-	if ip == "" {
-		ip = r.Header.Get("X-Real-IP")
+// writeClickRefused writes the response for a click the service refused
+func (h *ShortenHandler) writeClickRefused(w http.ResponseWriter, r *http.Request, shortID string, err error) {
+	h.Logger.FromContext(r.Context()).Warn("Link click refused",
+		zap.Error(err),
+		zap.String("shortID", shortID),
+	)
+	if apiErr, ok := err.(*customerrors.APIError); ok {
+		h.Metrics.Redirect(metrics.RedirectMiss)
+		apiErr.WriteResponse(w)
+		return
+	}
+	customerrors.ErrInternal.WriteResponse(w)
+}
+
+// isBot reports whether the request is a HEAD request or classified as a bot
+func (h *ShortenHandler) isBot(r *http.Request) bool {
+	if r.Method == http.MethodHead {
+		return true
+	}
+	return h.BotClassifier != nil && h.BotClassifier.IsBot(r)
+}
+
+// recordAccess hands the access event to the analytics store
+func (h *ShortenHandler) recordAccess(r *http.Request, link *model.Link, variant string, bot bool) {
+	event := analytics.AccessEvent{
+		ShortID:   link.ShortID,
+		IPAddress: privacy.ClientIP(r),
+		UserAgent: r.UserAgent(),
+		Referrer:  r.Referer(),
+		Country:   h.clientCountry(r),
+		Owner:     link.Owner,
+		Tags:      link.Tags,
+		Domain:    link.Domain(),
+		Bot:       bot,
+		Variant:   variant,
+		Timestamp: time.Now(),
+	}
+	if err := h.Analytics.RecordAccessEvent(r.Context(), event); err != nil {
+		h.Logger.FromContext(r.Context()).Warn("Failed to record URL access",
+			zap.Error(err),
+			zap.String("shortID", link.ShortID),
+		)
+	}
+}
+
+// PurgeURLAnalytics deletes all analytics recorded for a URL
+func (h *ShortenHandler) PurgeURLAnalytics(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "ShortenHandler.PurgeURLAnalytics")
+	defer span.End()
+	r = r.WithContext(ctx)
+
+	shortID := chi.URLParam(r, "shortened")
+
+	purger, ok := h.Analytics.(analytics.Purger)
+	if !ok {
+		customerrors.New(
+			http.StatusNotImplemented,
+			"Analytics purge is not available",
+		).WriteResponse(w)
+		return
+	}
+
+	if err := purger.PurgeURLAnalytics(r.Context(), shortID); err != nil {
+		h.Logger.FromContext(r.Context()).Error("Failed to purge URL analytics",
+			zap.Error(err),
+			zap.String("shortID", shortID),
+		)
+		customerrors.ErrInternal.WriteResponse(w)
+		return
+	}
+
+	h.Logger.FromContext(r.Context()).Info("URL analytics purged",
+		zap.String("shortID", shortID),
+	)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteURL deletes a link, its analytics are kept until purged
+func (h *ShortenHandler) DeleteURL(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracing.Start(r.Context(), "ShortenHandler.DeleteURL")
+	defer span.End()
+	r = r.WithContext(ctx)
+
+	shortID := chi.URLParam(r, "shortened")
+
+	if err := h.Service.DeleteURL(r.Context(), shortID); err != nil {
+		if apiErr, ok := err.(*customerrors.APIError); ok {
+			apiErr.WriteResponse(w)
+			return
+		}
+		h.Logger.FromContext(r.Context()).Error("Failed to delete URL",
+			zap.Error(err),
+			zap.String("shortID", shortID),
+		)
+		customerrors.ErrInternal.WriteResponse(w)
+		return
 	}
-	if ip == "" {
-		ip = r.RemoteAddr
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// chooseVariant picks the A/B variant of a visitor. A returning visitor
+// keeps the variant stored in its cookie, others are assigned by a hash of
+// their IP so repeat visits without cookies stay on the same variant.
+func (h *ShortenHandler) chooseVariant(w http.ResponseWriter, r *http.Request, link *model.Link) (targeting.Variant, bool) {
+	name := variantCookiePrefix + link.ShortID
+	if cookie, err := r.Cookie(name); err == nil {
+		if variant, ok := targeting.FindVariant(link.Variants, cookie.Value); ok {
+			return variant, true
+		}
+	}
+
+	variant, ok := targeting.PickVariant(link.Variants, link.ShortID+"|"+privacy.ClientIP(r))
+	if !ok {
+		return variant, false
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    variant.Name,
+		Path:     "/" + link.ShortID,
+		MaxAge:   int(variantCookieMaxAge.Seconds()),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return variant, true
+}
+
+// clientCountry returns the country code set by an upstream proxy or CDN,
+// falling back to the GeoIP database
+func (h *ShortenHandler) clientCountry(r *http.Request) string {
+	country := r.Header.Get("CF-IPCountry")
+	if country == "" {
+		country = r.Header.Get("X-Country-Code")
+	}
+	if country == "" && h.GeoIP != nil {
+		if addr, err := privacy.ParseIP(privacy.ClientIP(r)); err == nil {
+			country = h.GeoIP.Country(addr)
+		}
 	}
-	return ip
+	return strings.ToUpper(country)
 }
@@ -13,23 +13,38 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"net/netip"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
-	"github.com/go-chi/chi"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-chi/chi/v5"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/yasin-yalcin-dev/go-url-shortener/internal/config"
 	"github.com/yasin-yalcin-dev/go-url-shortener/internal/model"
+	"github.com/yasin-yalcin-dev/go-url-shortener/internal/redis"
 	"github.com/yasin-yalcin-dev/go-url-shortener/internal/service"
 	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/analytics"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/botfilter"
 	customerrors "github.com/yasin-yalcin-dev/go-url-shortener/pkg/errors"
 	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/logger"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/metrics"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/targeting"
 )
 
 // mockURLService simulates the URL shortening service for testing
 type mockURLService struct {
 	shortenFunc     func(ctx context.Context, url string, options ...service.URLShortenOption) (string, error)
 	getOriginalFunc func(ctx context.Context, shortID string) (string, error)
+	getLinkFunc     func(ctx context.Context, shortID string) (*model.Link, error)
+	consumeFunc     func(ctx context.Context, link *model.Link) error
+	checkFunc       func(ctx context.Context, link *model.Link) error
+	deleteFunc      func(ctx context.Context, shortID string) error
 }
 
 // ShortenURL implements the URL shortening method for the mock service
@@ -39,8 +54,9 @@ func (m *mockURLService) ShortenURL(ctx context.Context, url string, options ...
 
 // mockAnalyticsStore simulates the analytics store for testing
 type mockAnalyticsStore struct {
-	recordFunc func(ctx context.Context, shortID, ipAddress string) error
-	getFunc    func(ctx context.Context, shortID string) (*analytics.URLAnalytics, error)
+	recordFunc      func(ctx context.Context, shortID, ipAddress string) error
+	recordEventFunc func(ctx context.Context, event analytics.AccessEvent) error
+	getFunc         func(ctx context.Context, shortID string) (*analytics.URLAnalytics, error)
 }
 
 // RecordURLAccess implements the URL access recording method for the mock analytics store
@@ -51,6 +67,14 @@ func (m *mockAnalyticsStore) RecordURLAccess(ctx context.Context, shortID, ipAdd
 	return nil
 }
 
+// RecordAccessEvent implements the access event recording method for the mock analytics store
+func (m *mockAnalyticsStore) RecordAccessEvent(ctx context.Context, event analytics.AccessEvent) error {
+	if m.recordEventFunc != nil {
+		return m.recordEventFunc(ctx, event)
+	}
+	return m.RecordURLAccess(ctx, event.ShortID, event.IPAddress)
+}
+
 // GetURLAnalytics implements the URL analytics retrieval method
 func (m *mockAnalyticsStore) GetURLAnalytics(ctx context.Context, shortID string) (*analytics.URLAnalytics, error) {
 	if m.getFunc != nil {
@@ -64,6 +88,43 @@ func (m *mockURLService) GetOriginalURL(ctx context.Context, shortID string) (st
 	return m.getOriginalFunc(ctx, shortID)
 }
 
+// GetLink implements the link retrieval method for the mock service,
+// falling back to the original URL mock when no link mock is set
+func (m *mockURLService) GetLink(ctx context.Context, shortID string) (*model.Link, error) {
+	if m.getLinkFunc != nil {
+		return m.getLinkFunc(ctx, shortID)
+	}
+	originalURL, err := m.getOriginalFunc(ctx, shortID)
+	if err != nil {
+		return nil, err
+	}
+	return &model.Link{ShortID: shortID, Original: originalURL}, nil
+}
+
+// ConsumeClick implements the click counting method for the mock service
+func (m *mockURLService) ConsumeClick(ctx context.Context, link *model.Link) error {
+	if m.consumeFunc != nil {
+		return m.consumeFunc(ctx, link)
+	}
+	return nil
+}
+
+// CheckClicks implements the click limit check for the mock service
+func (m *mockURLService) CheckClicks(ctx context.Context, link *model.Link) error {
+	if m.checkFunc != nil {
+		return m.checkFunc(ctx, link)
+	}
+	return nil
+}
+
+// DeleteURL implements the link deletion method for the mock service
+func (m *mockURLService) DeleteURL(ctx context.Context, shortID string) error {
+	if m.deleteFunc != nil {
+		return m.deleteFunc(ctx, shortID)
+	}
+	return nil
+}
+
 // setUp prepares the test environment
 func setUp(t *testing.T) {
 	// Ensure logs directory exists
@@ -118,7 +179,7 @@ func TestShortenHandler_ShortenURL(t *testing.T) {
 			}
 
 			// Create mock logger
-			mockLogger, err := logger.New("info")
+			mockLogger, err := logger.NewTestLogger()
 			if err != nil {
 				t.Fatalf("Failed to create mock logger: %v", err)
 			}
@@ -222,7 +283,7 @@ func TestShortenHandler_Redirect(t *testing.T) {
 			}
 
 			// Create mock logger
-			mockLogger, err := logger.New("info")
+			mockLogger, err := logger.NewTestLogger()
 			if err != nil {
 				t.Fatalf("Failed to create mock logger: %v", err)
 			}
@@ -263,3 +324,940 @@ func TestShortenHandler_Redirect(t *testing.T) {
 		})
 	}
 }
+
+func TestShortenHandler_RedirectBotClassification(t *testing.T) {
+	// Prepare test environment
+	setUp(t)
+
+	classifier, err := botfilter.NewClassifier()
+	if err != nil {
+		t.Fatalf("Failed to create bot classifier: %v", err)
+	}
+
+	testCases := []struct {
+		name        string
+		userAgent   string
+		expectedBot bool
+	}{
+		{
+			name:        "Browser",
+			userAgent:   "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_0) AppleWebKit/605.1.15 Safari/605.1.15",
+			expectedBot: false,
+		},
+		{
+			name:        "Link Preview Bot",
+			userAgent:   "Twitterbot/1.0",
+			expectedBot: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var recorded *analytics.AccessEvent
+			mockAnalytics := &mockAnalyticsStore{
+				recordEventFunc: func(ctx context.Context, event analytics.AccessEvent) error {
+					recorded = &event
+					return nil
+				},
+			}
+			mockService := &mockURLService{
+				getOriginalFunc: func(ctx context.Context, shortID string) (string, error) {
+					return "https://example.com", nil
+				},
+			}
+
+			mockLogger, err := logger.NewTestLogger()
+			if err != nil {
+				t.Fatalf("Failed to create mock logger: %v", err)
+			}
+
+			handler := &ShortenHandler{
+				Service:       mockService,
+				Logger:        mockLogger,
+				Analytics:     mockAnalytics,
+				BotClassifier: classifier,
+			}
+
+			req, _ := http.NewRequest("GET", "/abc123", nil)
+			req.Header.Set("User-Agent", tc.userAgent)
+			req.Header.Set("Accept", "text/html")
+			w := httptest.NewRecorder()
+
+			handler.Redirect(w, req)
+
+			if recorded == nil {
+				t.Fatalf("Expected access to be recorded")
+			}
+			if recorded.Bot != tc.expectedBot {
+				t.Errorf("Expected bot %v, got %v", tc.expectedBot, recorded.Bot)
+			}
+		})
+	}
+}
+
+func TestShortenHandler_RedirectNoTracking(t *testing.T) {
+	// Prepare test environment
+	setUp(t)
+
+	recorded := false
+	mockAnalytics := &mockAnalyticsStore{
+		recordEventFunc: func(ctx context.Context, event analytics.AccessEvent) error {
+			recorded = true
+			return nil
+		},
+	}
+	mockService := &mockURLService{
+		getLinkFunc: func(ctx context.Context, shortID string) (*model.Link, error) {
+			return &model.Link{ShortID: shortID, Original: "https://example.com", NoTracking: true}, nil
+		},
+	}
+
+	mockLogger, err := logger.NewTestLogger()
+	if err != nil {
+		t.Fatalf("Failed to create mock logger: %v", err)
+	}
+
+	handler := &ShortenHandler{
+		Service:   mockService,
+		Logger:    mockLogger,
+		Analytics: mockAnalytics,
+	}
+
+	req, _ := http.NewRequest("GET", "/abc123", nil)
+	w := httptest.NewRecorder()
+
+	handler.Redirect(w, req)
+
+	if w.Code != http.StatusFound {
+		t.Errorf("Expected status %d, got %d", http.StatusFound, w.Code)
+	}
+	if recorded {
+		t.Errorf("Expected no analytics for a no-tracking link")
+	}
+}
+
+// mockPurgeStore adds purge support to the mock analytics store
+type mockPurgeStore struct {
+	mockAnalyticsStore
+	purged int
+}
+
+// PurgeURLAnalytics implements the purge method for the mock store
+func (m *mockPurgeStore) PurgeURLAnalytics(ctx context.Context, shortID string) error {
+	m.purged++
+	return nil
+}
+
+func TestShortenHandler_PurgeURLAnalytics(t *testing.T) {
+	// Prepare test environment
+	setUp(t)
+
+	mockLogger, err := logger.NewTestLogger()
+	if err != nil {
+		t.Fatalf("Failed to create mock logger: %v", err)
+	}
+
+	testCases := []struct {
+		name               string
+		store              analytics.AnalyticsStoreInterface
+		expectedStatusCode int
+	}{
+		{
+			name:               "Purged",
+			store:              &mockPurgeStore{},
+			expectedStatusCode: http.StatusNoContent,
+		},
+		{
+			name:               "Purge Not Supported",
+			store:              &mockAnalyticsStore{},
+			expectedStatusCode: http.StatusNotImplemented,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := &ShortenHandler{
+				Service:   &mockURLService{},
+				Logger:    mockLogger,
+				Analytics: tc.store,
+			}
+
+			req, _ := http.NewRequest("DELETE", "/abc123/analytics", nil)
+			w := httptest.NewRecorder()
+
+			handler.PurgeURLAnalytics(w, req)
+
+			if w.Code != tc.expectedStatusCode {
+				t.Errorf("Expected status %d, got %d", tc.expectedStatusCode, w.Code)
+			}
+			if store, ok := tc.store.(*mockPurgeStore); ok && store.purged != 1 {
+				t.Errorf("Expected one purge, got %d", store.purged)
+			}
+		})
+	}
+}
+
+func TestShortenHandler_DeleteURL(t *testing.T) {
+	mockLogger, err := logger.NewTestLogger()
+	if err != nil {
+		t.Fatalf("Failed to create mock logger: %v", err)
+	}
+
+	testCases := []struct {
+		name               string
+		deleteErr          error
+		expectedStatusCode int
+	}{
+		{
+			name:               "Deleted",
+			expectedStatusCode: http.StatusNoContent,
+		},
+		{
+			name:               "Not Found",
+			deleteErr:          customerrors.New(http.StatusNotFound, "Short URL not found"),
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "Store Error",
+			deleteErr:          errors.New("connection refused"),
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var deleted string
+			handler := &ShortenHandler{
+				Service: &mockURLService{
+					deleteFunc: func(ctx context.Context, shortID string) error {
+						deleted = shortID
+						return tc.deleteErr
+					},
+				},
+				Logger: mockLogger,
+			}
+
+			r := chi.NewRouter()
+			r.Delete("/admin/links/{shortened}", handler.DeleteURL)
+			req := httptest.NewRequest(http.MethodDelete, "/admin/links/abc123", nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tc.expectedStatusCode {
+				t.Errorf("Expected status %d, got %d", tc.expectedStatusCode, w.Code)
+			}
+			if deleted != "abc123" {
+				t.Errorf("Expected abc123 to be deleted, got %q", deleted)
+			}
+		})
+	}
+}
+
+func TestShortenHandler_RedirectCode(t *testing.T) {
+	// Prepare test environment
+	setUp(t)
+
+	testCases := []struct {
+		name                 string
+		redirectCode         int
+		expectedStatusCode   int
+		expectedCacheControl string
+	}{
+		{
+			name:                 "Default Temporary",
+			redirectCode:         0,
+			expectedStatusCode:   http.StatusFound,
+			expectedCacheControl: "private, no-cache",
+		},
+		{
+			name:                 "Moved Permanently",
+			redirectCode:         http.StatusMovedPermanently,
+			expectedStatusCode:   http.StatusMovedPermanently,
+			expectedCacheControl: "public, max-age=86400",
+		},
+		{
+			name:                 "Temporary Preserve Method",
+			redirectCode:         http.StatusTemporaryRedirect,
+			expectedStatusCode:   http.StatusTemporaryRedirect,
+			expectedCacheControl: "private, no-cache",
+		},
+		{
+			name:                 "Permanent Preserve Method",
+			redirectCode:         http.StatusPermanentRedirect,
+			expectedStatusCode:   http.StatusPermanentRedirect,
+			expectedCacheControl: "public, max-age=86400",
+		},
+	}
+
+	mockLogger, err := logger.NewTestLogger()
+	if err != nil {
+		t.Fatalf("Failed to create mock logger: %v", err)
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockService := &mockURLService{
+				getLinkFunc: func(ctx context.Context, shortID string) (*model.Link, error) {
+					return &model.Link{ShortID: shortID, Original: "https://example.com", RedirectCode: tc.redirectCode}, nil
+				},
+			}
+			handler := &ShortenHandler{
+				Service: mockService,
+				Logger:  mockLogger,
+			}
+
+			req, _ := http.NewRequest("POST", "/abc123", nil)
+			w := httptest.NewRecorder()
+
+			handler.Redirect(w, req)
+
+			if w.Code != tc.expectedStatusCode {
+				t.Errorf("Expected status %d, got %d", tc.expectedStatusCode, w.Code)
+			}
+			if location := w.Header().Get("Location"); location != "https://example.com" {
+				t.Errorf("Expected location https://example.com, got %s", location)
+			}
+			if cacheControl := w.Header().Get("Cache-Control"); cacheControl != tc.expectedCacheControl {
+				t.Errorf("Expected Cache-Control %q, got %q", tc.expectedCacheControl, cacheControl)
+			}
+		})
+	}
+}
+
+func TestShortenHandler_RedirectPassthrough(t *testing.T) {
+	// Prepare test environment
+	setUp(t)
+
+	testCases := []struct {
+		name               string
+		link               model.Link
+		path               string
+		expectedStatusCode int
+		expectedLocation   string
+	}{
+		{
+			name:               "Query Dropped By Default",
+			link:               model.Link{Original: "https://example.com/page?ref=site"},
+			path:               "/abc123?utm_source=mail",
+			expectedStatusCode: http.StatusFound,
+			expectedLocation:   "https://example.com/page?ref=site",
+		},
+		{
+			name:               "Query Merged",
+			link:               model.Link{Original: "https://example.com/page?ref=site", QueryPassthrough: model.QueryPassthroughKeep},
+			path:               "/abc123?utm_source=mail&utm_campaign=spring",
+			expectedStatusCode: http.StatusFound,
+			expectedLocation:   "https://example.com/page?ref=site&utm_campaign=spring&utm_source=mail",
+		},
+		{
+			name:               "Destination Wins Conflict",
+			link:               model.Link{Original: "https://example.com/page?ref=site", QueryPassthrough: model.QueryPassthroughKeep},
+			path:               "/abc123?ref=other",
+			expectedStatusCode: http.StatusFound,
+			expectedLocation:   "https://example.com/page?ref=site",
+		},
+		{
+			name:               "Incoming Wins Conflict",
+			link:               model.Link{Original: "https://example.com/page?ref=site", QueryPassthrough: model.QueryPassthroughOverride},
+			path:               "/abc123?ref=other",
+			expectedStatusCode: http.StatusFound,
+			expectedLocation:   "https://example.com/page?ref=other",
+		},
+		{
+			name:               "Path Appended",
+			link:               model.Link{Original: "https://example.com/docs/", PathPassthrough: true},
+			path:               "/abc123/guide/install",
+			expectedStatusCode: http.StatusFound,
+			expectedLocation:   "https://example.com/docs/guide/install",
+		},
+		{
+			name:               "Path Cannot Climb Above Target",
+			link:               model.Link{Original: "https://example.com/docs", PathPassthrough: true},
+			path:               "/abc123/../../admin",
+			expectedStatusCode: http.StatusFound,
+			expectedLocation:   "https://example.com/docs/admin",
+		},
+		{
+			name:               "Path And Query",
+			link:               model.Link{Original: "https://example.com/docs", PathPassthrough: true, QueryPassthrough: model.QueryPassthroughKeep},
+			path:               "/abc123/guide?utm_source=mail",
+			expectedStatusCode: http.StatusFound,
+			expectedLocation:   "https://example.com/docs/guide?utm_source=mail",
+		},
+		{
+			name:               "Path Without Passthrough",
+			link:               model.Link{Original: "https://example.com/docs"},
+			path:               "/abc123/guide",
+			expectedStatusCode: http.StatusNotFound,
+		},
+	}
+
+	mockLogger, err := logger.NewTestLogger()
+	if err != nil {
+		t.Fatalf("Failed to create mock logger: %v", err)
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockService := &mockURLService{
+				getLinkFunc: func(ctx context.Context, shortID string) (*model.Link, error) {
+					if shortID != "abc123" {
+						t.Errorf("Expected short ID abc123, got %s", shortID)
+					}
+					link := tc.link
+					link.ShortID = shortID
+					return &link, nil
+				},
+			}
+			handler := &ShortenHandler{
+				Service: mockService,
+				Logger:  mockLogger,
+			}
+
+			r := chi.NewRouter()
+			r.Get("/{shortened}", handler.Redirect)
+			r.Get("/{shortened}/*", handler.Redirect)
+
+			req := httptest.NewRequest("GET", tc.path, nil)
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			if w.Code != tc.expectedStatusCode {
+				t.Errorf("Expected status %d, got %d", tc.expectedStatusCode, w.Code)
+			}
+			if location := w.Header().Get("Location"); location != tc.expectedLocation {
+				t.Errorf("Expected location %q, got %q", tc.expectedLocation, location)
+			}
+		})
+	}
+}
+
+func TestShortenHandler_RedirectClickLimit(t *testing.T) {
+	// Prepare test environment
+	setUp(t)
+
+	mockLogger, err := logger.NewTestLogger()
+	if err != nil {
+		t.Fatalf("Failed to create mock logger: %v", err)
+	}
+
+	used := 0
+	recorded := 0
+	appMetrics := metrics.New()
+	handler := &ShortenHandler{
+		Metrics: appMetrics,
+		Service: &mockURLService{
+			getLinkFunc: func(ctx context.Context, shortID string) (*model.Link, error) {
+				return &model.Link{ShortID: shortID, Original: "https://example.com/file", MaxClicks: 1}, nil
+			},
+			consumeFunc: func(ctx context.Context, link *model.Link) error {
+				used++
+				if used > int(link.MaxClicks) {
+					return customerrors.New(http.StatusGone, "Link is no longer available")
+				}
+				return nil
+			},
+		},
+		Logger: mockLogger,
+		Analytics: &mockAnalyticsStore{
+			recordEventFunc: func(ctx context.Context, event analytics.AccessEvent) error {
+				recorded++
+				return nil
+			},
+		},
+	}
+
+	expected := []int{http.StatusFound, http.StatusGone, http.StatusGone}
+	for i, code := range expected {
+		req, _ := http.NewRequest("GET", "/abc123", nil)
+		w := httptest.NewRecorder()
+
+		handler.Redirect(w, req)
+
+		if w.Code != code {
+			t.Errorf("Click %d: expected status %d, got %d", i+1, code, w.Code)
+		}
+		if i == 0 && w.Header().Get("Cache-Control") != "no-store" {
+			t.Errorf("Expected click-limited redirect not to be cached, got %q", w.Header().Get("Cache-Control"))
+		}
+	}
+
+	if recorded != 1 {
+		t.Errorf("Expected only the served click to be recorded, got %d", recorded)
+	}
+
+	// Refused clicks are counted as misses
+	rr := httptest.NewRecorder()
+	appMetrics.Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	for _, line := range []string{`url_shortener_redirects_total{result="hit"} 1`, `url_shortener_redirects_total{result="miss"} 2`} {
+		if !strings.Contains(rr.Body.String(), line) {
+			t.Errorf("Expected %q in metrics output", line)
+		}
+	}
+}
+
+func TestShortenHandler_RedirectClickLimitBots(t *testing.T) {
+	const safariUA = "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_0) AppleWebKit/605.1.15 Safari/605.1.15"
+
+	// Prepare test environment
+	setUp(t)
+
+	mockLogger, err := logger.NewTestLogger()
+	if err != nil {
+		t.Fatalf("Failed to create mock logger: %v", err)
+	}
+	classifier, err := botfilter.NewClassifier()
+	if err != nil {
+		t.Fatalf("Failed to create classifier: %v", err)
+	}
+
+	used := 0
+	handler := &ShortenHandler{
+		Service: &mockURLService{
+			getLinkFunc: func(ctx context.Context, shortID string) (*model.Link, error) {
+				return &model.Link{ShortID: shortID, Original: "https://example.com/file", MaxClicks: 1}, nil
+			},
+			consumeFunc: func(ctx context.Context, link *model.Link) error {
+				if used >= int(link.MaxClicks) {
+					return customerrors.New(http.StatusGone, "Link is no longer available")
+				}
+				used++
+				return nil
+			},
+			checkFunc: func(ctx context.Context, link *model.Link) error {
+				if used >= int(link.MaxClicks) {
+					return customerrors.New(http.StatusGone, "Link is no longer available")
+				}
+				return nil
+			},
+		},
+		Logger:        mockLogger,
+		BotClassifier: classifier,
+	}
+
+	testCases := []struct {
+		name         string
+		method       string
+		userAgent    string
+		accept       string
+		expectedCode int
+		expectedUsed int
+	}{
+		{name: "HEAD Request", method: http.MethodHead, userAgent: safariUA, accept: "text/html", expectedCode: http.StatusNoContent, expectedUsed: 0},
+		{name: "Crawler", method: http.MethodGet, userAgent: "Googlebot/2.1", accept: "text/html", expectedCode: http.StatusNoContent, expectedUsed: 0},
+		{name: "Curl", method: http.MethodGet, userAgent: "curl/8.5.0", accept: "*/*", expectedCode: http.StatusNoContent, expectedUsed: 0},
+		{name: "Missing Accept", method: http.MethodGet, userAgent: safariUA, expectedCode: http.StatusNoContent, expectedUsed: 0},
+		{name: "Visitor", method: http.MethodGet, userAgent: safariUA, accept: "text/html", expectedCode: http.StatusFound, expectedUsed: 1},
+		{name: "HEAD After Limit", method: http.MethodHead, userAgent: safariUA, accept: "text/html", expectedCode: http.StatusGone, expectedUsed: 1},
+		{name: "Curl After Limit", method: http.MethodGet, userAgent: "curl/8.5.0", accept: "*/*", expectedCode: http.StatusGone, expectedUsed: 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "/abc123", nil)
+			req.Header.Set("User-Agent", tc.userAgent)
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			w := httptest.NewRecorder()
+
+			handler.Redirect(w, req)
+
+			if w.Code != tc.expectedCode {
+				t.Errorf("Expected status %d, got %d", tc.expectedCode, w.Code)
+			}
+			if used != tc.expectedUsed {
+				t.Errorf("Expected %d clicks used, got %d", tc.expectedUsed, used)
+			}
+			// Only a counted click reveals the destination
+			if tc.expectedCode != http.StatusFound {
+				if location := w.Header().Get("Location"); location != "" {
+					t.Errorf("Expected no Location header, got %q", location)
+				}
+				if strings.Contains(w.Body.String(), "example.com/file") || w.Header().Get("Link") != "" {
+					t.Errorf("Expected the destination to stay hidden, got %q", w.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestShortenHandler_ShortenURLSchedule(t *testing.T) {
+	// Prepare test environment
+	setUp(t)
+
+	testCases := []struct {
+		name               string
+		body               string
+		expectedStatusCode int
+		expectedOptions    int
+	}{
+		{
+			name:               "Duration String",
+			body:               `{"original":"https://example.com","ttl":"72h"}`,
+			expectedStatusCode: http.StatusOK,
+			expectedOptions:    1,
+		},
+		{
+			name:               "Duration Nanoseconds",
+			body:               `{"original":"https://example.com","ttl":3600000000000}`,
+			expectedStatusCode: http.StatusOK,
+			expectedOptions:    1,
+		},
+		{
+			name:               "Absolute Timestamps",
+			body:               `{"original":"https://example.com","not_before":"2030-01-01T09:00:00Z","expires_at":"2030-02-01T00:00:00+01:00"}`,
+			expectedStatusCode: http.StatusOK,
+			expectedOptions:    2,
+		},
+		{
+			name:               "Invalid Duration",
+			body:               `{"original":"https://example.com","ttl":"three days"}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Invalid Timestamp",
+			body:               `{"original":"https://example.com","expires_at":"tomorrow"}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	mockLogger, err := logger.NewTestLogger()
+	if err != nil {
+		t.Fatalf("Failed to create mock logger: %v", err)
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			options := 0
+			handler := &ShortenHandler{
+				Service: &mockURLService{
+					shortenFunc: func(ctx context.Context, url string, opts ...service.URLShortenOption) (string, error) {
+						options = len(opts)
+						return "http://short.url/abc123", nil
+					},
+				},
+				Logger: mockLogger,
+			}
+
+			req, _ := http.NewRequest("POST", "/shorten", bytes.NewBufferString(tc.body))
+			w := httptest.NewRecorder()
+
+			handler.ShortenURL(w, req)
+
+			if w.Code != tc.expectedStatusCode {
+				t.Errorf("Expected status %d, got %d", tc.expectedStatusCode, w.Code)
+			}
+			if options != tc.expectedOptions {
+				t.Errorf("Expected %d options, got %d", tc.expectedOptions, options)
+			}
+		})
+	}
+}
+
+func TestShortenHandler_ShortenURLTTL(t *testing.T) {
+	// Prepare test environment
+	setUp(t)
+
+	mr := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	mockLogger, err := logger.NewTestLogger()
+	if err != nil {
+		t.Fatalf("Failed to create mock logger: %v", err)
+	}
+	cfg := &config.Config{BaseURL: "http://short.url", DefaultURLTTL: 24 * time.Hour}
+	handler := &ShortenHandler{
+		Service: service.NewURLShorteningService(cfg, redis.NewRedisStore(client)),
+		Logger:  mockLogger,
+	}
+
+	launch := time.Now().Add(72 * time.Hour).UTC().Format(time.RFC3339)
+	testCases := []struct {
+		name               string
+		body               string
+		expectedStatusCode int
+	}{
+		{
+			name:               "Negative TTL",
+			body:               `{"original":"https://example.com","ttl":"-1h"}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Launch Beyond Default TTL",
+			body:               `{"original":"https://example.com","not_before":"` + launch + `"}`,
+			expectedStatusCode: http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", "/shorten", bytes.NewBufferString(tc.body))
+			w := httptest.NewRecorder()
+
+			handler.ShortenURL(w, req)
+
+			if w.Code != tc.expectedStatusCode {
+				t.Errorf("Expected status %d, got %d: %s", tc.expectedStatusCode, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestShortenHandler_RedirectSchedule(t *testing.T) {
+	// Prepare test environment
+	setUp(t)
+
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+	soon := time.Now().Add(10 * time.Minute)
+
+	testCases := []struct {
+		name               string
+		link               model.Link
+		expectedStatusCode int
+		expectedRetryAfter bool
+	}{
+		{
+			name:               "Active",
+			link:               model.Link{NotBefore: &past, ExpiresAt: &future},
+			expectedStatusCode: http.StatusFound,
+		},
+		{
+			name:               "Not Active Yet",
+			link:               model.Link{NotBefore: &future},
+			expectedStatusCode: http.StatusTooEarly,
+			expectedRetryAfter: true,
+		},
+		{
+			name:               "Expired",
+			link:               model.Link{ExpiresAt: &past},
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "Permanent Cached Until Expiry",
+			link:               model.Link{ExpiresAt: &soon, RedirectCode: http.StatusMovedPermanently},
+			expectedStatusCode: http.StatusMovedPermanently,
+		},
+	}
+
+	mockLogger, err := logger.NewTestLogger()
+	if err != nil {
+		t.Fatalf("Failed to create mock logger: %v", err)
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := &ShortenHandler{
+				Service: &mockURLService{
+					getLinkFunc: func(ctx context.Context, shortID string) (*model.Link, error) {
+						link := tc.link
+						link.ShortID = shortID
+						link.Original = "https://example.com/launch"
+						return &link, nil
+					},
+				},
+				Logger: mockLogger,
+			}
+
+			req, _ := http.NewRequest("GET", "/abc123", nil)
+			w := httptest.NewRecorder()
+
+			handler.Redirect(w, req)
+
+			if w.Code != tc.expectedStatusCode {
+				t.Errorf("Expected status %d, got %d", tc.expectedStatusCode, w.Code)
+			}
+			if retryAfter := w.Header().Get("Retry-After"); (retryAfter != "") != tc.expectedRetryAfter {
+				t.Errorf("Unexpected Retry-After header %q", retryAfter)
+			}
+			if tc.link.RedirectCode == http.StatusMovedPermanently {
+				if cacheControl := w.Header().Get("Cache-Control"); cacheControl != "public, max-age=599" && cacheControl != "public, max-age=600" {
+					t.Errorf("Expected caching to stop at expiry, got %q", cacheControl)
+				}
+			}
+		})
+	}
+}
+
+// mockGeoIP resolves every address to a fixed country
+type mockGeoIP struct {
+	country string
+}
+
+// Country implements the GeoIP resolver for the mock
+func (m *mockGeoIP) Country(addr netip.Addr) string {
+	return m.country
+}
+
+func TestShortenHandler_RedirectRules(t *testing.T) {
+	// Prepare test environment
+	setUp(t)
+
+	link := &model.Link{
+		ShortID:  "abc123",
+		Original: "https://example.com",
+		Rules: []targeting.Rule{
+			{Target: "https://apps.apple.com/app", OS: []string{targeting.OSiOS}},
+			{Target: "https://play.google.com/app", OS: []string{targeting.OSAndroid}},
+			{Target: "https://example.com/de", Countries: []string{"DE"}},
+		},
+	}
+
+	testCases := []struct {
+		name             string
+		userAgent        string
+		countryHeader    string
+		geoCountry       string
+		expectedLocation string
+	}{
+		{
+			name:             "iOS",
+			userAgent:        "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) Mobile/15E148",
+			countryHeader:    "DE",
+			expectedLocation: "https://apps.apple.com/app",
+		},
+		{
+			name:             "Android",
+			userAgent:        "Mozilla/5.0 (Linux; Android 14; Pixel 8) Mobile Safari/537.36",
+			expectedLocation: "https://play.google.com/app",
+		},
+		{
+			name:             "Country From Proxy Header",
+			userAgent:        "Mozilla/5.0 (Windows NT 10.0; Win64; x64)",
+			countryHeader:    "de",
+			expectedLocation: "https://example.com/de",
+		},
+		{
+			name:             "Country From GeoIP",
+			userAgent:        "Mozilla/5.0 (Windows NT 10.0; Win64; x64)",
+			geoCountry:       "DE",
+			expectedLocation: "https://example.com/de",
+		},
+		{
+			name:             "Fallback",
+			userAgent:        "Mozilla/5.0 (Windows NT 10.0; Win64; x64)",
+			geoCountry:       "US",
+			expectedLocation: "https://example.com",
+		},
+	}
+
+	mockLogger, err := logger.NewTestLogger()
+	if err != nil {
+		t.Fatalf("Failed to create mock logger: %v", err)
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := &ShortenHandler{
+				Service: &mockURLService{
+					getLinkFunc: func(ctx context.Context, shortID string) (*model.Link, error) {
+						return link, nil
+					},
+				},
+				Logger: mockLogger,
+				GeoIP:  &mockGeoIP{country: tc.geoCountry},
+			}
+
+			req, _ := http.NewRequest("GET", "/abc123", nil)
+			req.RemoteAddr = "198.51.100.7:51234"
+			req.Header.Set("User-Agent", tc.userAgent)
+			if tc.countryHeader != "" {
+				req.Header.Set("CF-IPCountry", tc.countryHeader)
+			}
+			w := httptest.NewRecorder()
+
+			handler.Redirect(w, req)
+
+			if location := w.Header().Get("Location"); location != tc.expectedLocation {
+				t.Errorf("Expected location %q, got %q", tc.expectedLocation, location)
+			}
+			if cacheControl := w.Header().Get("Cache-Control"); cacheControl != "private, no-cache" {
+				t.Errorf("Expected targeted redirect to be private, got %q", cacheControl)
+			}
+		})
+	}
+}
+
+func TestShortenHandler_RedirectVariants(t *testing.T) {
+	// Prepare test environment
+	setUp(t)
+
+	link := &model.Link{
+		ShortID:  "abc123",
+		Original: "https://example.com",
+		Variants: []targeting.Variant{
+			{Name: "a", Target: "https://example.com/a", Weight: 70},
+			{Name: "b", Target: "https://example.com/b", Weight: 30},
+		},
+	}
+
+	mockLogger, err := logger.NewTestLogger()
+	if err != nil {
+		t.Fatalf("Failed to create mock logger: %v", err)
+	}
+
+	var recorded []string
+	handler := &ShortenHandler{
+		Service: &mockURLService{
+			getLinkFunc: func(ctx context.Context, shortID string) (*model.Link, error) {
+				return link, nil
+			},
+		},
+		Logger: mockLogger,
+		Analytics: &mockAnalyticsStore{
+			recordEventFunc: func(ctx context.Context, event analytics.AccessEvent) error {
+				recorded = append(recorded, event.Variant)
+				return nil
+			},
+		},
+	}
+
+	redirect := func(remoteAddr string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/abc123", nil)
+		req.RemoteAddr = remoteAddr
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		handler.Redirect(w, req)
+		return w
+	}
+
+	// A new visitor gets a variant and a cookie naming it
+	first := redirect("192.0.2.1:1234", nil)
+	cookies := first.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "ab_abc123" {
+		t.Fatalf("Expected variant cookie, got %v", cookies)
+	}
+	variant, ok := targeting.FindVariant(link.Variants, cookies[0].Value)
+	if !ok {
+		t.Fatalf("Expected cookie to name a variant, got %q", cookies[0].Value)
+	}
+	if location := first.Header().Get("Location"); location != variant.Target {
+		t.Errorf("Expected location %q, got %q", variant.Target, location)
+	}
+
+	// The same IP without cookie stays on its variant
+	if location := redirect("192.0.2.1:5678", nil).Header().Get("Location"); location != variant.Target {
+		t.Errorf("Expected sticky location %q, got %q", variant.Target, location)
+	}
+
+	// The cookie wins over the IP
+	other := "b"
+	if variant.Name == "b" {
+		other = "a"
+	}
+	otherVariant, _ := targeting.FindVariant(link.Variants, other)
+	if location := redirect("192.0.2.1:1234", &http.Cookie{Name: "ab_abc123", Value: other}).Header().Get("Location"); location != otherVariant.Target {
+		t.Errorf("Expected cookie variant %q, got %q", otherVariant.Target, location)
+	}
+
+	// The served variant is recorded
+	expected := []string{variant.Name, variant.Name, other}
+	if strings.Join(recorded, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected recorded variants %v, got %v", expected, recorded)
+	}
+}
@@ -9,7 +9,162 @@
 
 package model
 
+import (
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/metadata"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/targeting"
+)
+
+// Query passthrough modes, decide how incoming query parameters are merged
+// into the destination URL
+const (
+	QueryPassthroughNone     = ""         // Incoming query parameters are dropped
+	QueryPassthroughKeep     = "keep"     // Added, the destination's own parameters win on conflict
+	QueryPassthroughOverride = "override" // Added, incoming parameters win on conflict
+)
+
 // URL struct represents the original and shortened URL structure
 type URL struct {
 	Original string `json:"original"` // Original URL
 }
+
+// Link is the stored record of a shortened URL
+type Link struct {
+	ShortID      string   `json:"short_id"`
+	Original     string   `json:"original"`
+	Title        string   `json:"title,omitempty"` // Shown on the preview page
+	Owner        string   `json:"owner,omitempty"`
+	Tags         []string `json:"tags,omitempty"`
+	NoTracking   bool     `json:"no_tracking,omitempty"`   // Never record analytics for this link
+	RedirectCode int      `json:"redirect_code,omitempty"` // HTTP status used for redirects, 302 when unset
+	// Query passthrough mode, see QueryPassthroughKeep and QueryPassthroughOverride
+	QueryPassthrough string `json:"query_passthrough,omitempty"`
+	// Append the path after the short ID to the destination path
+	PathPassthrough bool `json:"path_passthrough,omitempty"`
+	// bcrypt hash of the password required to follow the link
+	PasswordHash string `json:"password_hash,omitempty"`
+	// Number of redirects after which the link is gone, 0 means unlimited
+	MaxClicks int64 `json:"max_clicks,omitempty"`
+	// Ordered targeting rules, Original is the fallback target
+	Rules []targeting.Rule `json:"rules,omitempty"`
+	// Weighted A/B destinations for visitors no rule matched
+	Variants []targeting.Variant `json:"variants,omitempty"`
+	// Show the preview page instead of redirecting immediately
+	Interstitial bool `json:"interstitial,omitempty"`
+	// Title, description and favicon of the original page, fetched after creation
+	Metadata *metadata.Metadata `json:"metadata,omitempty"`
+	// The link only redirects from NotBefore until ExpiresAt
+	NotBefore *time.Time `json:"not_before,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Active reports whether the link has been activated at the given time
+func (l *Link) Active(at time.Time) bool {
+	return l.NotBefore == nil || !at.Before(*l.NotBefore)
+}
+
+// Expired reports whether the link has expired at the given time
+func (l *Link) Expired(at time.Time) bool {
+	return l.ExpiresAt != nil && !at.Before(*l.ExpiresAt)
+}
+
+// Protected reports whether a password is required to follow the link
+func (l *Link) Protected() bool {
+	return l.PasswordHash != ""
+}
+
+// ValidQueryPassthrough reports whether mode is a known query passthrough mode
+func ValidQueryPassthrough(mode string) bool {
+	switch mode {
+	case QueryPassthroughNone, QueryPassthroughKeep, QueryPassthroughOverride:
+		return true
+	}
+	return false
+}
+
+// ValidRedirectCode reports whether code can be used to redirect a link
+func ValidRedirectCode(code int) bool {
+	switch code {
+	case http.StatusMovedPermanently, http.StatusFound,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+// RedirectStatus returns the HTTP status used to redirect the link
+func (l *Link) RedirectStatus() int {
+	if ValidRedirectCode(l.RedirectCode) {
+		return l.RedirectCode
+	}
+	return http.StatusFound
+}
+
+// IsPermanent reports whether the link redirects permanently
+func (l *Link) IsPermanent() bool {
+	status := l.RedirectStatus()
+	return status == http.StatusMovedPermanently || status == http.StatusPermanentRedirect
+}
+
+// Domain returns the lower-cased host name of the original URL
+func (l *Link) Domain() string {
+	parsed, err := url.Parse(l.Original)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(parsed.Hostname())
+}
+
+// Target returns the destination of the first targeting rule matching the
+// visitor, or the original URL when no rule matches
+func (l *Link) Target(visitor targeting.Visitor) (string, bool) {
+	if target, ok := targeting.Select(l.Rules, visitor); ok {
+		return target, true
+	}
+	return l.Original, false
+}
+
+// Destination returns the redirect target for a request with the given
+// path suffix (the part after the short ID) and query parameters, applying
+// the link's passthrough policy to base
+func (l *Link) Destination(base, suffix string, query url.Values) (string, error) {
+	keepQuery := l.QueryPassthrough != QueryPassthroughNone && len(query) > 0
+	keepPath := l.PathPassthrough && strings.Trim(suffix, "/") != ""
+	if !keepQuery && !keepPath {
+		return base, nil
+	}
+
+	target, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+
+	if keepPath {
+		// Cleaning a rooted path drops any ".." that would climb above the target path
+		rest := path.Clean("/" + suffix)
+		if strings.HasSuffix(suffix, "/") && rest != "/" {
+			rest += "/"
+		}
+		target.Path = strings.TrimSuffix(target.Path, "/") + rest
+		target.RawPath = ""
+	}
+
+	if keepQuery {
+		values := target.Query()
+		for key, incoming := range query {
+			if _, exists := values[key]; exists && l.QueryPassthrough == QueryPassthroughKeep {
+				continue
+			}
+			values[key] = incoming
+		}
+		target.RawQuery = values.Encode()
+	}
+
+	return target.String(), nil
+}
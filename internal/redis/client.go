@@ -13,58 +13,130 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"math/rand/v2"
+	"sync/atomic"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 	"github.com/yasin-yalcin-dev/go-url-shortener/internal/config"
 )
 
-// RedisClient represents an enhanced Redis client
+const (
+	// connectTimeout bounds the ping that verifies a new connection
+	connectTimeout = 5 * time.Second
+
+	// closeGracePeriod lets commands on a replaced client finish before it is closed
+	closeGracePeriod = 5 * time.Second
+)
+
+// RedisClient represents an enhanced Redis client for a standalone server, a
+// Sentinel monitored master or a cluster. The underlying client can be
+// replaced by a reconnect, stores must read it through Client on every use.
 type RedisClient struct {
-	client *redis.Client
-	config *config.RedisConfig
+	current atomic.Pointer[redis.UniversalClient]
+	config  *config.RedisConfig
+	setup   []func(redis.UniversalClient) error
+}
+
+// ConnectOption configures optional Connect behaviour
+type ConnectOption func(*RedisClient)
+
+// WithClientSetup runs fn on every new client before it is used, including
+// clients created by a reconnect, e.g. to add tracing hooks
+func WithClientSetup(fn func(redis.UniversalClient) error) ConnectOption {
+	return func(r *RedisClient) {
+		r.setup = append(r.setup, fn)
+	}
 }
 
 // Connect establishes a connection to Redis and configures the client
-func Connect(cfg *config.Config) (*RedisClient, error) {
-	client := redis.NewClient(&redis.Options{
-		Addr:            cfg.RedisConfig.Address,
-		Password:        cfg.RedisConfig.Password,
-		DB:              cfg.RedisConfig.DB,
-		PoolSize:        cfg.RedisConfig.PoolSize,
-		DialTimeout:     cfg.RedisConfig.DialTimeout,
-		ReadTimeout:     cfg.RedisConfig.ReadTimeout,
-		WriteTimeout:    cfg.RedisConfig.WriteTimeout,
-		PoolTimeout:     cfg.RedisConfig.PoolTimeout,
-		MaxRetries:      cfg.RedisConfig.MaxRetries,
-		MinRetryBackoff: cfg.RedisConfig.MinRetryBackoff,
-		MaxRetryBackoff: cfg.RedisConfig.MaxRetryBackoff,
-	})
+func Connect(cfg *config.Config, options ...ConnectOption) (*RedisClient, error) {
+	r := &RedisClient{config: cfg.RedisConfig}
+	for _, opt := range options {
+		opt(r)
+	}
+
+	client, err := r.dial(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	r.current.Store(&client)
+
+	log.Println("Redis connection successful!")
+
+	return r, nil
+}
+
+// newClient creates a client for the configured mode
+func newClient(cfg *config.RedisConfig) redis.UniversalClient {
+	opts := &redis.UniversalOptions{
+		Addrs:            cfg.Addresses,
+		MasterName:       cfg.MasterName,
+		Password:         cfg.Password,
+		SentinelPassword: cfg.SentinelPassword,
+		DB:               cfg.DB,
+		PoolSize:         cfg.PoolSize,
+		DialTimeout:      cfg.DialTimeout,
+		ReadTimeout:      cfg.ReadTimeout,
+		WriteTimeout:     cfg.WriteTimeout,
+		PoolTimeout:      cfg.PoolTimeout,
+		MaxRetries:       cfg.MaxRetries,
+		MinRetryBackoff:  cfg.MinRetryBackoff,
+		MaxRetryBackoff:  cfg.MaxRetryBackoff,
+	}
+
+	switch cfg.Mode {
+	case config.RedisModeSentinel:
+		return redis.NewFailoverClient(opts.Failover())
+	case config.RedisModeCluster:
+		// REDIS_ADDR is enough to discover a cluster
+		if len(opts.Addrs) == 0 {
+			opts.Addrs = []string{cfg.Address}
+		}
+		return redis.NewClusterClient(opts.Cluster())
+	default:
+		opts.Addrs = []string{cfg.Address}
+		return redis.NewClient(opts.Simple())
+	}
+}
+
+// dial creates a new client from the configuration and checks the connection
+func (r *RedisClient) dial(ctx context.Context) (redis.UniversalClient, error) {
+	client := newClient(r.config)
+
+	for _, setup := range r.setup {
+		if err := setup(client); err != nil {
+			client.Close()
+			return nil, err
+		}
+	}
 
 	// Check connection
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, connectTimeout)
 	defer cancel()
 
 	if _, err := client.Ping(ctx).Result(); err != nil {
+		client.Close()
 		return nil, fmt.Errorf("redis connection error: %v", err)
 	}
 
-	log.Println("Redis connection successful!")
+	return client, nil
+}
 
-	return &RedisClient{
-		client: client,
-		config: cfg.RedisConfig,
-	}, nil
+// Client returns the current Redis client, it is the client source of stores
+// that follow reconnects
+func (r *RedisClient) Client() redis.UniversalClient {
+	return *r.current.Load()
 }
 
-// Client returns the raw Redis client
-func (r *RedisClient) Client() *redis.Client {
-	return r.client
+// PoolStats returns the connection pool statistics of the current client
+func (r *RedisClient) PoolStats() *redis.PoolStats {
+	return r.Client().PoolStats()
 }
 
 // Close safely closes the connection
 func (r *RedisClient) Close() error {
-	if err := r.client.Close(); err != nil {
+	if err := r.Client().Close(); err != nil {
 		log.Printf("Error closing Redis connection: %v", err)
 		return err
 	}
@@ -73,7 +145,7 @@ func (r *RedisClient) Close() error {
 
 // IsHealthy checks if the Redis connection is healthy
 func (r *RedisClient) IsHealthy(ctx context.Context) bool {
-	_, err := r.client.Ping(ctx).Result()
+	_, err := r.Client().Ping(ctx).Result()
 	return err == nil
 }
 
@@ -82,27 +154,114 @@ func (r *RedisClient) GetConfig() *config.RedisConfig {
 	return r.config
 }
 
-// ReconnectWithBackoff attempts to reconnect with exponential backoff
-func (r *RedisClient) ReconnectWithBackoff(cfg *config.Config) error {
-	backoff := time.Second
-	maxBackoff := 1 * time.Minute
+// ReconnectWithBackoff replaces the client with a new connection, retrying
+// with jittered exponential backoff until it succeeds or ctx is done
+func (r *RedisClient) ReconnectWithBackoff(ctx context.Context, minBackoff, maxBackoff time.Duration) error {
+	backoff := minBackoff
 
-	for attempt := 1; attempt <= 5; attempt++ {
-		newClient, err := Connect(cfg)
+	for attempt := 1; ; attempt++ {
+		client, err := r.dial(ctx)
 		if err == nil {
-			r.client = newClient.client
-			r.config = newClient.config
+			r.swap(client)
 			return nil
 		}
 
 		log.Printf("Reconnection attempt %d failed: %v", attempt, err)
 
-		time.Sleep(backoff)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("reconnection aborted after %d attempts: %v", attempt, ctx.Err())
+		case <-time.After(jitter(backoff)):
+		}
+
 		backoff *= 2
 		if backoff > maxBackoff {
 			backoff = maxBackoff
 		}
 	}
+}
+
+// swap installs a new client and closes the old one once in-flight commands
+// had time to finish
+func (r *RedisClient) swap(client redis.UniversalClient) {
+	old := r.current.Swap(&client)
+	if old != nil {
+		time.AfterFunc(closeGracePeriod, func() {
+			(*old).Close()
+		})
+	}
+}
+
+// jitter returns a random duration between d/2 and d, so instances do not
+// reconnect in lockstep after an outage
+func jitter(d time.Duration) time.Duration {
+	if d <= 1 {
+		return d
+	}
+	half := d / 2
+	return half + rand.N(d-half+1)
+}
 
-	return fmt.Errorf("5 reconnection attempts failed")
+// SupervisorConfig configures Supervise
+type SupervisorConfig struct {
+	Interval         time.Duration // Time between health checks, defaults to 5s
+	FailureThreshold int           // Failed checks in a row before reconnecting, defaults to 3
+	MinBackoff       time.Duration // First reconnect delay, defaults to 500ms
+	MaxBackoff       time.Duration // Maximum reconnect delay, defaults to 30s
+
+	OnReconnect func()          // Called after the client was replaced
+	OnError     func(err error) // Called when a health check fails
+}
+
+// Supervise checks the connection periodically and replaces the client once
+// it failed FailureThreshold checks in a row. It blocks until ctx is done.
+func (r *RedisClient) Supervise(ctx context.Context, cfg SupervisorConfig) {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 5 * time.Second
+	}
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = 3
+	}
+	if cfg.MinBackoff <= 0 {
+		cfg.MinBackoff = 500 * time.Millisecond
+	}
+	if cfg.MaxBackoff < cfg.MinBackoff {
+		cfg.MaxBackoff = max(30*time.Second, cfg.MinBackoff)
+	}
+
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	failures := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		checkCtx, cancel := context.WithTimeout(ctx, cfg.Interval)
+		err := r.Client().Ping(checkCtx).Err()
+		cancel()
+		if err == nil {
+			failures = 0
+			continue
+		}
+
+		failures++
+		if cfg.OnError != nil {
+			cfg.OnError(fmt.Errorf("health check %d of %d failed: %v", failures, cfg.FailureThreshold, err))
+		}
+		if failures < cfg.FailureThreshold {
+			continue
+		}
+
+		if err := r.ReconnectWithBackoff(ctx, cfg.MinBackoff, cfg.MaxBackoff); err != nil {
+			return
+		}
+		failures = 0
+		if cfg.OnReconnect != nil {
+			cfg.OnReconnect()
+		}
+	}
 }
@@ -0,0 +1,187 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package redis
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/yasin-yalcin-dev/go-url-shortener/internal/config"
+	"github.com/yasin-yalcin-dev/go-url-shortener/internal/model"
+)
+
+// connectMock connects a RedisClient to a miniredis server
+func connectMock(t *testing.T, mr *miniredis.Miniredis, options ...ConnectOption) *RedisClient {
+	t.Helper()
+	client, err := Connect(&config.Config{RedisConfig: &config.RedisConfig{Address: mr.Addr()}}, options...)
+	if err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestRedisClient_Supervise(t *testing.T) {
+	mr := miniredis.RunT(t)
+
+	var setups atomic.Int32
+	client := connectMock(t, mr, WithClientSetup(func(redis.UniversalClient) error {
+		setups.Add(1)
+		return nil
+	}))
+	store := NewSupervisedRedisStore(client)
+	if err := store.SaveShortenedURLWithTTL(context.Background(), "abc123", "https://example.com", 0); err != nil {
+		t.Fatalf("Failed to save URL: %v", err)
+	}
+	original := client.Client()
+
+	const threshold = 2
+	failed := make(chan struct{})
+	reconnected := make(chan struct{})
+	var failures atomic.Int32
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go client.Supervise(ctx, SupervisorConfig{
+		Interval:         10 * time.Millisecond,
+		FailureThreshold: threshold,
+		MinBackoff:       10 * time.Millisecond,
+		MaxBackoff:       50 * time.Millisecond,
+		OnReconnect:      func() { close(reconnected) },
+		OnError: func(err error) {
+			if failures.Add(1) == threshold {
+				close(failed)
+			}
+		},
+	})
+
+	// The supervisor starts reconnecting while the server is down
+	mr.Close()
+	select {
+	case <-failed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected failed health checks while Redis is down")
+	}
+	if err := mr.Restart(); err != nil {
+		t.Fatalf("Failed to restart miniredis: %v", err)
+	}
+
+	select {
+	case <-reconnected:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected a reconnect after Redis came back")
+	}
+
+	if client.Client() == original {
+		t.Error("Expected the client to be replaced")
+	}
+	if setups.Load() != 2 {
+		t.Errorf("Expected the setup to run for both clients, ran %d times", setups.Load())
+	}
+
+	// The store uses the new client
+	url, err := store.GetOriginalURL(context.Background(), "abc123")
+	if err != nil || url != "https://example.com" {
+		t.Errorf("Expected the stored URL after reconnecting, got %q (%v)", url, err)
+	}
+}
+
+func TestRedisClient_ReconnectWithBackoff(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := connectMock(t, mr)
+	original := client.Client()
+
+	// Gives up when the context is done while Redis is unreachable
+	mr.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := client.ReconnectWithBackoff(ctx, 10*time.Millisecond, 20*time.Millisecond); err == nil {
+		t.Error("Expected an error while Redis is down")
+	}
+	if client.Client() != original {
+		t.Error("Expected the client to be kept after a failed reconnect")
+	}
+
+	if err := mr.Restart(); err != nil {
+		t.Fatalf("Failed to restart miniredis: %v", err)
+	}
+	if err := client.ReconnectWithBackoff(context.Background(), 10*time.Millisecond, 20*time.Millisecond); err != nil {
+		t.Fatalf("Reconnect failed: %v", err)
+	}
+	if !client.IsHealthy(context.Background()) {
+		t.Error("Expected a healthy client after reconnecting")
+	}
+}
+
+func TestConnect_SetupError(t *testing.T) {
+	mr := miniredis.RunT(t)
+
+	_, err := Connect(&config.Config{RedisConfig: &config.RedisConfig{Address: mr.Addr()}},
+		WithClientSetup(func(redis.UniversalClient) error { return errors.New("boom") }))
+	if err == nil {
+		t.Error("Expected the setup error to fail Connect")
+	}
+}
+
+func TestJitter(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		if d := jitter(time.Second); d < 500*time.Millisecond || d > time.Second {
+			t.Fatalf("Expected a delay between 500ms and 1s, got %v", d)
+		}
+	}
+}
+
+func TestConnect_Modes(t *testing.T) {
+	mr := miniredis.RunT(t)
+
+	// miniredis answers CLUSTER SLOTS as a single node cluster
+	client := connectMock(t, mr)
+	if _, ok := client.Client().(*redis.Client); !ok {
+		t.Errorf("Expected a single node client, got %T", client.Client())
+	}
+
+	cluster, err := Connect(&config.Config{RedisConfig: &config.RedisConfig{
+		Mode:      config.RedisModeCluster,
+		Addresses: []string{mr.Addr()},
+	}})
+	if err != nil {
+		t.Fatalf("Connect failed in cluster mode: %v", err)
+	}
+	defer cluster.Close()
+	if _, ok := cluster.Client().(*redis.ClusterClient); !ok {
+		t.Errorf("Expected a cluster client, got %T", cluster.Client())
+	}
+
+	// Links and their click counters work through the cluster client
+	store := NewSupervisedRedisStore(cluster)
+	ctx := context.Background()
+	if err := store.SaveLinkWithTTL(ctx, &model.Link{ShortID: "abc123", Original: "https://example.com", MaxClicks: 2}, 0); err != nil {
+		t.Fatalf("Failed to save link: %v", err)
+	}
+	if used, err := store.ConsumeClick(ctx, "abc123", 2); err != nil || used != 1 {
+		t.Errorf("Expected the first click to be counted, got %d (%v)", used, err)
+	}
+
+	// Sentinel mode connects through a failover client
+	sentinel := newClient(&config.RedisConfig{
+		Mode:       config.RedisModeSentinel,
+		Addresses:  []string{mr.Addr()},
+		MasterName: "mymaster",
+	})
+	defer sentinel.Close()
+	if _, ok := sentinel.(*redis.Client); !ok {
+		t.Errorf("Expected a failover client, got %T", sentinel)
+	}
+}
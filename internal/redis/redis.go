@@ -11,37 +11,89 @@ package redis
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/redis/go-redis/v9"
+	"github.com/yasin-yalcin-dev/go-url-shortener/internal/model"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/metadata"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+// ErrURLNotFound occurs when a short ID has no stored URL
+var ErrURLNotFound = errors.New("short URL not found")
+
+// ErrClickLimitReached occurs when a click-limited link has used up its clicks
+var ErrClickLimitReached = errors.New("click limit reached")
+
+// consumeClickScript counts a click unless the limit is reached. The counter
+// expires together with the link so a recreated short ID starts from zero.
+// Returns the clicks used, -1 when the limit is reached, -2 when the link is gone.
+var consumeClickScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return -2
+end
+local used = tonumber(redis.call('GET', KEYS[2]) or '0')
+if used >= tonumber(ARGV[1]) then
+	return -1
+end
+used = redis.call('INCR', KEYS[2])
+if used == 1 then
+	local ttl = redis.call('PTTL', KEYS[1])
+	if ttl > 0 then
+		redis.call('PEXPIRE', KEYS[2], ttl)
+	end
+end
+return used
+`)
+
 type URLStore interface {
 
 	// SaveShortenedURLWithTTL stores a shortened URL with a time-to-live (TTL) in the database
 	SaveShortenedURLWithTTL(ctx context.Context, shortID, originalURL string, ttl time.Duration) error
 	// GetOriginalURL retrieves the original URL from the database
 	GetOriginalURL(ctx context.Context, shortID string) (string, error)
+	// SaveLinkWithTTL stores a link record with a time-to-live (TTL) in the database
+	SaveLinkWithTTL(ctx context.Context, link *model.Link, ttl time.Duration) error
+	// GetLink retrieves the link record from the database
+	GetLink(ctx context.Context, shortID string) (*model.Link, error)
+	// ConsumeClick atomically counts a click of a link limited to maxClicks
+	ConsumeClick(ctx context.Context, shortID string, maxClicks int64) (int64, error)
+	// ClicksUsed returns the clicks counted for a link without counting one
+	ClicksUsed(ctx context.Context, shortID string) (int64, error)
+	// SaveLinkMetadata stores the page metadata of an existing link, keeping its TTL
+	SaveLinkMetadata(ctx context.Context, shortID string, meta *metadata.Metadata) error
+	// DeleteLink removes the link record and its click counter
+	DeleteLink(ctx context.Context, shortID string) error
 }
 
 // RedisStore struct implements the URLStore interface for Redis.
 type RedisStore struct {
-	Client *redis.Client
+	client func() redis.UniversalClient
 }
 
 // NewRedisStore creates a new RedisStore instance
-func NewRedisStore(client *redis.Client) *RedisStore {
-	return &RedisStore{Client: client}
+func NewRedisStore(client redis.UniversalClient) *RedisStore {
+	return &RedisStore{client: func() redis.UniversalClient { return client }}
+}
+
+// NewSupervisedRedisStore creates a RedisStore that always uses the current
+// client of a RedisClient, including clients replaced after a reconnect
+func NewSupervisedRedisStore(client *RedisClient) *RedisStore {
+	return &RedisStore{client: client.Client}
 }
 
 // GetOriginalURL retrieves the original URL from Redis
 func (r *RedisStore) GetOriginalURL(ctx context.Context, shortID string) (string, error) {
-	originalURL, err := r.Client.Get(ctx, shortID).Result()
+	link, err := r.GetLink(ctx, shortID)
 	if err != nil {
-		return "", fmt.Errorf("could not get original URL: %v", err)
+		return "", err
 	}
-	return originalURL, nil
+	return link.Original, nil
 }
 
 func (r *RedisStore) SaveShortenedURLWithTTL(
@@ -50,9 +102,144 @@ func (r *RedisStore) SaveShortenedURLWithTTL(
 	originalURL string,
 	ttl time.Duration,
 ) error {
-	err := r.Client.Set(ctx, shortID, originalURL, ttl).Err()
+	return r.SaveLinkWithTTL(ctx, &model.Link{
+		ShortID:   shortID,
+		Original:  originalURL,
+		CreatedAt: time.Now().UTC(),
+	}, ttl)
+}
+
+// SaveLinkWithTTL stores the link record as JSON under its short ID
+func (r *RedisStore) SaveLinkWithTTL(ctx context.Context, link *model.Link, ttl time.Duration) (err error) {
+	ctx, span := tracing.Start(ctx, "RedisStore.SaveLinkWithTTL", attribute.String("short_id", link.ShortID))
+	defer func() { tracing.End(span, err) }()
+
+	data, err := json.Marshal(link)
+	if err != nil {
+		return fmt.Errorf("failed to encode link: %v", err)
+	}
+
+	err = r.client().Set(ctx, link.ShortID, data, ttl).Err()
 	if err != nil {
 		return fmt.Errorf("failed to save URL with TTL: %v", err)
 	}
 	return nil
 }
+
+// GetLink retrieves the link record from Redis
+func (r *RedisStore) GetLink(ctx context.Context, shortID string) (link *model.Link, err error) {
+	ctx, span := tracing.Start(ctx, "RedisStore.GetLink", attribute.String("short_id", shortID))
+	defer func() { tracing.End(span, err) }()
+
+	value, err := r.client().Get(ctx, shortID).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("could not get original URL: %w", ErrURLNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not get original URL: %v", err)
+	}
+	return decodeLink(shortID, value)
+}
+
+// SaveLinkMetadata updates the link record with its page metadata. The link keeps
+// its TTL and is not recreated if it expired in the meantime.
+func (r *RedisStore) SaveLinkMetadata(ctx context.Context, shortID string, meta *metadata.Metadata) (err error) {
+	ctx, span := tracing.Start(ctx, "RedisStore.SaveLinkMetadata", attribute.String("short_id", shortID))
+	defer func() { tracing.End(span, err) }()
+
+	link, err := r.GetLink(ctx, shortID)
+	if err != nil {
+		return err
+	}
+	link.Metadata = meta
+
+	data, err := json.Marshal(link)
+	if err != nil {
+		return fmt.Errorf("failed to encode link: %v", err)
+	}
+
+	err = r.client().SetArgs(ctx, shortID, data, redis.SetArgs{Mode: "XX", KeepTTL: true}).Err()
+	if errors.Is(err, redis.Nil) {
+		return fmt.Errorf("could not save link metadata: %w", ErrURLNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save link metadata: %v", err)
+	}
+	return nil
+}
+
+// DeleteLink removes the link record and its click counter. It fails with
+// ErrURLNotFound when no link is stored under the short ID.
+func (r *RedisStore) DeleteLink(ctx context.Context, shortID string) (err error) {
+	ctx, span := tracing.Start(ctx, "RedisStore.DeleteLink", attribute.String("short_id", shortID))
+	defer func() { tracing.End(span, err) }()
+
+	// The hash tag keeps both keys in one cluster slot
+	pipe := r.client().TxPipeline()
+	deleted := pipe.Del(ctx, shortID)
+	pipe.Del(ctx, clicksKey(shortID))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to delete link: %v", err)
+	}
+	if deleted.Val() == 0 {
+		return fmt.Errorf("could not delete link: %w", ErrURLNotFound)
+	}
+	return nil
+}
+
+// decodeLink parses a stored link record.
+// Values written before link records existed hold only the original URL.
+func decodeLink(shortID, value string) (*model.Link, error) {
+	if !strings.HasPrefix(value, "{") {
+		return &model.Link{ShortID: shortID, Original: value}, nil
+	}
+
+	var link model.Link
+	if err := json.Unmarshal([]byte(value), &link); err != nil {
+		return nil, fmt.Errorf("failed to decode link: %v", err)
+	}
+	link.ShortID = shortID
+	return &link, nil
+}
+
+// clicksKey returns the click counter key of a link, the hash tag keeps it
+// in the same cluster slot as the link itself
+func clicksKey(shortID string) string {
+	return fmt.Sprintf("{%s}:clicks", shortID)
+}
+
+// ConsumeClick counts a click of the link and returns the clicks used so far.
+// It fails with ErrClickLimitReached once maxClicks clicks were counted, even
+// under concurrent clicks.
+func (r *RedisStore) ConsumeClick(ctx context.Context, shortID string, maxClicks int64) (used int64, err error) {
+	ctx, span := tracing.Start(ctx, "RedisStore.ConsumeClick", attribute.String("short_id", shortID))
+	defer func() { tracing.End(span, err) }()
+
+	used, err = consumeClickScript.Run(ctx, r.client(), []string{shortID, clicksKey(shortID)}, maxClicks).Int64()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count click: %v", err)
+	}
+
+	switch used {
+	case -1:
+		return 0, ErrClickLimitReached
+	case -2:
+		return 0, fmt.Errorf("could not count click: %w", ErrURLNotFound)
+	}
+	return used, nil
+}
+
+// ClicksUsed returns the clicks counted for the link so far
+func (r *RedisStore) ClicksUsed(ctx context.Context, shortID string) (used int64, err error) {
+	ctx, span := tracing.Start(ctx, "RedisStore.ClicksUsed", attribute.String("short_id", shortID))
+	defer func() { tracing.End(span, err) }()
+
+	used, err = r.client().Get(ctx, clicksKey(shortID)).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read clicks: %v", err)
+	}
+	return used, nil
+}
@@ -11,12 +11,18 @@ package redis
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/alicebob/miniredis/v2"
 	"github.com/redis/go-redis/v9"
+	"github.com/yasin-yalcin-dev/go-url-shortener/internal/model"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/metadata"
 )
 
 // We will use miniredis to test without real Redis connection
@@ -142,6 +148,55 @@ func TestRedisStore_NonExistentURL(t *testing.T) {
 	}
 }
 
+func TestRedisStore_LinkRecord(t *testing.T) {
+	// setup mock Redis
+	mr, client := setupMockRedis()
+	defer mr.Close()
+	defer client.Close()
+
+	store := NewRedisStore(client)
+	ctx := context.Background()
+
+	link := &model.Link{
+		ShortID:  "link123",
+		Original: "https://example.com/page",
+		Owner:    "alice",
+		Tags:     []string{"launch", "blog"},
+	}
+	if err := store.SaveLinkWithTTL(ctx, link, time.Hour); err != nil {
+		t.Fatalf("SaveLinkWithTTL failed: %v", err)
+	}
+
+	retrieved, err := store.GetLink(ctx, "link123")
+	if err != nil {
+		t.Fatalf("GetLink failed: %v", err)
+	}
+	if retrieved.Original != link.Original || retrieved.Owner != "alice" || len(retrieved.Tags) != 2 {
+		t.Errorf("Unexpected link: %+v", retrieved)
+	}
+
+	// GetOriginalURL still returns the plain URL
+	originalURL, err := store.GetOriginalURL(ctx, "link123")
+	if err != nil || originalURL != link.Original {
+		t.Errorf("Expected %s, got %s (%v)", link.Original, originalURL, err)
+	}
+
+	// Values stored before link records are read as plain URLs
+	mr.Set("legacy1", "https://legacy.example.com")
+	legacy, err := store.GetLink(ctx, "legacy1")
+	if err != nil {
+		t.Fatalf("GetLink failed for legacy value: %v", err)
+	}
+	if legacy.Original != "https://legacy.example.com" || legacy.ShortID != "legacy1" {
+		t.Errorf("Unexpected legacy link: %+v", legacy)
+	}
+
+	// Missing links report ErrURLNotFound
+	if _, err := store.GetLink(ctx, "missing"); !errors.Is(err, ErrURLNotFound) {
+		t.Errorf("Expected ErrURLNotFound, got %v", err)
+	}
+}
+
 // performance test for URL shortening
 func BenchmarkRedisStore_SaveAndGet(b *testing.B) {
 	mr, client := setupMockRedis()
@@ -169,3 +224,138 @@ func BenchmarkRedisStore_SaveAndGet(b *testing.B) {
 		}
 	}
 }
+
+func TestRedisStore_ConsumeClick(t *testing.T) {
+	mr, client := setupMockRedis()
+	defer mr.Close()
+	defer client.Close()
+
+	store := NewRedisStore(client)
+	ctx := context.Background()
+
+	if _, err := store.ConsumeClick(ctx, "missing", 1); !errors.Is(err, ErrURLNotFound) {
+		t.Errorf("Expected ErrURLNotFound, got %v", err)
+	}
+
+	link := &model.Link{ShortID: "limited", Original: "https://example.com", MaxClicks: 5}
+	if err := store.SaveLinkWithTTL(ctx, link, time.Hour); err != nil {
+		t.Fatalf("SaveLinkWithTTL failed: %v", err)
+	}
+
+	// Concurrent clicks never exceed the limit
+	var wg sync.WaitGroup
+	var allowed, refused atomic.Int64
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := store.ConsumeClick(ctx, "limited", link.MaxClicks)
+			switch {
+			case err == nil:
+				allowed.Add(1)
+			case errors.Is(err, ErrClickLimitReached):
+				refused.Add(1)
+			default:
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if allowed.Load() != 5 || refused.Load() != 15 {
+		t.Errorf("Expected 5 allowed and 15 refused clicks, got %d and %d", allowed.Load(), refused.Load())
+	}
+	if used, err := store.ClicksUsed(ctx, "limited"); err != nil || used != 5 {
+		t.Errorf("Expected 5 clicks used, got %d (%v)", used, err)
+	}
+	if used, err := store.ClicksUsed(ctx, "missing"); err != nil || used != 0 {
+		t.Errorf("Expected no clicks for an uncounted link, got %d (%v)", used, err)
+	}
+
+	// The counter expires together with the link
+	if ttl := mr.TTL(clicksKey("limited")); ttl <= 0 || ttl > time.Hour {
+		t.Errorf("Expected click counter to expire with the link, got TTL %v", ttl)
+	}
+}
+
+func TestRedisStore_SaveLinkMetadata(t *testing.T) {
+	mr, client := setupMockRedis()
+	defer mr.Close()
+	defer client.Close()
+
+	store := NewRedisStore(client)
+	ctx := context.Background()
+
+	link := &model.Link{ShortID: "meta1", Original: "https://example.com", Owner: "alice"}
+	if err := store.SaveLinkWithTTL(ctx, link, time.Hour); err != nil {
+		t.Fatalf("SaveLinkWithTTL failed: %v", err)
+	}
+	mr.FastForward(10 * time.Minute)
+
+	meta := &metadata.Metadata{Title: "Example", Description: "An example page"}
+	if err := store.SaveLinkMetadata(ctx, "meta1", meta); err != nil {
+		t.Fatalf("SaveLinkMetadata failed: %v", err)
+	}
+
+	retrieved, err := store.GetLink(ctx, "meta1")
+	if err != nil {
+		t.Fatalf("GetLink failed: %v", err)
+	}
+	if retrieved.Metadata == nil || retrieved.Metadata.Title != "Example" || retrieved.Owner != "alice" {
+		t.Errorf("Unexpected link: %+v", retrieved)
+	}
+
+	// The remaining TTL is kept
+	if ttl := mr.TTL("meta1"); ttl != 50*time.Minute {
+		t.Errorf("Expected TTL of 50m, got %v", ttl)
+	}
+
+	// Expired links are not recreated
+	if err := store.SaveLinkMetadata(ctx, "missing", meta); !errors.Is(err, ErrURLNotFound) {
+		t.Errorf("Expected ErrURLNotFound, got %v", err)
+	}
+	if mr.Exists("missing") {
+		t.Error("Expected missing link not to be created")
+	}
+}
+
+func TestRedisStore_DeleteLink(t *testing.T) {
+	mr, client := setupMockRedis()
+	defer mr.Close()
+	defer client.Close()
+
+	store := NewRedisStore(client)
+	ctx := context.Background()
+
+	link := &model.Link{ShortID: "gone1", Original: "https://example.com", MaxClicks: 5}
+	if err := store.SaveLinkWithTTL(ctx, link, time.Hour); err != nil {
+		t.Fatalf("SaveLinkWithTTL failed: %v", err)
+	}
+	if _, err := store.ConsumeClick(ctx, "gone1", link.MaxClicks); err != nil {
+		t.Fatalf("ConsumeClick failed: %v", err)
+	}
+
+	if err := store.DeleteLink(ctx, "gone1"); err != nil {
+		t.Fatalf("DeleteLink failed: %v", err)
+	}
+	if mr.Exists("gone1") || mr.Exists(clicksKey("gone1")) {
+		t.Error("Expected the link and its click counter to be deleted")
+	}
+	if _, err := store.GetLink(ctx, "gone1"); !errors.Is(err, ErrURLNotFound) {
+		t.Errorf("Expected ErrURLNotFound, got %v", err)
+	}
+
+	if err := store.DeleteLink(ctx, "gone1"); !errors.Is(err, ErrURLNotFound) {
+		t.Errorf("Expected ErrURLNotFound for a deleted link, got %v", err)
+	}
+}
+
+func TestClicksKey_SharesSlot(t *testing.T) {
+	// A key without braces is hashed as a whole, so the link key "abc123"
+	// and "{abc123}:clicks" map to the same cluster slot
+	key := clicksKey("abc123")
+	start, end := strings.IndexByte(key, '{'), strings.IndexByte(key, '}')
+	if start != 0 || key[start+1:end] != "abc123" {
+		t.Errorf("Expected %s to be tagged with the short ID", key)
+	}
+}
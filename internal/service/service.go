@@ -11,19 +11,64 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/yasin-yalcin-dev/go-url-shortener/internal/audit"
 	"github.com/yasin-yalcin-dev/go-url-shortener/internal/config"
+	"github.com/yasin-yalcin-dev/go-url-shortener/internal/model"
 	"github.com/yasin-yalcin-dev/go-url-shortener/internal/redis"
+	customerrors "github.com/yasin-yalcin-dev/go-url-shortener/pkg/errors"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/metadata"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/metrics"
 	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/shortener"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/targeting"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/tracing"
 	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/validator"
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/crypto/bcrypt"
 )
 
 // URLShorteningService defines methods for URL shortening
 type URLShorteningService interface {
 	ShortenURL(ctx context.Context, originalURL string, options ...URLShortenOption) (string, error)
 	GetOriginalURL(ctx context.Context, shortID string) (string, error)
+	GetLink(ctx context.Context, shortID string) (*model.Link, error)
+	ConsumeClick(ctx context.Context, link *model.Link) error
+	CheckClicks(ctx context.Context, link *model.Link) error
+	DeleteURL(ctx context.Context, shortID string) error
+}
+
+const (
+	// bcrypt ignores everything after 72 bytes
+	maxPasswordLength = 72
+
+	maxOwnerLength = 64
+	maxTagLength   = 32
+	maxTags        = 10
+	maxRules       = 20
+	maxTitleLength = 200
+
+	// Background metadata fetches running at once, more are skipped
+	maxMetadataFetches = 16
+	metadataTimeout    = 30 * time.Second
+)
+
+// errMetadataBusy occurs when too many metadata fetches are already running
+var errMetadataBusy = errors.New("too many metadata fetches in progress")
+
+// MetadataFetcher fetches the page metadata of a URL
+type MetadataFetcher interface {
+	Fetch(ctx context.Context, rawURL string) (*metadata.Metadata, error)
+}
+
+// AuditRecorder appends link mutations to the audit log
+type AuditRecorder interface {
+	Record(ctx context.Context, event audit.Event) error
 }
 
 // URLShorteningServiceImpl implements the URLShorteningService interface
@@ -31,31 +76,191 @@ type URLShorteningServiceImpl struct {
 	cfg       *config.Config
 	Store     redis.URLStore
 	validator *validator.URLValidator
+
+	fetcher         MetadataFetcher
+	onMetadataError func(shortID string, err error)
+	metadataSlots   chan struct{}
+	metadataWG      sync.WaitGroup
+	metrics         *metrics.Metrics
+	auditLog        AuditRecorder
+	onAuditError    func(event audit.Event, err error)
+}
+
+// ServiceOption configures optional service behaviour
+type ServiceOption func(*URLShorteningServiceImpl)
+
+// WithMetadataFetcher fetches the page metadata of new links in the background.
+// onError is called with failed fetches and may be nil.
+func WithMetadataFetcher(fetcher MetadataFetcher, onError func(shortID string, err error)) ServiceOption {
+	return func(s *URLShorteningServiceImpl) {
+		s.fetcher = fetcher
+		s.onMetadataError = onError
+	}
+}
+
+// WithMetrics counts short ID collisions
+func WithMetrics(m *metrics.Metrics) ServiceOption {
+	return func(s *URLShorteningServiceImpl) {
+		s.metrics = m
+	}
 }
 
-func NewURLShorteningService(cfg *config.Config, store redis.URLStore) *URLShorteningServiceImpl {
-	return &URLShorteningServiceImpl{
-		cfg:       cfg,
-		Store:     store,
-		validator: validator.NewURLValidator(),
+// WithAuditLog records every link mutation. Events are recorded after the
+// mutation succeeded, onError is called with events that could not be
+// recorded and may be nil.
+func WithAuditLog(recorder AuditRecorder, onError func(event audit.Event, err error)) ServiceOption {
+	return func(s *URLShorteningServiceImpl) {
+		s.auditLog = recorder
+		s.onAuditError = onError
+	}
+}
+
+func NewURLShorteningService(cfg *config.Config, store redis.URLStore, options ...ServiceOption) *URLShorteningServiceImpl {
+	s := &URLShorteningServiceImpl{
+		cfg:           cfg,
+		Store:         store,
+		validator:     validator.NewURLValidator(),
+		metadataSlots: make(chan struct{}, maxMetadataFetches),
+	}
+	for _, opt := range options {
+		opt(s)
 	}
+	return s
 }
 
 // Structures for URL shortening options
 type URLShortenOption func(*urlShortenOptions)
 
 type urlShortenOptions struct {
-	ttl time.Duration
+	ttl              time.Duration
+	ttlSet           bool
+	expiresAt        time.Time
+	notBefore        time.Time
+	owner            string
+	tags             []string
+	noTracking       bool
+	redirectCode     int
+	queryPassthrough string
+	pathPassthrough  bool
+	password         string
+	maxClicks        int64
+	rules            []targeting.Rule
+	variants         []targeting.Variant
+	title            string
+	interstitial     bool
 }
 
 // Optional function to set expiration time withTTL
 func WithTTL(duration time.Duration) URLShortenOption {
 	return func(opts *urlShortenOptions) {
 		opts.ttl = duration
+		opts.ttlSet = true
 	}
 }
 
-func (s *URLShorteningServiceImpl) ShortenURL(ctx context.Context, originalURL string, options ...URLShortenOption) (string, error) {
+// WithExpiresAt makes the link expire at an absolute time instead of after a TTL
+func WithExpiresAt(expiresAt time.Time) URLShortenOption {
+	return func(opts *urlShortenOptions) {
+		opts.expiresAt = expiresAt
+	}
+}
+
+// WithNotBefore keeps the link inactive until the given time
+func WithNotBefore(notBefore time.Time) URLShortenOption {
+	return func(opts *urlShortenOptions) {
+		opts.notBefore = notBefore
+	}
+}
+
+// WithOwner sets the owner of the link, used for per-owner dashboards
+func WithOwner(owner string) URLShortenOption {
+	return func(opts *urlShortenOptions) {
+		opts.owner = owner
+	}
+}
+
+// WithTags sets the tags of the link, used for per-tag dashboards
+func WithTags(tags ...string) URLShortenOption {
+	return func(opts *urlShortenOptions) {
+		opts.tags = append(opts.tags, tags...)
+	}
+}
+
+// WithNoTracking disables analytics for the link
+func WithNoTracking() URLShortenOption {
+	return func(opts *urlShortenOptions) {
+		opts.noTracking = true
+	}
+}
+
+// WithRedirectCode sets the HTTP status used to redirect the link (301, 302, 307 or 308)
+func WithRedirectCode(code int) URLShortenOption {
+	return func(opts *urlShortenOptions) {
+		opts.redirectCode = code
+	}
+}
+
+// WithQueryPassthrough merges incoming query parameters into the destination,
+// mode decides which value wins on conflict ("keep" or "override")
+func WithQueryPassthrough(mode string) URLShortenOption {
+	return func(opts *urlShortenOptions) {
+		opts.queryPassthrough = mode
+	}
+}
+
+// WithPathPassthrough appends the path after the short ID to the destination
+func WithPathPassthrough() URLShortenOption {
+	return func(opts *urlShortenOptions) {
+		opts.pathPassthrough = true
+	}
+}
+
+// WithPassword requires the password to follow the link, only its hash is stored
+func WithPassword(password string) URLShortenOption {
+	return func(opts *urlShortenOptions) {
+		opts.password = password
+	}
+}
+
+// WithMaxClicks makes the link expire after the given number of redirects
+func WithMaxClicks(clicks int64) URLShortenOption {
+	return func(opts *urlShortenOptions) {
+		opts.maxClicks = clicks
+	}
+}
+
+// WithRules sets ordered targeting rules, the original URL is the fallback
+func WithRules(rules ...targeting.Rule) URLShortenOption {
+	return func(opts *urlShortenOptions) {
+		opts.rules = append(opts.rules, rules...)
+	}
+}
+
+// WithVariants splits visitors between weighted destinations
+func WithVariants(variants ...targeting.Variant) URLShortenOption {
+	return func(opts *urlShortenOptions) {
+		opts.variants = append(opts.variants, variants...)
+	}
+}
+
+// WithTitle sets the title shown on the preview page
+func WithTitle(title string) URLShortenOption {
+	return func(opts *urlShortenOptions) {
+		opts.title = title
+	}
+}
+
+// WithInterstitial shows the preview page on every visit instead of redirecting
+func WithInterstitial() URLShortenOption {
+	return func(opts *urlShortenOptions) {
+		opts.interstitial = true
+	}
+}
+
+func (s *URLShorteningServiceImpl) ShortenURL(ctx context.Context, originalURL string, options ...URLShortenOption) (shortURL string, err error) {
+	ctx, span := tracing.Start(ctx, "URLShorteningService.ShortenURL")
+	defer func() { tracing.End(span, err) }()
+
 	// Validate URL
 	if apiErr := s.validator.Validate(originalURL); apiErr != nil {
 		return "", apiErr
@@ -69,21 +274,419 @@ func (s *URLShorteningServiceImpl) ShortenURL(ctx context.Context, originalURL s
 		opt(opts)
 	}
 
+	owner := strings.TrimSpace(opts.owner)
+	if len(owner) > maxOwnerLength {
+		return "", customerrors.New(
+			http.StatusBadRequest,
+			"Owner is too long",
+			fmt.Sprintf("Owner may be at most %d characters", maxOwnerLength),
+		)
+	}
+	title := strings.TrimSpace(opts.title)
+	if len(title) > maxTitleLength {
+		return "", customerrors.New(
+			http.StatusBadRequest,
+			"Title is too long",
+			fmt.Sprintf("Title may be at most %d characters", maxTitleLength),
+		)
+	}
+	tags, apiErr := normalizeTags(opts.tags)
+	if apiErr != nil {
+		return "", apiErr
+	}
+	if opts.redirectCode != 0 && !model.ValidRedirectCode(opts.redirectCode) {
+		return "", customerrors.New(
+			http.StatusBadRequest,
+			"Invalid redirect code",
+			"Redirect code must be 301, 302, 307 or 308",
+		)
+	}
+	if !model.ValidQueryPassthrough(opts.queryPassthrough) {
+		return "", customerrors.New(
+			http.StatusBadRequest,
+			"Invalid query passthrough mode",
+			"Query passthrough must be keep or override",
+		)
+	}
+	rules, apiErr := s.normalizeRules(opts.rules)
+	if apiErr != nil {
+		return "", apiErr
+	}
+	variants, apiErr := s.normalizeVariants(opts.variants)
+	if apiErr != nil {
+		return "", apiErr
+	}
+	notBefore, expiresAt, apiErr := schedule(opts, time.Now().UTC())
+	if apiErr != nil {
+		return "", apiErr
+	}
+	if opts.maxClicks < 0 {
+		return "", customerrors.New(
+			http.StatusBadRequest,
+			"Invalid click limit",
+			"Max clicks cannot be negative",
+		)
+	}
+	if len(opts.password) > maxPasswordLength {
+		return "", customerrors.New(
+			http.StatusBadRequest,
+			"Password is too long",
+			fmt.Sprintf("Password may be at most %d bytes", maxPasswordLength),
+		)
+	}
+	var passwordHash string
+	if opts.password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(opts.password), bcrypt.DefaultCost)
+		if err != nil {
+			return "", fmt.Errorf("failed to hash password: %v", err)
+		}
+		passwordHash = string(hash)
+	}
+
 	shortID, err := shortener.GenerateUnique(func(id string) bool {
 		// Check if this ID exists in Redis
 		_, err := s.Store.GetOriginalURL(ctx, id)
-		return err == nil // If the error is nil, the ID already exists
+		if err == nil { // If the error is nil, the ID already exists
+			s.metrics.IDCollision()
+			return true
+		}
+		return false
 	})
 	if err != nil {
 		return "", err
 	}
-	err = s.Store.SaveShortenedURLWithTTL(ctx, shortID, originalURL, opts.ttl)
+	span.SetAttributes(attribute.String("short_id", shortID))
+	link := &model.Link{
+		ShortID:          shortID,
+		Original:         originalURL,
+		Title:            title,
+		Owner:            owner,
+		Tags:             tags,
+		NoTracking:       opts.noTracking,
+		RedirectCode:     opts.redirectCode,
+		QueryPassthrough: opts.queryPassthrough,
+		PathPassthrough:  opts.pathPassthrough,
+		PasswordHash:     passwordHash,
+		MaxClicks:        opts.maxClicks,
+		Rules:            rules,
+		Variants:         variants,
+		Interstitial:     opts.interstitial,
+		NotBefore:        notBefore,
+		ExpiresAt:        expiresAt,
+		CreatedAt:        time.Now().UTC(),
+	}
+	err = s.Store.SaveLinkWithTTL(ctx, link, opts.ttl)
 	if err != nil {
 		return "", err
 	}
+	s.audit(ctx, audit.ActionCreate, nil, link)
+	s.fetchMetadata(ctx, link)
 	return fmt.Sprintf("%s/%s", s.cfg.BaseURL, shortID), nil
 }
 
-func (s *URLShorteningServiceImpl) GetOriginalURL(ctx context.Context, shortID string) (string, error) {
+// fetchMetadata stores the page metadata of a new link without delaying the response
+func (s *URLShorteningServiceImpl) fetchMetadata(ctx context.Context, link *model.Link) {
+	if s.fetcher == nil {
+		return
+	}
+	shortID := link.ShortID
+	select {
+	case s.metadataSlots <- struct{}{}:
+	default:
+		s.metadataFailed(shortID, errMetadataBusy)
+		return
+	}
+
+	before := *link
+
+	s.metadataWG.Add(1)
+	go func() {
+		defer s.metadataWG.Done()
+		defer func() { <-s.metadataSlots }()
+
+		// The request context ends with the response, the fetch outlives it.
+		// Its update is audited as made by the service within that request.
+		ctx, cancel := context.WithTimeout(audit.WithActor(context.WithoutCancel(ctx), audit.ActorSystem), metadataTimeout)
+		defer cancel()
+
+		meta, err := s.fetcher.Fetch(ctx, before.Original)
+		if err != nil {
+			s.metadataFailed(shortID, err)
+			return
+		}
+		if err := s.Store.SaveLinkMetadata(ctx, shortID, meta); err != nil {
+			s.metadataFailed(shortID, err)
+			return
+		}
+		after := before
+		after.Metadata = meta
+		s.audit(ctx, audit.ActionUpdate, &before, &after)
+	}()
+}
+
+// metadataFailed reports a failed metadata fetch
+func (s *URLShorteningServiceImpl) metadataFailed(shortID string, err error) {
+	if s.onMetadataError != nil {
+		s.onMetadataError(shortID, err)
+	}
+}
+
+// audit records a link mutation made in ctx
+func (s *URLShorteningServiceImpl) audit(ctx context.Context, action string, before, after *model.Link) {
+	if s.auditLog == nil {
+		return
+	}
+	event := audit.NewEvent(ctx, action, before, after)
+	if err := s.auditLog.Record(ctx, event); err != nil && s.onAuditError != nil {
+		s.onAuditError(event, err)
+	}
+}
+
+// Close waits for background metadata fetches until the context is done
+func (s *URLShorteningServiceImpl) Close(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.metadataWG.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *URLShorteningServiceImpl) GetOriginalURL(ctx context.Context, shortID string) (originalURL string, err error) {
+	ctx, span := tracing.Start(ctx, "URLShorteningService.GetOriginalURL", attribute.String("short_id", shortID))
+	defer func() { tracing.End(span, err) }()
+
 	return s.Store.GetOriginalURL(ctx, shortID)
 }
+
+// GetLink retrieves the stored link record
+func (s *URLShorteningServiceImpl) GetLink(ctx context.Context, shortID string) (link *model.Link, err error) {
+	ctx, span := tracing.Start(ctx, "URLShorteningService.GetLink", attribute.String("short_id", shortID))
+	defer func() { tracing.End(span, err) }()
+
+	return s.Store.GetLink(ctx, shortID)
+}
+
+// DeleteURL removes a link and its click counter, analytics are kept
+func (s *URLShorteningServiceImpl) DeleteURL(ctx context.Context, shortID string) (err error) {
+	ctx, span := tracing.Start(ctx, "URLShorteningService.DeleteURL", attribute.String("short_id", shortID))
+	defer func() { tracing.End(span, err) }()
+
+	link, err := s.Store.GetLink(ctx, shortID)
+	if err == nil {
+		err = s.Store.DeleteLink(ctx, shortID)
+	}
+	if errors.Is(err, redis.ErrURLNotFound) {
+		return customerrors.New(
+			http.StatusNotFound,
+			"Short URL not found",
+			"The requested short URL does not exist",
+		)
+	}
+	if err != nil {
+		return err
+	}
+	s.audit(ctx, audit.ActionDelete, link, nil)
+	return nil
+}
+
+// normalizeRules validates the targeting rules and their target URLs
+func (s *URLShorteningServiceImpl) normalizeRules(rules []targeting.Rule) ([]targeting.Rule, *customerrors.APIError) {
+	if len(rules) > maxRules {
+		return nil, customerrors.New(
+			http.StatusBadRequest,
+			"Too many rules",
+			fmt.Sprintf("A link may have at most %d rules", maxRules),
+		)
+	}
+
+	normalized := make([]targeting.Rule, 0, len(rules))
+	for i, rule := range rules {
+		rule, err := rule.Normalize()
+		if err != nil {
+			return nil, customerrors.New(
+				http.StatusBadRequest,
+				"Invalid rule",
+				fmt.Sprintf("Rule %d: %v", i+1, err),
+			)
+		}
+		if apiErr := s.validator.Validate(rule.Target); apiErr != nil {
+			return nil, apiErr
+		}
+		normalized = append(normalized, rule)
+	}
+	return normalized, nil
+}
+
+// normalizeVariants validates the A/B variants and their target URLs
+func (s *URLShorteningServiceImpl) normalizeVariants(variants []targeting.Variant) ([]targeting.Variant, *customerrors.APIError) {
+	if len(variants) == 0 {
+		return nil, nil
+	}
+
+	normalized, err := targeting.NormalizeVariants(variants)
+	if err != nil {
+		return nil, customerrors.New(
+			http.StatusBadRequest,
+			"Invalid variants",
+			err.Error(),
+		)
+	}
+	for _, variant := range normalized {
+		if apiErr := s.validator.Validate(variant.Target); apiErr != nil {
+			return nil, apiErr
+		}
+	}
+	return normalized, nil
+}
+
+// schedule validates the activation and expiry of a link and sets the TTL
+// of the record so it expires at ExpiresAt
+func schedule(opts *urlShortenOptions, now time.Time) (*time.Time, *time.Time, *customerrors.APIError) {
+	var notBefore, expiresAt *time.Time
+
+	if !opts.expiresAt.IsZero() {
+		if opts.ttlSet {
+			return nil, nil, customerrors.New(
+				http.StatusBadRequest,
+				"Conflicting expiry",
+				"Use either ttl or expires_at",
+			)
+		}
+		if !opts.expiresAt.After(now) {
+			return nil, nil, customerrors.New(
+				http.StatusBadRequest,
+				"Invalid expiry",
+				"expires_at must be in the future",
+			)
+		}
+		at := opts.expiresAt.UTC()
+		expiresAt = &at
+		opts.ttl = at.Sub(now)
+	} else if opts.ttl > 0 {
+		// Without an explicit TTL the default lifetime starts at activation
+		if !opts.ttlSet && opts.notBefore.After(now) {
+			opts.ttl += opts.notBefore.Sub(now)
+		}
+		at := now.Add(opts.ttl)
+		expiresAt = &at
+	} else if opts.ttl < 0 {
+		return nil, nil, customerrors.New(
+			http.StatusBadRequest,
+			"Invalid TTL",
+			"TTL cannot be negative",
+		)
+	}
+
+	if !opts.notBefore.IsZero() {
+		at := opts.notBefore.UTC()
+		if expiresAt != nil && !at.Before(*expiresAt) {
+			return nil, nil, customerrors.New(
+				http.StatusBadRequest,
+				"Invalid schedule",
+				"not_before must be before the link expires",
+			)
+		}
+		notBefore = &at
+	}
+
+	return notBefore, expiresAt, nil
+}
+
+// ConsumeClick counts a redirect of a click-limited link and fails with a
+// 410 Gone APIError once its clicks are used up
+func (s *URLShorteningServiceImpl) ConsumeClick(ctx context.Context, link *model.Link) (err error) {
+	if link.MaxClicks <= 0 {
+		return nil
+	}
+	ctx, span := tracing.Start(ctx, "URLShorteningService.ConsumeClick", attribute.String("short_id", link.ShortID))
+	defer func() { tracing.End(span, err) }()
+
+	_, err = s.Store.ConsumeClick(ctx, link.ShortID, link.MaxClicks)
+	switch {
+	case errors.Is(err, redis.ErrClickLimitReached):
+		return errClickLimitReached()
+	case errors.Is(err, redis.ErrURLNotFound):
+		return customerrors.New(
+			http.StatusNotFound,
+			"Short URL not found",
+			"The requested short URL does not exist",
+		)
+	}
+	return err
+}
+
+// CheckClicks fails like ConsumeClick once the clicks of a click-limited
+// link are used up, without counting a click itself
+func (s *URLShorteningServiceImpl) CheckClicks(ctx context.Context, link *model.Link) (err error) {
+	if link.MaxClicks <= 0 {
+		return nil
+	}
+	ctx, span := tracing.Start(ctx, "URLShorteningService.CheckClicks", attribute.String("short_id", link.ShortID))
+	defer func() { tracing.End(span, err) }()
+
+	used, err := s.Store.ClicksUsed(ctx, link.ShortID)
+	if err != nil {
+		return err
+	}
+	if used >= link.MaxClicks {
+		return errClickLimitReached()
+	}
+	return nil
+}
+
+// errClickLimitReached is returned for links whose clicks are used up
+func errClickLimitReached() *customerrors.APIError {
+	return customerrors.New(
+		http.StatusGone,
+		"Link is no longer available",
+		"The link has reached its click limit",
+	)
+}
+
+// VerifyPassword reports whether password unlocks the link, the hash
+// comparison runs in constant time
+func VerifyPassword(link *model.Link, password string) bool {
+	if !link.Protected() {
+		return true
+	}
+	return bcrypt.CompareHashAndPassword([]byte(link.PasswordHash), []byte(password)) == nil
+}
+
+// normalizeTags lower-cases, trims and de-duplicates tags
+func normalizeTags(tags []string) ([]string, *customerrors.APIError) {
+	var normalized []string
+	seen := make(map[string]bool)
+
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if len(tag) > maxTagLength {
+			return nil, customerrors.New(
+				http.StatusBadRequest,
+				"Tag is too long",
+				fmt.Sprintf("Tags may be at most %d characters", maxTagLength),
+			)
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+
+	if len(normalized) > maxTags {
+		return nil, customerrors.New(
+			http.StatusBadRequest,
+			"Too many tags",
+			fmt.Sprintf("A link may have at most %d tags", maxTags),
+		)
+	}
+
+	return normalized, nil
+}
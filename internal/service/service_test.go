@@ -11,17 +11,31 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/yasin-yalcin-dev/go-url-shortener/internal/audit"
 	"github.com/yasin-yalcin-dev/go-url-shortener/internal/config"
+	"github.com/yasin-yalcin-dev/go-url-shortener/internal/model"
+	"github.com/yasin-yalcin-dev/go-url-shortener/internal/redis"
+	customerrors "github.com/yasin-yalcin-dev/go-url-shortener/pkg/errors"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/metadata"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/metrics"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/targeting"
 )
 
 // Mock Redis Store
 type mockRedisStore struct {
-	urls map[string]string
+	urls   map[string]string
+	links  map[string]*model.Link
+	clicks map[string]int64
 }
 
 func (m *mockRedisStore) SaveShortenedURLWithTTL(ctx context.Context, shortID, originalURL string, ttl time.Duration) error {
@@ -37,6 +51,63 @@ func (m *mockRedisStore) GetOriginalURL(ctx context.Context, shortID string) (st
 	return url, nil
 }
 
+func (m *mockRedisStore) SaveLinkWithTTL(ctx context.Context, link *model.Link, ttl time.Duration) error {
+	if m.links == nil {
+		m.links = make(map[string]*model.Link)
+	}
+	m.urls[link.ShortID] = link.Original
+	m.links[link.ShortID] = link
+	return nil
+}
+
+func (m *mockRedisStore) GetLink(ctx context.Context, shortID string) (*model.Link, error) {
+	if link, exists := m.links[shortID]; exists {
+		return link, nil
+	}
+	url, exists := m.urls[shortID]
+	if !exists {
+		return nil, fmt.Errorf("URL not found")
+	}
+	return &model.Link{ShortID: shortID, Original: url}, nil
+}
+
+func (m *mockRedisStore) SaveLinkMetadata(ctx context.Context, shortID string, meta *metadata.Metadata) error {
+	link, exists := m.links[shortID]
+	if !exists {
+		return redis.ErrURLNotFound
+	}
+	link.Metadata = meta
+	return nil
+}
+
+func (m *mockRedisStore) ConsumeClick(ctx context.Context, shortID string, maxClicks int64) (int64, error) {
+	if _, exists := m.urls[shortID]; !exists {
+		return 0, redis.ErrURLNotFound
+	}
+	if m.clicks == nil {
+		m.clicks = make(map[string]int64)
+	}
+	if m.clicks[shortID] >= maxClicks {
+		return 0, redis.ErrClickLimitReached
+	}
+	m.clicks[shortID]++
+	return m.clicks[shortID], nil
+}
+
+func (m *mockRedisStore) ClicksUsed(ctx context.Context, shortID string) (int64, error) {
+	return m.clicks[shortID], nil
+}
+
+func (m *mockRedisStore) DeleteLink(ctx context.Context, shortID string) error {
+	if _, exists := m.urls[shortID]; !exists {
+		return redis.ErrURLNotFound
+	}
+	delete(m.urls, shortID)
+	delete(m.links, shortID)
+	delete(m.clicks, shortID)
+	return nil
+}
+
 func TestShortenURL(t *testing.T) {
 	testCases := []struct {
 		name          string
@@ -162,6 +233,629 @@ func TestGetOriginalURL(t *testing.T) {
 	}
 }
 
+func TestShortenURL_OwnerAndTags(t *testing.T) {
+	cfg := &config.Config{
+		BaseURL:       "http://short.url",
+		DefaultURLTTL: 24 * time.Hour,
+	}
+	mockStore := &mockRedisStore{urls: make(map[string]string)}
+	service := NewURLShorteningService(cfg, mockStore)
+
+	testCases := []struct {
+		name          string
+		options       []URLShortenOption
+		expectedError bool
+		expectedTags  []string
+	}{
+		{
+			name:         "Tags Are Normalized",
+			options:      []URLShortenOption{WithOwner("alice"), WithTags(" Launch", "launch", "BLOG", "")},
+			expectedTags: []string{"launch", "blog"},
+		},
+		{
+			name:          "Too Many Tags",
+			options:       []URLShortenOption{WithTags("a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k")},
+			expectedError: true,
+		},
+		{
+			name:          "Owner Too Long",
+			options:       []URLShortenOption{WithOwner(strings.Repeat("x", maxOwnerLength+1))},
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			shortenedURL, err := service.ShortenURL(context.Background(), "https://example.com", tc.options...)
+			if tc.expectedError {
+				if err == nil {
+					t.Errorf("Expected error, but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			shortID := strings.TrimPrefix(shortenedURL, cfg.BaseURL+"/")
+			link, err := service.GetLink(context.Background(), shortID)
+			if err != nil {
+				t.Fatalf("GetLink failed: %v", err)
+			}
+			if link.Owner != "alice" {
+				t.Errorf("Expected owner alice, got %s", link.Owner)
+			}
+			if strings.Join(link.Tags, ",") != strings.Join(tc.expectedTags, ",") {
+				t.Errorf("Expected tags %v, got %v", tc.expectedTags, link.Tags)
+			}
+		})
+	}
+}
+
+func TestShortenURL_RedirectCode(t *testing.T) {
+	cfg := &config.Config{
+		BaseURL:       "http://short.url",
+		DefaultURLTTL: 24 * time.Hour,
+	}
+	mockStore := &mockRedisStore{urls: make(map[string]string)}
+	service := NewURLShorteningService(cfg, mockStore)
+
+	testCases := []struct {
+		name           string
+		code           int
+		expectedError  bool
+		expectedStatus int
+	}{
+		{name: "Default", code: 0, expectedStatus: http.StatusFound},
+		{name: "Permanent", code: http.StatusMovedPermanently, expectedStatus: http.StatusMovedPermanently},
+		{name: "Preserve Method", code: http.StatusTemporaryRedirect, expectedStatus: http.StatusTemporaryRedirect},
+		{name: "Permanent Preserve Method", code: http.StatusPermanentRedirect, expectedStatus: http.StatusPermanentRedirect},
+		{name: "Not A Redirect", code: http.StatusOK, expectedError: true},
+		{name: "Unsupported Redirect", code: http.StatusSeeOther, expectedError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			shortenedURL, err := service.ShortenURL(context.Background(), "https://example.com", WithRedirectCode(tc.code))
+			if tc.expectedError {
+				if err == nil {
+					t.Errorf("Expected error, but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			shortID := strings.TrimPrefix(shortenedURL, cfg.BaseURL+"/")
+			link, err := service.GetLink(context.Background(), shortID)
+			if err != nil {
+				t.Fatalf("GetLink failed: %v", err)
+			}
+			if link.RedirectStatus() != tc.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tc.expectedStatus, link.RedirectStatus())
+			}
+		})
+	}
+}
+
+func TestShortenURL_Passthrough(t *testing.T) {
+	cfg := &config.Config{
+		BaseURL:       "http://short.url",
+		DefaultURLTTL: 24 * time.Hour,
+	}
+	mockStore := &mockRedisStore{urls: make(map[string]string)}
+	service := NewURLShorteningService(cfg, mockStore)
+
+	if _, err := service.ShortenURL(context.Background(), "https://example.com", WithQueryPassthrough("append")); err == nil {
+		t.Errorf("Expected error for unknown query passthrough mode")
+	}
+
+	shortenedURL, err := service.ShortenURL(context.Background(), "https://example.com",
+		WithQueryPassthrough(model.QueryPassthroughOverride), WithPathPassthrough())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	link, err := service.GetLink(context.Background(), strings.TrimPrefix(shortenedURL, cfg.BaseURL+"/"))
+	if err != nil {
+		t.Fatalf("GetLink failed: %v", err)
+	}
+	if link.QueryPassthrough != model.QueryPassthroughOverride || !link.PathPassthrough {
+		t.Errorf("Expected passthrough policy to be stored, got %q and %v", link.QueryPassthrough, link.PathPassthrough)
+	}
+}
+
+func TestShortenURL_Password(t *testing.T) {
+	cfg := &config.Config{
+		BaseURL:       "http://short.url",
+		DefaultURLTTL: 24 * time.Hour,
+	}
+	mockStore := &mockRedisStore{urls: make(map[string]string)}
+	service := NewURLShorteningService(cfg, mockStore)
+
+	if _, err := service.ShortenURL(context.Background(), "https://example.com", WithPassword(strings.Repeat("x", maxPasswordLength+1))); err == nil {
+		t.Errorf("Expected error for a too long password")
+	}
+
+	shortenedURL, err := service.ShortenURL(context.Background(), "https://example.com", WithPassword("s3cret"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	link, err := service.GetLink(context.Background(), strings.TrimPrefix(shortenedURL, cfg.BaseURL+"/"))
+	if err != nil {
+		t.Fatalf("GetLink failed: %v", err)
+	}
+	if !link.Protected() || link.PasswordHash == "s3cret" {
+		t.Fatalf("Expected only a password hash to be stored, got %q", link.PasswordHash)
+	}
+	if !VerifyPassword(link, "s3cret") {
+		t.Errorf("Expected correct password to be accepted")
+	}
+	if VerifyPassword(link, "S3cret") {
+		t.Errorf("Expected wrong password to be rejected")
+	}
+	if !VerifyPassword(&model.Link{}, "") {
+		t.Errorf("Expected unprotected link to need no password")
+	}
+}
+
+func TestConsumeClick(t *testing.T) {
+	cfg := &config.Config{
+		BaseURL:       "http://short.url",
+		DefaultURLTTL: 24 * time.Hour,
+	}
+	mockStore := &mockRedisStore{urls: make(map[string]string)}
+	service := NewURLShorteningService(cfg, mockStore)
+
+	if _, err := service.ShortenURL(context.Background(), "https://example.com", WithMaxClicks(-1)); err == nil {
+		t.Errorf("Expected error for a negative click limit")
+	}
+
+	shortenedURL, err := service.ShortenURL(context.Background(), "https://example.com", WithMaxClicks(1))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	link, err := service.GetLink(context.Background(), strings.TrimPrefix(shortenedURL, cfg.BaseURL+"/"))
+	if err != nil {
+		t.Fatalf("GetLink failed: %v", err)
+	}
+
+	// Checks do not use up clicks
+	for i := 0; i < 2; i++ {
+		if err := service.CheckClicks(context.Background(), link); err != nil {
+			t.Fatalf("Expected check to pass, got %v", err)
+		}
+	}
+
+	if err := service.ConsumeClick(context.Background(), link); err != nil {
+		t.Fatalf("Expected first click to pass, got %v", err)
+	}
+
+	err = service.ConsumeClick(context.Background(), link)
+	apiErr, ok := err.(*customerrors.APIError)
+	if !ok || apiErr.Code != http.StatusGone {
+		t.Errorf("Expected 410 Gone once the clicks are used up, got %v", err)
+	}
+	err = service.CheckClicks(context.Background(), link)
+	if apiErr, ok := err.(*customerrors.APIError); !ok || apiErr.Code != http.StatusGone {
+		t.Errorf("Expected check to fail with 410 Gone, got %v", err)
+	}
+
+	// Unlimited links are never counted
+	if err := service.ConsumeClick(context.Background(), &model.Link{ShortID: "unknown"}); err != nil {
+		t.Errorf("Expected unlimited link to pass, got %v", err)
+	}
+}
+
+func TestShortenURL_Schedule(t *testing.T) {
+	cfg := &config.Config{
+		BaseURL:       "http://short.url",
+		DefaultURLTTL: 24 * time.Hour,
+	}
+	mockStore := &mockRedisStore{urls: make(map[string]string)}
+	service := NewURLShorteningService(cfg, mockStore)
+
+	now := time.Now()
+	launch := now.Add(48 * time.Hour)
+	end := now.Add(96 * time.Hour)
+
+	testCases := []struct {
+		name            string
+		options         []URLShortenOption
+		expectedError   bool
+		expectNotBefore bool
+		expectedExpiry  time.Duration
+	}{
+		{
+			name:           "Default TTL",
+			expectedExpiry: 24 * time.Hour,
+		},
+		{
+			name:           "Human Friendly TTL",
+			options:        []URLShortenOption{WithTTL(72 * time.Hour)},
+			expectedExpiry: 72 * time.Hour,
+		},
+		{
+			name:            "Scheduled Launch",
+			options:         []URLShortenOption{WithNotBefore(launch), WithExpiresAt(end)},
+			expectNotBefore: true,
+			expectedExpiry:  96 * time.Hour,
+		},
+		{
+			name:            "Launch Beyond Default TTL",
+			options:         []URLShortenOption{WithNotBefore(launch)},
+			expectNotBefore: true,
+			expectedExpiry:  72 * time.Hour,
+		},
+		{
+			name:          "Explicit TTL Before Launch",
+			options:       []URLShortenOption{WithNotBefore(launch), WithTTL(time.Hour)},
+			expectedError: true,
+		},
+		{
+			name:          "Negative TTL",
+			options:       []URLShortenOption{WithTTL(-time.Hour)},
+			expectedError: true,
+		},
+		{
+			name:          "TTL And Expiry",
+			options:       []URLShortenOption{WithTTL(time.Hour), WithExpiresAt(end)},
+			expectedError: true,
+		},
+		{
+			name:          "Expiry In The Past",
+			options:       []URLShortenOption{WithExpiresAt(now.Add(-time.Minute))},
+			expectedError: true,
+		},
+		{
+			name:          "Launch After Expiry",
+			options:       []URLShortenOption{WithNotBefore(end), WithExpiresAt(launch)},
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			shortenedURL, err := service.ShortenURL(context.Background(), "https://example.com", tc.options...)
+			if tc.expectedError {
+				if err == nil {
+					t.Errorf("Expected error, but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			link, err := service.GetLink(context.Background(), strings.TrimPrefix(shortenedURL, cfg.BaseURL+"/"))
+			if err != nil {
+				t.Fatalf("GetLink failed: %v", err)
+			}
+			if (link.NotBefore != nil) != tc.expectNotBefore {
+				t.Errorf("Unexpected not_before %v", link.NotBefore)
+			}
+			if link.ExpiresAt == nil {
+				t.Fatalf("Expected expires_at to be set")
+			}
+			if diff := link.ExpiresAt.Sub(now) - tc.expectedExpiry; diff < -time.Second || diff > time.Second {
+				t.Errorf("Expected expiry in %v, got %v", tc.expectedExpiry, link.ExpiresAt.Sub(now))
+			}
+		})
+	}
+}
+
+func TestShortenURL_Rules(t *testing.T) {
+	cfg := &config.Config{
+		BaseURL:       "http://short.url",
+		DefaultURLTTL: 24 * time.Hour,
+	}
+	mockStore := &mockRedisStore{urls: make(map[string]string)}
+	service := NewURLShorteningService(cfg, mockStore)
+
+	testCases := []struct {
+		name          string
+		rules         []targeting.Rule
+		expectedError bool
+	}{
+		{
+			name:  "Valid Rules",
+			rules: []targeting.Rule{{Target: "https://apps.apple.com/app", OS: []string{"iOS"}}, {Target: "https://example.com/de", Countries: []string{"de"}}},
+		},
+		{
+			name:          "Invalid Target",
+			rules:         []targeting.Rule{{Target: "not a url", OS: []string{"ios"}}},
+			expectedError: true,
+		},
+		{
+			name:          "Invalid Condition",
+			rules:         []targeting.Rule{{Target: "https://example.com", Countries: []string{"Germany"}}},
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			shortenedURL, err := service.ShortenURL(context.Background(), "https://example.com", WithRules(tc.rules...))
+			if tc.expectedError {
+				if err == nil {
+					t.Errorf("Expected error, but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			link, err := service.GetLink(context.Background(), strings.TrimPrefix(shortenedURL, cfg.BaseURL+"/"))
+			if err != nil {
+				t.Fatalf("GetLink failed: %v", err)
+			}
+			if len(link.Rules) != 2 || link.Rules[0].OS[0] != targeting.OSiOS || link.Rules[1].Countries[0] != "DE" {
+				t.Errorf("Expected normalized rules, got %+v", link.Rules)
+			}
+		})
+	}
+}
+
+func TestShortenURL_Variants(t *testing.T) {
+	cfg := &config.Config{
+		BaseURL:       "http://short.url",
+		DefaultURLTTL: 24 * time.Hour,
+	}
+	mockStore := &mockRedisStore{urls: make(map[string]string)}
+	service := NewURLShorteningService(cfg, mockStore)
+
+	valid := []targeting.Variant{
+		{Name: "control", Target: "https://example.com/a", Weight: 70},
+		{Name: "test", Target: "https://example.com/b", Weight: 30},
+	}
+	shortenedURL, err := service.ShortenURL(context.Background(), "https://example.com", WithVariants(valid...))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	link, err := service.GetLink(context.Background(), strings.TrimPrefix(shortenedURL, cfg.BaseURL+"/"))
+	if err != nil {
+		t.Fatalf("GetLink failed: %v", err)
+	}
+	if len(link.Variants) != 2 {
+		t.Errorf("Expected 2 variants, got %+v", link.Variants)
+	}
+
+	invalid := []targeting.Variant{
+		{Name: "control", Target: "https://example.com/a", Weight: 70},
+		{Name: "test", Target: "ftp://example.com/b", Weight: 30},
+	}
+	if _, err := service.ShortenURL(context.Background(), "https://example.com", WithVariants(invalid...)); err == nil {
+		t.Errorf("Expected error for an invalid variant target")
+	}
+	if _, err := service.ShortenURL(context.Background(), "https://example.com", WithVariants(valid[0])); err == nil {
+		t.Errorf("Expected error for a single variant")
+	}
+}
+
+func TestShortenURL_Preview(t *testing.T) {
+	cfg := &config.Config{
+		BaseURL:       "http://short.url",
+		DefaultURLTTL: 24 * time.Hour,
+	}
+	mockStore := &mockRedisStore{urls: make(map[string]string)}
+	service := NewURLShorteningService(cfg, mockStore)
+
+	if _, err := service.ShortenURL(context.Background(), "https://example.com", WithTitle(strings.Repeat("x", maxTitleLength+1))); err == nil {
+		t.Errorf("Expected error for a too long title")
+	}
+
+	shortenedURL, err := service.ShortenURL(context.Background(), "https://example.com", WithTitle(" Docs "), WithInterstitial())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	link, err := service.GetLink(context.Background(), strings.TrimPrefix(shortenedURL, cfg.BaseURL+"/"))
+	if err != nil {
+		t.Fatalf("GetLink failed: %v", err)
+	}
+	if link.Title != "Docs" || !link.Interstitial {
+		t.Errorf("Expected title and interstitial to be stored, got %q and %v", link.Title, link.Interstitial)
+	}
+}
+
+func TestShortenURL_Metadata(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/page" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<title>Example Page</title><meta name="description" content="About the page">`))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		BaseURL:       "http://short.url",
+		DefaultURLTTL: 24 * time.Hour,
+	}
+	mockStore := &mockRedisStore{urls: make(map[string]string)}
+	var failed atomic.Int64
+	fetcher := metadata.NewFetcher(metadata.WithTransport(server.Client().Transport))
+	service := NewURLShorteningService(cfg, mockStore, WithMetadataFetcher(fetcher, func(shortID string, err error) {
+		failed.Add(1)
+	}))
+
+	ok, err := service.ShortenURL(context.Background(), server.URL+"/page")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	missing, err := service.ShortenURL(context.Background(), server.URL+"/missing")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := service.Close(ctx); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	link, _ := service.GetLink(context.Background(), strings.TrimPrefix(ok, cfg.BaseURL+"/"))
+	if link.Metadata == nil || link.Metadata.Title != "Example Page" || link.Metadata.Description != "About the page" {
+		t.Errorf("Expected page metadata to be stored, got %+v", link.Metadata)
+	}
+	link, _ = service.GetLink(context.Background(), strings.TrimPrefix(missing, cfg.BaseURL+"/"))
+	if link.Metadata != nil {
+		t.Errorf("Expected no metadata for a missing page, got %+v", link.Metadata)
+	}
+	if failed.Load() != 1 {
+		t.Errorf("Expected 1 failed fetch, got %d", failed.Load())
+	}
+}
+
+// mockAuditRecorder collects recorded audit events
+type mockAuditRecorder struct {
+	mu     sync.Mutex
+	events []audit.Event
+	err    error
+}
+
+func (m *mockAuditRecorder) Record(ctx context.Context, event audit.Event) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.events = append(m.events, event)
+	return m.err
+}
+
+func TestURLShorteningService_Audit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<title>Example Page</title>`))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		BaseURL:       "http://short.url",
+		DefaultURLTTL: 24 * time.Hour,
+	}
+	recorder := &mockAuditRecorder{}
+	service := NewURLShorteningService(cfg, &mockRedisStore{urls: make(map[string]string)},
+		WithMetadataFetcher(metadata.NewFetcher(metadata.WithTransport(server.Client().Transport)), nil),
+		WithAuditLog(recorder, nil),
+	)
+	ctx := audit.WithActor(context.Background(), "jane")
+
+	shortURL, err := service.ShortenURL(ctx, server.URL+"/page", WithPassword("secret"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	shortID := strings.TrimPrefix(shortURL, cfg.BaseURL+"/")
+
+	closeCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := service.Close(closeCtx); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if err := service.DeleteURL(ctx, shortID); err != nil {
+		t.Fatalf("DeleteURL failed: %v", err)
+	}
+	if _, err := service.GetLink(ctx, shortID); err == nil {
+		t.Error("Expected the deleted link to be gone")
+	}
+
+	expected := []struct {
+		action string
+		actor  string
+		before bool
+		after  bool
+	}{
+		{action: audit.ActionCreate, actor: "jane", after: true},
+		{action: audit.ActionUpdate, actor: audit.ActorSystem, before: true, after: true},
+		{action: audit.ActionDelete, actor: "jane", before: true},
+	}
+	if len(recorder.events) != len(expected) {
+		t.Fatalf("Expected %d audit events, got %d", len(expected), len(recorder.events))
+	}
+	for i, want := range expected {
+		event := recorder.events[i]
+		if event.Action != want.action || event.Actor != want.actor || event.ShortID != shortID {
+			t.Errorf("Event %d: expected %s by %s, got %+v", i, want.action, want.actor, event)
+		}
+		if (event.Before != nil) != want.before || (event.After != nil) != want.after {
+			t.Errorf("Event %d: unexpected snapshots %+v / %+v", i, event.Before, event.After)
+		}
+	}
+
+	update := recorder.events[1]
+	if update.Before.Metadata != nil || update.After.Metadata == nil || update.After.Metadata.Title != "Example Page" {
+		t.Errorf("Expected the update to record the fetched metadata, got %+v / %+v", update.Before.Metadata, update.After.Metadata)
+	}
+	if update.After.PasswordHash == "" || strings.HasPrefix(update.After.PasswordHash, "$2") {
+		t.Errorf("Expected the password hash to be redacted, got %q", update.After.PasswordHash)
+	}
+
+	// Failed mutations are not audited
+	if err := service.DeleteURL(ctx, shortID); err == nil {
+		t.Error("Expected an error deleting a missing link")
+	}
+	if len(recorder.events) != len(expected) {
+		t.Errorf("Expected no audit event for a failed delete, got %d events", len(recorder.events))
+	}
+}
+
+func TestURLShorteningService_AuditError(t *testing.T) {
+	cfg := &config.Config{
+		BaseURL:       "http://short.url",
+		DefaultURLTTL: 24 * time.Hour,
+	}
+	var failed []audit.Event
+	service := NewURLShorteningService(cfg, &mockRedisStore{urls: make(map[string]string)},
+		WithAuditLog(&mockAuditRecorder{err: errors.New("stream unavailable")}, func(event audit.Event, err error) {
+			failed = append(failed, event)
+		}),
+	)
+
+	// The link is created even when the event is lost, the error is reported
+	if _, err := service.ShortenURL(context.Background(), "https://example.com"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(failed) != 1 || failed[0].Action != audit.ActionCreate {
+		t.Errorf("Expected the failed create event to be reported, got %+v", failed)
+	}
+}
+
+// takenStore reports every short ID as taken
+type takenStore struct {
+	*mockRedisStore
+}
+
+func (m *takenStore) GetOriginalURL(ctx context.Context, shortID string) (string, error) {
+	return "https://example.com", nil
+}
+
+func TestShortenURL_Metrics(t *testing.T) {
+	cfg := &config.Config{
+		BaseURL:       "http://short.url",
+		DefaultURLTTL: 24 * time.Hour,
+	}
+	m := metrics.New()
+	service := NewURLShorteningService(cfg, &takenStore{&mockRedisStore{urls: make(map[string]string)}}, WithMetrics(m))
+
+	if _, err := service.ShortenURL(context.Background(), "https://example.com"); err == nil {
+		t.Fatalf("Expected error when every short ID is taken")
+	}
+
+	body := scrape(t, m)
+	if !strings.Contains(body, "url_shortener_short_id_collisions_total 10") {
+		t.Errorf("Expected 10 collisions, got:\n%s", body)
+	}
+}
+
+// scrape returns the metrics output
+func scrape(t *testing.T, m *metrics.Metrics) string {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	return rr.Body.String()
+}
+
 // Performans test for URL shortening
 func BenchmarkShortenURL(b *testing.B) {
 	cfg := &config.Config{
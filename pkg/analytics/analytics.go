@@ -12,32 +12,139 @@ package analytics
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/redis/go-redis/v9"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type AnalyticsStoreInterface interface {
 	RecordURLAccess(ctx context.Context, shortID, ipAddress string) error
+	RecordAccessEvent(ctx context.Context, event AccessEvent) error
 	GetURLAnalytics(ctx context.Context, shortID string) (*URLAnalytics, error)
 }
 
+// AccessEvent describes a single access to a shortened URL
+type AccessEvent struct {
+	ShortID   string    // Short ID of the accessed URL
+	IPAddress string    // Client IP address
+	UserAgent string    // Client User-Agent header
+	Referrer  string    // Client Referer header
+	Country   string    // ISO country code of the client, if known
+	Owner     string    // Owner of the accessed URL
+	Tags      []string  // Tags of the accessed URL
+	Domain    string    // Domain of the original URL
+	Bot       bool      // Access was classified as a bot or crawler
+	Variant   string    // A/B variant served, empty for links without variants
+	Timestamp time.Time // Time of the access
+
+	spanContext trace.SpanContext // Span of the request that queued the event
+}
+
 // URLAnalytics stores analytics information for the URL
 type URLAnalytics struct {
-	TotalClicks   int64     `json:"total_clicks"`
+	TotalClicks   int64     `json:"total_clicks"` // Human clicks only
+	BotClicks     int64     `json:"bot_clicks"`
 	FirstAccessed time.Time `json:"first_accessed"`
 	LastAccessed  time.Time `json:"last_accessed"`
 	UniqueVisits  int64     `json:"unique_visits"`
+	// Human clicks per A/B variant served
+	VariantClicks map[string]int64 `json:"variant_clicks,omitempty"`
 }
 
 // AnalyticsStore manages analytics on Redis
 type AnalyticsStore struct {
-	client *redis.Client
+	client     func() redis.UniversalClient
+	stream     *StreamConfig
+	anonymizer IPAnonymizer
+	retention  time.Duration
+}
+
+// IPAnonymizer transforms client IPs before they are stored
+type IPAnonymizer interface {
+	Anonymize(ip string, at time.Time) string
+}
+
+// StoreOption configures optional AnalyticsStore behaviour
+type StoreOption func(*AnalyticsStore)
+
+// WithEventStream appends every access event to a Redis Stream
+func WithEventStream(cfg StreamConfig) StoreOption {
+	return func(a *AnalyticsStore) {
+		a.stream = &cfg
+	}
+}
+
+// WithIPAnonymizer anonymizes client IPs before any of them is stored
+func WithIPAnonymizer(anonymizer IPAnonymizer) StoreOption {
+	return func(a *AnalyticsStore) {
+		a.anonymizer = anonymizer
+	}
+}
+
+// WithClientSource reads the Redis client from source on every operation, so
+// a reconnecting supervisor can replace it
+func WithClientSource(source func() redis.UniversalClient) StoreOption {
+	return func(a *AnalyticsStore) {
+		a.client = source
+	}
+}
+
+// WithRetention expires analytics data that has not been updated for the given duration
+func WithRetention(retention time.Duration) StoreOption {
+	return func(a *AnalyticsStore) {
+		a.retention = retention
+	}
 }
 
 // New Analytics Store creates a new analytics store
-func NewAnalyticsStore(client *redis.Client) *AnalyticsStore {
-	return &AnalyticsStore{client: client}
+func NewAnalyticsStore(client redis.UniversalClient, options ...StoreOption) *AnalyticsStore {
+	store := &AnalyticsStore{
+		client: func() redis.UniversalClient { return client },
+	}
+	for _, opt := range options {
+		opt(store)
+	}
+	return store
+}
+
+// redis returns the current Redis client
+func (a *AnalyticsStore) redis() redis.UniversalClient {
+	return a.client()
+}
+
+// analyticsKey builds the Redis key of an analytics field for a URL. The hash
+// tag keeps all keys of a URL in one cluster slot.
+func analyticsKey(shortID, field string) string {
+	return fmt.Sprintf("analytics:{%s}:%s", shortID, field)
+}
+
+func totalClicksKey(shortID string) string   { return analyticsKey(shortID, "total_clicks") }
+func uniqueVisitsKey(shortID string) string  { return analyticsKey(shortID, "unique_visits") }
+func lastAccessedKey(shortID string) string  { return analyticsKey(shortID, "last_accessed") }
+func firstAccessedKey(shortID string) string { return analyticsKey(shortID, "first_accessed") }
+func uniqueIPKey(shortID string) string      { return analyticsKey(shortID, "unique_ips") }
+func hourlyClicksKey(shortID string) string  { return analyticsKey(shortID, "hourly_clicks") }
+func botClicksKey(shortID string) string     { return analyticsKey(shortID, "bot_clicks") }
+func variantClicksKey(shortID string) string { return analyticsKey(shortID, "variant_clicks") }
+func eventsKey(shortID string) string        { return analyticsKey(shortID, "events") }
+
+// urlKeys returns all analytics keys of a URL
+func urlKeys(shortID string) []string {
+	return []string{
+		totalClicksKey(shortID),
+		uniqueVisitsKey(shortID),
+		lastAccessedKey(shortID),
+		firstAccessedKey(shortID),
+		uniqueIPKey(shortID),
+		hourlyClicksKey(shortID),
+		botClicksKey(shortID),
+		variantClicksKey(shortID),
+		eventsKey(shortID),
+	}
 }
 
 // RecordURLAccess records a URL access
@@ -46,34 +153,99 @@ func (a *AnalyticsStore) RecordURLAccess(
 	shortID,
 	ipAddress string,
 ) error {
-	// Analytics keys
-	totalClicksKey := fmt.Sprintf("analytics:%s:total_clicks", shortID)
-	uniqueVisitsKey := fmt.Sprintf("analytics:%s:unique_visits", shortID)
-	lastAccessedKey := fmt.Sprintf("analytics:%s:last_accessed", shortID)
-	firstAccessedKey := fmt.Sprintf("analytics:%s:first_accessed", shortID)
-	uniqueIPKey := fmt.Sprintf("analytics:%s:unique_ips", shortID)
+	return a.RecordAccessEvent(ctx, AccessEvent{
+		ShortID:   shortID,
+		IPAddress: ipAddress,
+		Timestamp: time.Now(),
+	})
+}
+
+// RecordAccessEvent records a single access event
+func (a *AnalyticsStore) RecordAccessEvent(ctx context.Context, event AccessEvent) error {
+	return a.RecordBatch(ctx, []AccessEvent{event})
+}
+
+// RecordBatch records multiple access events in a single Redis pipeline
+func (a *AnalyticsStore) RecordBatch(ctx context.Context, events []AccessEvent) (err error) {
+	if len(events) == 0 {
+		return nil
+	}
+
+	var links []trace.Link
+	for _, event := range events {
+		if event.spanContext.IsValid() {
+			links = append(links, trace.Link{SpanContext: event.spanContext})
+		}
+	}
+	ctx, span := tracing.StartLinked(ctx, "AnalyticsStore.RecordBatch", links, attribute.Int("events", len(events)))
+	defer func() { tracing.End(span, err) }()
 
 	// Pipeline
-	pipe := a.client.Pipeline()
+	pipe := a.redis().Pipeline()
+
+	for _, event := range events {
+		if event.Timestamp.IsZero() {
+			event.Timestamp = time.Now()
+		}
+		accessedAt := event.Timestamp.Format(time.RFC3339)
+
+		// Raw client IPs never reach Redis when an anonymizer is set
+		if a.anonymizer != nil {
+			event.IPAddress = a.anonymizer.Anonymize(event.IPAddress, event.Timestamp)
+		}
+
+		// Bots are only counted, they do not affect human click analytics
+		if event.Bot {
+			pipe.Incr(ctx, botClicksKey(event.ShortID))
+			if a.stream != nil {
+				a.appendToStream(ctx, pipe, event)
+			}
+			a.expireURLKeys(ctx, pipe, event.ShortID)
+			continue
+		}
+
+		// Increase total clicks
+		pipe.Incr(ctx, totalClicksKey(event.ShortID))
+
+		// Record first access time (does not change if already exists)
+		pipe.SetNX(ctx, firstAccessedKey(event.ShortID), accessedAt, 0)
+
+		// Update last access time
+		pipe.Set(ctx, lastAccessedKey(event.ShortID), accessedAt, 0)
+
+		// Unique IP control
+		pipe.SAdd(ctx, uniqueIPKey(event.ShortID), event.IPAddress)
+
+		// Clicks per hour for time series exports
+		pipe.HIncrBy(ctx, hourlyClicksKey(event.ShortID), bucketField(event.Timestamp), 1)
 
-	// Increase total clicks
-	pipe.Incr(ctx, totalClicksKey)
+		// Clicks per A/B variant
+		if event.Variant != "" {
+			pipe.HIncrBy(ctx, variantClicksKey(event.ShortID), event.Variant, 1)
+		}
 
-	// Record first access time (does not change if already exists)
-	pipe.SetNX(ctx, firstAccessedKey, time.Now().Format(time.RFC3339), 0)
+		// Daily leaderboards
+		for _, board := range boardsForEvent(event) {
+			key := leaderboardKey(board, event.Timestamp)
+			pipe.ZIncrBy(ctx, key, 1, event.ShortID)
+			pipe.Expire(ctx, key, a.leaderboardTTL())
+		}
 
-	// Update last access time
-	pipe.Set(ctx, lastAccessedKey, time.Now().Format(time.RFC3339), 0)
+		a.expireURLKeys(ctx, pipe, event.ShortID)
 
-	//Unique IP control
-	uniqueVisit, err := pipe.SAdd(ctx, uniqueIPKey, ipAddress).Result()
-	if err != nil {
-		return err
+		// Raw event export
+		if a.stream != nil {
+			a.appendToStream(ctx, pipe, event)
+		}
 	}
 
-	// Increase unique visits
-	if uniqueVisit > 0 {
-		pipe.Incr(ctx, uniqueVisitsKey)
+	// Drop streamed events older than the retention window
+	if a.stream != nil && a.retention > 0 {
+		minID := strconv.FormatInt(time.Now().Add(-a.retention).UnixMilli(), 10)
+		pipe.XTrimMinIDApprox(ctx, a.streamName(), minID, 0)
+		for shortID := range shortIDs(events) {
+			pipe.XTrimMinIDApprox(ctx, eventsKey(shortID), minID, 0)
+		}
 	}
 
 	// Run pipeline
@@ -81,57 +253,104 @@ func (a *AnalyticsStore) RecordURLAccess(
 	return err
 }
 
+// shortIDs returns the distinct short IDs of the events
+func shortIDs(events []AccessEvent) map[string]struct{} {
+	ids := make(map[string]struct{}, len(events))
+	for _, event := range events {
+		ids[event.ShortID] = struct{}{}
+	}
+	return ids
+}
+
+// expireURLKeys applies the sliding retention window to the analytics keys of a URL
+func (a *AnalyticsStore) expireURLKeys(ctx context.Context, pipe redis.Pipeliner, shortID string) {
+	if a.retention <= 0 {
+		return
+	}
+	for _, key := range urlKeys(shortID) {
+		pipe.Expire(ctx, key, a.retention)
+	}
+}
+
+// leaderboardTTL returns how long daily leaderboards are kept
+func (a *AnalyticsStore) leaderboardTTL() time.Duration {
+	if a.retention > 0 && a.retention < leaderboardRetention {
+		return a.retention
+	}
+	return leaderboardRetention
+}
+
 // GetURLAnalytics retrieves analytics for a URL
 func (a *AnalyticsStore) GetURLAnalytics(
 	ctx context.Context,
 	shortID string,
-) (*URLAnalytics, error) {
-	// Analytics keys
-	totalClicksKey := fmt.Sprintf("analytics:%s:total_clicks", shortID)
-	uniqueVisitsKey := fmt.Sprintf("analytics:%s:unique_visits", shortID)
-	lastAccessedKey := fmt.Sprintf("analytics:%s:last_accessed", shortID)
-	firstAccessedKey := fmt.Sprintf("analytics:%s:first_accessed", shortID)
-	uniqueIPKey := fmt.Sprintf("analytics:%s:unique_ips", shortID)
-
-	//Collecting data with Pipeline
-	pipe := a.client.Pipeline()
-	totalClicksCmd := pipe.Get(ctx, totalClicksKey)
-	uniqueVisitsCmd := pipe.Get(ctx, uniqueVisitsKey)
-	lastAccessedCmd := pipe.Get(ctx, lastAccessedKey)
-	firstAccessedCmd := pipe.Get(ctx, firstAccessedKey)
-	uniqueIPsCmd := pipe.SCard(ctx, uniqueIPKey)
-
-	_, err := pipe.Exec(ctx)
+) (_ *URLAnalytics, err error) {
+	ctx, span := tracing.Start(ctx, "AnalyticsStore.GetURLAnalytics", attribute.String("short_id", shortID))
+	defer func() { tracing.End(span, err) }()
+
+	pipe := a.redis().Pipeline()
+	result := queueURLAnalytics(ctx, pipe, shortID)
+
+	_, err = pipe.Exec(ctx)
 	if err != nil && err != redis.Nil {
 		return nil, err
 	}
 
-	// Create URLAnalytics struct
-	analytics := &URLAnalytics{}
+	return result(), nil
+}
 
-	// Total clicks
-	if totalClicks, err := totalClicksCmd.Int64(); err == nil {
-		analytics.TotalClicks = totalClicks
-	}
+// queueURLAnalytics adds the reads of a URL's analytics to the pipeline. The
+// returned function builds the URLAnalytics once the pipeline has run.
+func queueURLAnalytics(ctx context.Context, pipe redis.Pipeliner, shortID string) func() *URLAnalytics {
+	totalClicksCmd := pipe.Get(ctx, totalClicksKey(shortID))
+	botClicksCmd := pipe.Get(ctx, botClicksKey(shortID))
+	uniqueVisitsCmd := pipe.Get(ctx, uniqueVisitsKey(shortID))
+	lastAccessedCmd := pipe.Get(ctx, lastAccessedKey(shortID))
+	firstAccessedCmd := pipe.Get(ctx, firstAccessedKey(shortID))
+	uniqueIPsCmd := pipe.SCard(ctx, uniqueIPKey(shortID))
+	variantClicksCmd := pipe.HGetAll(ctx, variantClicksKey(shortID))
 
-	if uniqueIPCount, err := uniqueIPsCmd.Result(); err == nil {
-		analytics.UniqueVisits = uniqueIPCount
-	}
+	return func() *URLAnalytics {
+		// Create URLAnalytics struct
+		analytics := &URLAnalytics{}
 
-	// Number of unique visits
-	if uniqueVisits, err := uniqueVisitsCmd.Int64(); err == nil {
-		analytics.UniqueVisits = uniqueVisits
-	}
+		// Total clicks
+		if totalClicks, err := totalClicksCmd.Int64(); err == nil {
+			analytics.TotalClicks = totalClicks
+		}
 
-	// Last access time
-	if lastAccessed, err := lastAccessedCmd.Result(); err == nil {
-		analytics.LastAccessed, _ = time.Parse(time.RFC3339, lastAccessed)
-	}
+		// Bot clicks
+		if botClicks, err := botClicksCmd.Int64(); err == nil {
+			analytics.BotClicks = botClicks
+		}
 
-	// First access time
-	if firstAccessed, err := firstAccessedCmd.Result(); err == nil {
-		analytics.FirstAccessed, _ = time.Parse(time.RFC3339, firstAccessed)
-	}
+		if uniqueIPCount, err := uniqueIPsCmd.Result(); err == nil {
+			analytics.UniqueVisits = uniqueIPCount
+		}
+
+		// Number of unique visits
+		if uniqueVisits, err := uniqueVisitsCmd.Int64(); err == nil {
+			analytics.UniqueVisits = uniqueVisits
+		}
 
-	return analytics, nil
+		// Last access time
+		if lastAccessed, err := lastAccessedCmd.Result(); err == nil {
+			analytics.LastAccessed, _ = time.Parse(time.RFC3339, lastAccessed)
+		}
+
+		// First access time
+		if firstAccessed, err := firstAccessedCmd.Result(); err == nil {
+			analytics.FirstAccessed, _ = time.Parse(time.RFC3339, firstAccessed)
+		}
+
+		// Clicks per variant
+		if variants, err := variantClicksCmd.Result(); err == nil && len(variants) > 0 {
+			analytics.VariantClicks = make(map[string]int64, len(variants))
+			for variant, value := range variants {
+				analytics.VariantClicks[variant], _ = strconv.ParseInt(value, 10, 64)
+			}
+		}
+
+		return analytics
+	}
 }
@@ -11,7 +11,9 @@ package analytics
 
 import (
 	"context"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/alicebob/miniredis/v2"
 	"github.com/redis/go-redis/v9"
@@ -108,6 +110,103 @@ func TestRecordURLAccess(t *testing.T) {
 	}
 }
 
+// TestRecordBotAccess tests that bot hits are counted separately from human clicks
+func TestRecordBotAccess(t *testing.T) {
+	mr, client := setupMockRedis()
+	defer mr.Close()
+	defer client.Close()
+
+	store := NewAnalyticsStore(client)
+	ctx := context.Background()
+
+	events := []AccessEvent{
+		{ShortID: "bot-url", IPAddress: "10.0.0.1"},
+		{ShortID: "bot-url", IPAddress: "10.0.0.2", Bot: true},
+		{ShortID: "bot-url", IPAddress: "10.0.0.3", Bot: true},
+	}
+	for _, event := range events {
+		if err := store.RecordAccessEvent(ctx, event); err != nil {
+			t.Fatalf("RecordAccessEvent failed: %v", err)
+		}
+	}
+
+	analytics, err := store.GetURLAnalytics(ctx, "bot-url")
+	if err != nil {
+		t.Fatalf("GetURLAnalytics failed: %v", err)
+	}
+	if analytics.TotalClicks != 1 {
+		t.Errorf("Expected 1 human click, got %d", analytics.TotalClicks)
+	}
+	if analytics.BotClicks != 2 {
+		t.Errorf("Expected 2 bot clicks, got %d", analytics.BotClicks)
+	}
+	if analytics.UniqueVisits != 1 {
+		t.Errorf("Expected bots to be excluded from unique visits, got %d", analytics.UniqueVisits)
+	}
+
+	// Bots do not appear on leaderboards
+	top, err := store.TopLinks(ctx, Board{Scope: ScopeGlobal}, 1, 10)
+	if err != nil {
+		t.Fatalf("TopLinks failed: %v", err)
+	}
+	if len(top) != 1 || top[0].Clicks != 1 {
+		t.Errorf("Expected 1 leaderboard click, got %+v", top)
+	}
+}
+
+// TestRecordVariantAccess tests that clicks are counted per A/B variant
+func TestRecordVariantAccess(t *testing.T) {
+	mr, client := setupMockRedis()
+	defer mr.Close()
+	defer client.Close()
+
+	store := NewAnalyticsStore(client, WithEventStream(StreamConfig{}))
+	ctx := context.Background()
+
+	events := []AccessEvent{
+		{ShortID: "split-url", IPAddress: "10.0.0.1", Variant: "a"},
+		{ShortID: "split-url", IPAddress: "10.0.0.2", Variant: "a"},
+		{ShortID: "split-url", IPAddress: "10.0.0.3", Variant: "b"},
+		{ShortID: "split-url", IPAddress: "10.0.0.4", Variant: "b", Bot: true},
+	}
+	if err := store.RecordBatch(ctx, events); err != nil {
+		t.Fatalf("RecordBatch failed: %v", err)
+	}
+
+	analytics, err := store.GetURLAnalytics(ctx, "split-url")
+	if err != nil {
+		t.Fatalf("GetURLAnalytics failed: %v", err)
+	}
+	if analytics.VariantClicks["a"] != 2 || analytics.VariantClicks["b"] != 1 {
+		t.Errorf("Expected 2 clicks for a and 1 for b, got %v", analytics.VariantClicks)
+	}
+
+	// The served variant is kept with the raw events
+	var variants []string
+	err = store.ExportEvents(ctx, "split-url", time.Now().Add(-time.Minute), time.Now().Add(time.Minute), func(event StreamEvent) error {
+		variants = append(variants, event.Variant)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ExportEvents failed: %v", err)
+	}
+	if strings.Join(variants, ",") != "a,a,b,b" {
+		t.Errorf("Expected streamed variants a,a,b,b, got %v", variants)
+	}
+
+	// Links without variants report none
+	if err := store.RecordAccessEvent(ctx, AccessEvent{ShortID: "plain-url", IPAddress: "10.0.0.1"}); err != nil {
+		t.Fatalf("RecordAccessEvent failed: %v", err)
+	}
+	plain, err := store.GetURLAnalytics(ctx, "plain-url")
+	if err != nil {
+		t.Fatalf("GetURLAnalytics failed: %v", err)
+	}
+	if plain.VariantClicks != nil {
+		t.Errorf("Expected no variant clicks, got %v", plain.VariantClicks)
+	}
+}
+
 // uniqueIPs returns unique IP addresses
 func uniqueIPs(ips []string) []string {
 	unique := make(map[string]bool)
@@ -141,3 +240,36 @@ func BenchmarkAnalyticsStore(b *testing.B) {
 		}
 	}
 }
+
+// hashTag returns the part of a key Redis Cluster hashes to pick its slot
+func hashTag(key string) string {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			return key[start+1 : start+1+end]
+		}
+	}
+	return key
+}
+
+// TestKeysShareSlot tests that keys used together map to one cluster slot
+func TestKeysShareSlot(t *testing.T) {
+	for _, key := range urlKeys("abc123") {
+		if hashTag(key) != "abc123" {
+			t.Errorf("Expected %s to be tagged with the short ID", key)
+		}
+	}
+	if hashTag(analyticsKey("abc123", "x")) == hashTag(analyticsKey("def456", "x")) {
+		t.Error("Expected different URLs to use different tags")
+	}
+
+	boards := []Board{{Scope: ScopeGlobal}, {Scope: ScopeOwner, Value: "alice"}, {Scope: ScopeTag, Value: "launch"}}
+	for _, board := range boards {
+		now := time.Now()
+		tag := hashTag(leaderboardKey(board, now))
+		for i := 1; i < MaxLeaderboardDays; i++ {
+			if key := leaderboardKey(board, now.AddDate(0, 0, -i)); hashTag(key) != tag {
+				t.Errorf("Expected %s to share the tag %q", key, tag)
+			}
+		}
+	}
+}
@@ -0,0 +1,167 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package analytics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ErrStreamDisabled occurs when events are exported without an event stream
+var ErrStreamDisabled = errors.New("event stream is not enabled")
+
+const (
+	// bucketLayout is the hash field format of hourly click buckets
+	bucketLayout = "2006-01-02T15"
+
+	// exportBucketChunk is the number of hourly buckets fetched per round trip
+	exportBucketChunk = 168
+
+	// exportEventPage is the number of stream entries fetched per round trip
+	exportEventPage = 500
+)
+
+// Exporter streams analytics rows for a URL without loading them all in memory
+type Exporter interface {
+	ExportBuckets(ctx context.Context, shortID string, from, to time.Time, fn func(ClickBucket) error) error
+	ExportEvents(ctx context.Context, shortID string, from, to time.Time, fn func(StreamEvent) error) error
+}
+
+// ClickBucket is the number of clicks of a URL within one hour
+type ClickBucket struct {
+	Start  time.Time `json:"start"`
+	Clicks int64     `json:"clicks"`
+}
+
+// bucketField returns the hourly bucket hash field for a timestamp
+func bucketField(t time.Time) string {
+	return t.UTC().Format(bucketLayout)
+}
+
+// ExportBuckets calls fn for every non-empty hourly bucket between from and to
+func (a *AnalyticsStore) ExportBuckets(
+	ctx context.Context,
+	shortID string,
+	from, to time.Time,
+	fn func(ClickBucket) error,
+) (err error) {
+	ctx, span := tracing.Start(ctx, "AnalyticsStore.ExportBuckets", attribute.String("short_id", shortID))
+	defer func() { tracing.End(span, err) }()
+
+	key := hourlyClicksKey(shortID)
+	start := from.UTC().Truncate(time.Hour)
+	end := to.UTC()
+
+	for start.Before(end) {
+		// Collect the next chunk of hours
+		var hours []time.Time
+		var fields []string
+		for hour := start; hour.Before(end) && len(hours) < exportBucketChunk; hour = hour.Add(time.Hour) {
+			hours = append(hours, hour)
+			fields = append(fields, bucketField(hour))
+		}
+		start = hours[len(hours)-1].Add(time.Hour)
+
+		values, err := a.redis().HMGet(ctx, key, fields...).Result()
+		if err != nil {
+			return fmt.Errorf("failed to read click buckets: %v", err)
+		}
+
+		for i, value := range values {
+			raw, ok := value.(string)
+			if !ok {
+				continue
+			}
+			clicks, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil || clicks == 0 {
+				continue
+			}
+			if err := fn(ClickBucket{Start: hours[i], Clicks: clicks}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// ExportEvents calls fn for every streamed access event of the URL from
+// from up to, but not including, to. Events are read from the URL's own
+// stream, bounded by the stream IDs of the time window. URLs without one,
+// whose events were all recorded before per-URL streams existed, fall back
+// to scanning the window of the shared stream.
+func (a *AnalyticsStore) ExportEvents(
+	ctx context.Context,
+	shortID string,
+	from, to time.Time,
+	fn func(StreamEvent) error,
+) (err error) {
+	if a.stream == nil {
+		return ErrStreamDisabled
+	}
+	ctx, span := tracing.Start(ctx, "AnalyticsStore.ExportEvents", attribute.String("short_id", shortID))
+	defer func() { tracing.End(span, err) }()
+
+	name := eventsKey(shortID)
+	exists, err := a.redis().Exists(ctx, name).Result()
+	if err != nil {
+		return fmt.Errorf("failed to read event stream: %v", err)
+	}
+	if exists == 0 {
+		name = a.streamName()
+	}
+
+	// Stream IDs start with the millisecond timestamp of the entry
+	start := strconv.FormatInt(from.UnixMilli(), 10)
+	end := strconv.FormatInt(to.UnixMilli()-1, 10)
+
+	for {
+		messages, err := a.redis().XRangeN(ctx, name, start, end, exportEventPage).Result()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return fmt.Errorf("failed to read event stream: %v", err)
+		}
+
+		for _, msg := range messages {
+			event := parseStreamEvent(msg)
+			if event.ShortID != shortID {
+				continue
+			}
+			if err := fn(event); err != nil {
+				return err
+			}
+		}
+
+		if len(messages) < exportEventPage {
+			return nil
+		}
+		start = nextStreamID(messages[len(messages)-1].ID)
+	}
+}
+
+// nextStreamID returns the smallest stream ID greater than id
+func nextStreamID(id string) string {
+	ms, seq, found := strings.Cut(id, "-")
+	if !found {
+		return id
+	}
+	n, err := strconv.ParseUint(seq, 10, 64)
+	if err != nil {
+		return id
+	}
+	return fmt.Sprintf("%s-%d", ms, n+1)
+}
@@ -0,0 +1,140 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package analytics
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// TestExportBuckets tests hourly bucket export within a time range
+func TestExportBuckets(t *testing.T) {
+	mr, client := setupMockRedis()
+	defer mr.Close()
+	defer client.Close()
+
+	store := NewAnalyticsStore(client)
+	ctx := context.Background()
+
+	base := time.Date(2025, 3, 1, 10, 15, 0, 0, time.UTC)
+	accesses := []time.Time{
+		base,
+		base.Add(10 * time.Minute),
+		base.Add(2 * time.Hour),
+		base.Add(48 * time.Hour), // outside of the exported range
+	}
+	for _, accessedAt := range accesses {
+		err := store.RecordAccessEvent(ctx, AccessEvent{
+			ShortID:   "export-url",
+			IPAddress: "10.0.0.1",
+			Timestamp: accessedAt,
+		})
+		if err != nil {
+			t.Fatalf("RecordAccessEvent failed: %v", err)
+		}
+	}
+
+	var buckets []ClickBucket
+	err := store.ExportBuckets(ctx, "export-url", base, base.Add(24*time.Hour), func(bucket ClickBucket) error {
+		buckets = append(buckets, bucket)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ExportBuckets failed: %v", err)
+	}
+
+	if len(buckets) != 2 {
+		t.Fatalf("Expected 2 buckets, got %d: %+v", len(buckets), buckets)
+	}
+	if !buckets[0].Start.Equal(base.Truncate(time.Hour)) || buckets[0].Clicks != 2 {
+		t.Errorf("Unexpected first bucket: %+v", buckets[0])
+	}
+	if buckets[1].Clicks != 1 {
+		t.Errorf("Unexpected second bucket: %+v", buckets[1])
+	}
+}
+
+// TestExportEvents tests paging through the event stream filtered by short ID
+func TestExportEvents(t *testing.T) {
+	mr, client := setupMockRedis()
+	defer mr.Close()
+	defer client.Close()
+
+	ctx := context.Background()
+
+	// Without a stream events cannot be exported
+	plain := NewAnalyticsStore(client)
+	err := plain.ExportEvents(ctx, "url", time.Unix(0, 0), time.Now(), func(StreamEvent) error { return nil })
+	if !errors.Is(err, ErrStreamDisabled) {
+		t.Fatalf("Expected ErrStreamDisabled, got %v", err)
+	}
+
+	store := NewAnalyticsStore(client, WithEventStream(StreamConfig{Name: "test:events"}))
+
+	// More events than a single page
+	from := time.Now().Add(-time.Minute)
+	for i := 0; i < exportEventPage+10; i++ {
+		shortID := "export-url"
+		if i%2 == 1 {
+			shortID = "other-url"
+		}
+		if err := store.RecordURLAccess(ctx, shortID, "10.0.0.1"); err != nil {
+			t.Fatalf("RecordURLAccess failed: %v", err)
+		}
+	}
+
+	count := 0
+	err = store.ExportEvents(ctx, "export-url", from, time.Now().Add(time.Minute), func(event StreamEvent) error {
+		if event.ShortID != "export-url" {
+			t.Errorf("Unexpected event for %s", event.ShortID)
+		}
+		count++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ExportEvents failed: %v", err)
+	}
+	if count != (exportEventPage+10)/2 {
+		t.Errorf("Expected %d events, got %d", (exportEventPage+10)/2, count)
+	}
+
+	// Every URL keeps its own copy of its events
+	if n := client.XLen(ctx, eventsKey("export-url")).Val(); n != int64(count) {
+		t.Errorf("Expected %d events in the URL stream, got %d", count, n)
+	}
+
+	// Events outside the window are skipped
+	count = 0
+	err = store.ExportEvents(ctx, "export-url", from.Add(-time.Hour), from, func(StreamEvent) error {
+		count++
+		return nil
+	})
+	if err != nil || count != 0 {
+		t.Errorf("Expected no events before the window, got %d (%v)", count, err)
+	}
+
+	// URLs without their own stream are read from the shared stream
+	client.XAdd(ctx, &redis.XAddArgs{
+		Stream: "test:events",
+		Values: map[string]interface{}{"short_id": "legacy-url", "timestamp": time.Now().UTC().Format(time.RFC3339Nano)},
+	})
+	count = 0
+	err = store.ExportEvents(ctx, "legacy-url", from, time.Now().Add(time.Minute), func(StreamEvent) error {
+		count++
+		return nil
+	})
+	if err != nil || count != 1 {
+		t.Errorf("Expected 1 legacy event, got %d (%v)", count, err)
+	}
+}
@@ -0,0 +1,158 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package analytics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Leaderboard scopes
+const (
+	ScopeGlobal = "global"
+	ScopeOwner  = "owner"
+	ScopeTag    = "tag"
+	ScopeDomain = "domain"
+)
+
+const (
+	// leaderboardRetention is how long daily leaderboards are kept
+	leaderboardRetention = 90 * 24 * time.Hour
+
+	// MaxLeaderboardDays is the longest window a leaderboard query may cover
+	MaxLeaderboardDays = 90
+
+	// leaderboardDayLayout is the date suffix of daily leaderboard keys
+	leaderboardDayLayout = "2006-01-02"
+)
+
+// Leaderboard ranks URLs by clicks within a time window
+type Leaderboard interface {
+	TopLinks(ctx context.Context, board Board, days int, limit int) ([]TopLink, error)
+}
+
+// Board identifies a leaderboard, e.g. all links of one owner
+type Board struct {
+	Scope string // One of the Scope constants
+	Value string // Owner, tag or domain; empty for the global board
+}
+
+// TopLink is a leaderboard entry
+type TopLink struct {
+	ShortID   string        `json:"short_id"`
+	Clicks    int64         `json:"clicks"` // Clicks within the requested window
+	Analytics *URLAnalytics `json:"analytics"`
+}
+
+// leaderboardKey returns the daily sorted set key of a board
+func leaderboardKey(board Board, day time.Time) string {
+	return fmt.Sprintf("analytics:top:%s:%s", boardTag(board), day.UTC().Format(leaderboardDayLayout))
+}
+
+// boardTag returns the hash tag of a board, all daily keys of a board share
+// a cluster slot so they can be summed with ZUNIONSTORE. The value is escaped
+// so separators and braces in it cannot collide with another board's key.
+func boardTag(board Board) string {
+	if board.Scope == ScopeGlobal {
+		return "{" + ScopeGlobal + "}"
+	}
+	return "{" + board.Scope + ":" + url.QueryEscape(board.Value) + "}"
+}
+
+// boardsForEvent returns all boards an access event counts towards
+func boardsForEvent(event AccessEvent) []Board {
+	boards := []Board{{Scope: ScopeGlobal}}
+	if event.Owner != "" {
+		boards = append(boards, Board{Scope: ScopeOwner, Value: event.Owner})
+	}
+	for _, tag := range event.Tags {
+		boards = append(boards, Board{Scope: ScopeTag, Value: tag})
+	}
+	if event.Domain != "" {
+		boards = append(boards, Board{Scope: ScopeDomain, Value: event.Domain})
+	}
+	return boards
+}
+
+// TopLinks returns the most clicked URLs of a board over the last days (today included)
+func (a *AnalyticsStore) TopLinks(ctx context.Context, board Board, days int, limit int) (_ []TopLink, err error) {
+	ctx, span := tracing.Start(ctx, "AnalyticsStore.TopLinks", attribute.String("scope", board.Scope))
+	defer func() { tracing.End(span, err) }()
+
+	if days < 1 {
+		days = 1
+	}
+	if days > MaxLeaderboardDays {
+		days = MaxLeaderboardDays
+	}
+
+	now := time.Now()
+	keys := make([]string, 0, days)
+	for i := 0; i < days; i++ {
+		keys = append(keys, leaderboardKey(board, now.AddDate(0, 0, -i)))
+	}
+
+	var scores []redis.Z
+	if len(keys) == 1 {
+		scores, err = a.redis().ZRevRangeWithScores(ctx, keys[0], 0, int64(limit-1)).Result()
+	} else {
+		scores, err = a.unionTop(ctx, board, keys, limit)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read leaderboard: %v", err)
+	}
+
+	// Analytics of all entries are read in a single round trip
+	pipe := a.redis().Pipeline()
+	results := make([]func() *URLAnalytics, 0, len(scores))
+	for _, score := range scores {
+		shortID, _ := score.Member.(string)
+		results = append(results, queueURLAnalytics(ctx, pipe, shortID))
+	}
+	if len(scores) > 0 {
+		if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+			return nil, fmt.Errorf("failed to read analytics: %v", err)
+		}
+	}
+
+	top := make([]TopLink, 0, len(scores))
+	for i, score := range scores {
+		shortID, _ := score.Member.(string)
+		top = append(top, TopLink{
+			ShortID:   shortID,
+			Clicks:    int64(score.Score),
+			Analytics: results[i](),
+		})
+	}
+	return top, nil
+}
+
+// unionTop sums daily leaderboards into a temporary key and ranks it
+func (a *AnalyticsStore) unionTop(ctx context.Context, board Board, keys []string, limit int) ([]redis.Z, error) {
+	tmpKey := fmt.Sprintf("analytics:top:%s:tmp:%s", boardTag(board), uuid.NewString())
+
+	pipe := a.redis().TxPipeline()
+	pipe.ZUnionStore(ctx, tmpKey, &redis.ZStore{Keys: keys})
+	rangeCmd := pipe.ZRevRangeWithScores(ctx, tmpKey, 0, int64(limit-1))
+	pipe.Del(ctx, tmpKey)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+	return rangeCmd.Result()
+}
@@ -0,0 +1,148 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package analytics
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestTopLinks tests global, owner, tag and domain leaderboards over a window
+func TestTopLinks(t *testing.T) {
+	mr, client := setupMockRedis()
+	defer mr.Close()
+	defer client.Close()
+
+	store := NewAnalyticsStore(client)
+	ctx := context.Background()
+
+	now := time.Now()
+	yesterday := now.AddDate(0, 0, -1)
+
+	record := func(shortID, owner, tag string, clicks int, at time.Time) {
+		for i := 0; i < clicks; i++ {
+			err := store.RecordAccessEvent(ctx, AccessEvent{
+				ShortID:   shortID,
+				IPAddress: "10.0.0.1",
+				Owner:     owner,
+				Tags:      []string{tag},
+				Domain:    "example.com",
+				Timestamp: at,
+			})
+			if err != nil {
+				t.Fatalf("RecordAccessEvent failed: %v", err)
+			}
+		}
+	}
+
+	record("link-a", "alice", "launch", 3, now)
+	record("link-b", "bob", "launch", 1, now)
+	record("link-b", "bob", "launch", 5, yesterday)
+	record("link-c", "alice", "blog", 2, now)
+
+	testCases := []struct {
+		name     string
+		board    Board
+		days     int
+		expected []string
+		clicks   []int64
+	}{
+		{
+			name:     "Global Today",
+			board:    Board{Scope: ScopeGlobal},
+			days:     1,
+			expected: []string{"link-a", "link-c", "link-b"},
+			clicks:   []int64{3, 2, 1},
+		},
+		{
+			name:     "Global Two Days",
+			board:    Board{Scope: ScopeGlobal},
+			days:     2,
+			expected: []string{"link-b", "link-a", "link-c"},
+			clicks:   []int64{6, 3, 2},
+		},
+		{
+			name:     "Owner",
+			board:    Board{Scope: ScopeOwner, Value: "alice"},
+			days:     1,
+			expected: []string{"link-a", "link-c"},
+			clicks:   []int64{3, 2},
+		},
+		{
+			name:     "Tag",
+			board:    Board{Scope: ScopeTag, Value: "launch"},
+			days:     2,
+			expected: []string{"link-b", "link-a"},
+			clicks:   []int64{6, 3},
+		},
+		{
+			name:     "Domain With Limit",
+			board:    Board{Scope: ScopeDomain, Value: "example.com"},
+			days:     1,
+			expected: []string{"link-a"},
+			clicks:   []int64{3},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			limit := 10
+			if tc.board.Scope == ScopeDomain {
+				limit = 1
+			}
+
+			top, err := store.TopLinks(ctx, tc.board, tc.days, limit)
+			if err != nil {
+				t.Fatalf("TopLinks failed: %v", err)
+			}
+
+			if len(top) != len(tc.expected) {
+				t.Fatalf("Expected %d links, got %d: %+v", len(tc.expected), len(top), top)
+			}
+			for i, entry := range top {
+				if entry.ShortID != tc.expected[i] || entry.Clicks != tc.clicks[i] {
+					t.Errorf("Position %d: expected %s with %d clicks, got %s with %d",
+						i, tc.expected[i], tc.clicks[i], entry.ShortID, entry.Clicks)
+				}
+				if entry.Analytics == nil || entry.Analytics.TotalClicks == 0 {
+					t.Errorf("Expected analytics for %s", entry.ShortID)
+				}
+			}
+		})
+	}
+
+	// Temporary union keys are cleaned up
+	if keys, _ := client.Keys(ctx, "analytics:top:*:tmp:*").Result(); len(keys) != 0 {
+		t.Errorf("Expected no temporary keys, got %v", keys)
+	}
+}
+
+// TestBoardTag tests that board values cannot break out of their key
+func TestBoardTag(t *testing.T) {
+	testCases := []struct {
+		name     string
+		board    Board
+		expected string
+	}{
+		{name: "Global", board: Board{Scope: ScopeGlobal}, expected: "{global}"},
+		{name: "Plain Value", board: Board{Scope: ScopeTag, Value: "launch"}, expected: "{tag:launch}"},
+		{name: "Separator", board: Board{Scope: ScopeOwner, Value: "tag:launch"}, expected: "{owner:tag%3Alaunch}"},
+		{name: "Braces", board: Board{Scope: ScopeDomain, Value: "a}b{c"}, expected: "{domain:a%7Db%7Bc}"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := boardTag(tc.board); got != tc.expected {
+				t.Errorf("Expected %s, got %s", tc.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package analytics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// legacyPattern matches keys written before analytics keys were hash-tagged,
+// analytics:<short id>:<field> and analytics:top:<scope>[:<value>]:<date>
+const legacyPattern = "analytics:[^{]*"
+
+// Legacy analytics fields by how they are merged into the current keys
+var (
+	legacyCounters = map[string]bool{"total_clicks": true, "bot_clicks": true, "unique_visits": true}
+	legacyHashes   = map[string]bool{"hourly_clicks": true, "variant_clicks": true}
+)
+
+// MigrateLegacyKeys merges analytics recorded under the keys used before
+// hash tags into the current keys and deletes the old ones. It returns the
+// number of migrated keys; running it again only picks up keys written by
+// replicas that still use the old layout.
+func (a *AnalyticsStore) MigrateLegacyKeys(ctx context.Context) (migrated int, err error) {
+	ctx, span := tracing.Start(ctx, "AnalyticsStore.MigrateLegacyKeys")
+	defer func() {
+		span.SetAttributes(attribute.Int("keys", migrated))
+		tracing.End(span, err)
+	}()
+
+	keys, err := a.scanKeys(ctx, legacyPattern)
+	if err != nil {
+		return 0, fmt.Errorf("failed to scan legacy analytics keys: %v", err)
+	}
+
+	for _, key := range keys {
+		target, ok := legacyTarget(key)
+		if !ok {
+			continue
+		}
+		if err := a.migrateKey(ctx, key, target); err != nil {
+			return migrated, fmt.Errorf("failed to migrate %s: %v", key, err)
+		}
+		migrated++
+	}
+	return migrated, nil
+}
+
+// legacyTarget returns the current key of a legacy analytics key
+func legacyTarget(key string) (string, bool) {
+	rest, ok := strings.CutPrefix(key, "analytics:")
+	if !ok || strings.ContainsAny(rest, "{}") {
+		return "", false
+	}
+	i := strings.LastIndex(rest, ":")
+	if i <= 0 {
+		return "", false
+	}
+	name, suffix := rest[:i], rest[i+1:]
+
+	// Daily leaderboards end with their date
+	if day, err := time.Parse(leaderboardDayLayout, suffix); err == nil {
+		board, ok := legacyBoard(name)
+		if !ok {
+			return "", false
+		}
+		return leaderboardKey(board, day), true
+	}
+
+	if !legacyCounters[suffix] && !legacyHashes[suffix] && suffix != "unique_ips" &&
+		suffix != "first_accessed" && suffix != "last_accessed" {
+		return "", false
+	}
+	return analyticsKey(name, suffix), true
+}
+
+// legacyBoard parses the board of a legacy leaderboard key without its date,
+// "top:global" or "top:<scope>:<value>"
+func legacyBoard(name string) (Board, bool) {
+	rest, ok := strings.CutPrefix(name, "top:")
+	if !ok {
+		return Board{}, false
+	}
+	if rest == ScopeGlobal {
+		return Board{Scope: ScopeGlobal}, true
+	}
+	scope, value, found := strings.Cut(rest, ":")
+	if !found || value == "" {
+		return Board{}, false
+	}
+	switch scope {
+	case ScopeOwner, ScopeTag, ScopeDomain:
+		return Board{Scope: scope, Value: value}, true
+	}
+	return Board{}, false
+}
+
+// migrateKey merges one legacy key into its current key. Both may live in
+// different cluster slots, so they are written with separate commands.
+func (a *AnalyticsStore) migrateKey(ctx context.Context, legacy, target string) error {
+	client := a.redis()
+
+	keyType, err := client.Type(ctx, legacy).Result()
+	if err != nil {
+		return err
+	}
+	field := legacy[strings.LastIndex(legacy, ":")+1:]
+
+	switch keyType {
+	case "none":
+		// Expired or migrated concurrently
+		return nil
+	case "string":
+		value, err := client.Get(ctx, legacy).Result()
+		if err != nil {
+			if errors.Is(err, redis.Nil) {
+				return nil
+			}
+			return err
+		}
+		if err := mergeString(ctx, client, target, field, value); err != nil {
+			return err
+		}
+	case "set":
+		members, err := client.SMembers(ctx, legacy).Result()
+		if err != nil {
+			return err
+		}
+		if len(members) > 0 {
+			if err := client.SAdd(ctx, target, members).Err(); err != nil {
+				return err
+			}
+		}
+	case "hash":
+		values, err := client.HGetAll(ctx, legacy).Result()
+		if err != nil {
+			return err
+		}
+		pipe := client.Pipeline()
+		for name, value := range values {
+			count, _ := strconv.ParseInt(value, 10, 64)
+			pipe.HIncrBy(ctx, target, name, count)
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			return err
+		}
+	case "zset":
+		scores, err := client.ZRangeWithScores(ctx, legacy, 0, -1).Result()
+		if err != nil {
+			return err
+		}
+		pipe := client.Pipeline()
+		for _, score := range scores {
+			member, _ := score.Member.(string)
+			pipe.ZIncrBy(ctx, target, score.Score, member)
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unexpected key type %s", keyType)
+	}
+
+	// Merged keys keep the retention of the legacy key unless they expire already
+	ttl, err := client.PTTL(ctx, legacy).Result()
+	if err != nil {
+		return err
+	}
+	if ttl > 0 {
+		if targetTTL, err := client.PTTL(ctx, target).Result(); err == nil && targetTTL == -1 {
+			client.PExpire(ctx, target, ttl)
+		}
+	}
+
+	return client.Del(ctx, legacy).Err()
+}
+
+// mergeString merges a legacy counter or access time into target
+func mergeString(ctx context.Context, client redis.UniversalClient, target, field, value string) error {
+	if legacyCounters[field] {
+		count, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil
+		}
+		return client.IncrBy(ctx, target, count).Err()
+	}
+
+	legacyTime, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil
+	}
+	current, err := client.Get(ctx, target).Result()
+	if errors.Is(err, redis.Nil) {
+		return client.SetNX(ctx, target, value, 0).Err()
+	}
+	if err != nil {
+		return err
+	}
+
+	// Keep the earliest first access and the latest last access
+	currentTime, err := time.Parse(time.RFC3339, current)
+	replace := err != nil ||
+		(field == "first_accessed" && legacyTime.Before(currentTime)) ||
+		(field == "last_accessed" && legacyTime.After(currentTime))
+	if !replace {
+		return nil
+	}
+	return client.Set(ctx, target, value, redis.KeepTTL).Err()
+}
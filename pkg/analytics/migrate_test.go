@@ -0,0 +1,100 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package analytics
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestMigrateLegacyKeys tests merging analytics recorded before hash tags
+func TestMigrateLegacyKeys(t *testing.T) {
+	mr, client := setupMockRedis()
+	defer mr.Close()
+	defer client.Close()
+
+	store := NewAnalyticsStore(client)
+	ctx := context.Background()
+
+	first := time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)
+	last := time.Date(2024, 1, 2, 8, 0, 0, 0, time.UTC)
+	day := time.Now().UTC()
+
+	// Analytics written by an older version
+	mr.Set("analytics:abc123:total_clicks", "5")
+	mr.Set("analytics:abc123:bot_clicks", "2")
+	mr.Set("analytics:abc123:first_accessed", first.Format(time.RFC3339))
+	mr.Set("analytics:abc123:last_accessed", last.Format(time.RFC3339))
+	mr.SetAdd("analytics:abc123:unique_ips", "192.0.2.1", "192.0.2.2")
+	mr.HSet("analytics:abc123:variant_clicks", "control", "4")
+	mr.SetTTL("analytics:abc123:total_clicks", time.Hour)
+	mr.ZAdd("analytics:top:global:"+day.Format(leaderboardDayLayout), 5, "abc123")
+	mr.ZAdd("analytics:top:tag:launch:"+day.Format(leaderboardDayLayout), 5, "abc123")
+	mr.Set("analytics:unrelated", "1")
+
+	// And one click after the upgrade
+	if err := store.RecordAccessEvent(ctx, AccessEvent{
+		ShortID:   "abc123",
+		IPAddress: "192.0.2.2",
+		Tags:      []string{"launch"},
+		Variant:   "control",
+		Timestamp: time.Now(),
+	}); err != nil {
+		t.Fatalf("RecordAccessEvent failed: %v", err)
+	}
+
+	migrated, err := store.MigrateLegacyKeys(ctx)
+	if err != nil {
+		t.Fatalf("MigrateLegacyKeys failed: %v", err)
+	}
+	if migrated != 8 {
+		t.Errorf("Expected 8 migrated keys, got %d", migrated)
+	}
+
+	analytics, err := store.GetURLAnalytics(ctx, "abc123")
+	if err != nil {
+		t.Fatalf("GetURLAnalytics failed: %v", err)
+	}
+	if analytics.TotalClicks != 6 || analytics.BotClicks != 2 || analytics.UniqueVisits != 2 {
+		t.Errorf("Expected 6 clicks, 2 bot clicks and 2 unique visits, got %+v", analytics)
+	}
+	if !analytics.FirstAccessed.Equal(first) {
+		t.Errorf("Expected first access %v, got %v", first, analytics.FirstAccessed)
+	}
+	if !analytics.LastAccessed.After(last) {
+		t.Errorf("Expected the last access after the upgrade, got %v", analytics.LastAccessed)
+	}
+	if analytics.VariantClicks["control"] != 5 {
+		t.Errorf("Expected 5 control clicks, got %+v", analytics.VariantClicks)
+	}
+	if ttl := mr.TTL(totalClicksKey("abc123")); ttl != time.Hour {
+		t.Errorf("Expected the legacy TTL to be kept, got %v", ttl)
+	}
+
+	for _, board := range []Board{{Scope: ScopeGlobal}, {Scope: ScopeTag, Value: "launch"}} {
+		top, err := store.TopLinks(ctx, board, 1, 10)
+		if err != nil {
+			t.Fatalf("TopLinks failed: %v", err)
+		}
+		if len(top) != 1 || top[0].Clicks != 6 {
+			t.Errorf("Expected 6 clicks on the %s board, got %+v", board.Scope, top)
+		}
+	}
+
+	if mr.Exists("analytics:abc123:total_clicks") || !mr.Exists("analytics:unrelated") {
+		t.Errorf("Expected only the migrated legacy keys to be deleted")
+	}
+
+	// Running it again finds nothing left to migrate
+	if migrated, err := store.MigrateLegacyKeys(ctx); err != nil || migrated != 0 {
+		t.Errorf("Expected nothing to migrate, got %d (%v)", migrated, err)
+	}
+}
@@ -0,0 +1,248 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package analytics
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	// ErrQueueFull occurs when an event is dropped because the queue is full
+	ErrQueueFull = errors.New("analytics queue is full, event dropped")
+
+	// ErrPipelineClosed occurs when an event is recorded after Close
+	ErrPipelineClosed = errors.New("analytics pipeline is closed")
+)
+
+const (
+	defaultQueueSize     = 10000
+	defaultWorkers       = 4
+	defaultBatchSize     = 100
+	defaultFlushInterval = time.Second
+	defaultWriteTimeout  = 5 * time.Second
+)
+
+// PipelineConfig configures the asynchronous analytics pipeline
+type PipelineConfig struct {
+	QueueSize      int                         // Maximum number of buffered events
+	Workers        int                         // Number of concurrent batch writers
+	BatchSize      int                         // Maximum number of events per Redis pipeline
+	FlushInterval  time.Duration               // Maximum time an event waits before being written
+	EnqueueTimeout time.Duration               // Time to wait for queue space before dropping (0 drops immediately)
+	WriteTimeout   time.Duration               // Timeout of a single batch write
+	OnError        func(err error, events int) // Called when a batch could not be written
+}
+
+// PipelineStats is a snapshot of the pipeline counters
+type PipelineStats struct {
+	Enqueued      int64 `json:"enqueued"`
+	Dropped       int64 `json:"dropped"`
+	Processed     int64 `json:"processed"`
+	Failed        int64 `json:"failed"`
+	QueueDepth    int   `json:"queue_depth"`
+	QueueCapacity int   `json:"queue_capacity"`
+}
+
+// Pipeline records access events asynchronously through a bounded queue.
+// Worker goroutines batch queued events into single Redis pipelines.
+// Read methods are served directly by the embedded AnalyticsStore.
+type Pipeline struct {
+	*AnalyticsStore
+
+	cfg    PipelineConfig
+	events chan AccessEvent
+	wg     sync.WaitGroup
+
+	// mutex guards closed and the events channel against sends after close
+	mutex  sync.RWMutex
+	closed bool
+
+	enqueued  atomic.Int64
+	dropped   atomic.Int64
+	processed atomic.Int64
+	failed    atomic.Int64
+}
+
+// NewPipeline creates a pipeline on top of the store and starts its workers
+func NewPipeline(store *AnalyticsStore, cfg PipelineConfig) *Pipeline {
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = defaultQueueSize
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = defaultWorkers
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultBatchSize
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = defaultFlushInterval
+	}
+	if cfg.WriteTimeout <= 0 {
+		cfg.WriteTimeout = defaultWriteTimeout
+	}
+
+	p := &Pipeline{
+		AnalyticsStore: store,
+		cfg:            cfg,
+		events:         make(chan AccessEvent, cfg.QueueSize),
+	}
+
+	for i := 0; i < cfg.Workers; i++ {
+		p.wg.Add(1)
+		go p.worker()
+	}
+
+	return p
+}
+
+// RecordURLAccess queues a URL access
+func (p *Pipeline) RecordURLAccess(ctx context.Context, shortID, ipAddress string) error {
+	return p.RecordAccessEvent(ctx, AccessEvent{
+		ShortID:   shortID,
+		IPAddress: ipAddress,
+		Timestamp: time.Now(),
+	})
+}
+
+// RecordAccessEvent queues an access event without waiting for Redis.
+// When the queue is full the event is dropped after EnqueueTimeout.
+func (p *Pipeline) RecordAccessEvent(ctx context.Context, event AccessEvent) error {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+	// The batch span is linked to the request that queued the event
+	event.spanContext = trace.SpanContextFromContext(ctx)
+
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	if p.closed {
+		p.dropped.Add(1)
+		return ErrPipelineClosed
+	}
+
+	// Fast path: queue has room
+	select {
+	case p.events <- event:
+		p.enqueued.Add(1)
+		return nil
+	default:
+	}
+
+	// Drop immediately unless backpressure is configured
+	if p.cfg.EnqueueTimeout <= 0 {
+		p.dropped.Add(1)
+		return ErrQueueFull
+	}
+
+	timer := time.NewTimer(p.cfg.EnqueueTimeout)
+	defer timer.Stop()
+
+	select {
+	case p.events <- event:
+		p.enqueued.Add(1)
+		return nil
+	case <-timer.C:
+		p.dropped.Add(1)
+		return ErrQueueFull
+	case <-ctx.Done():
+		p.dropped.Add(1)
+		return ctx.Err()
+	}
+}
+
+// Stats returns a snapshot of the pipeline counters
+func (p *Pipeline) Stats() PipelineStats {
+	return PipelineStats{
+		Enqueued:      p.enqueued.Load(),
+		Dropped:       p.dropped.Load(),
+		Processed:     p.processed.Load(),
+		Failed:        p.failed.Load(),
+		QueueDepth:    len(p.events),
+		QueueCapacity: cap(p.events),
+	}
+}
+
+// Close stops accepting events and flushes the queue.
+// It returns the context error if the queue could not be drained in time.
+func (p *Pipeline) Close(ctx context.Context) error {
+	p.mutex.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.events)
+	}
+	p.mutex.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// worker collects queued events into batches and writes them
+func (p *Pipeline) worker() {
+	defer p.wg.Done()
+
+	batch := make([]AccessEvent, 0, p.cfg.BatchSize)
+	ticker := time.NewTicker(p.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case event, ok := <-p.events:
+			if !ok {
+				// Queue closed, flush what is left
+				p.flush(batch)
+				return
+			}
+			batch = append(batch, event)
+			if len(batch) >= p.cfg.BatchSize {
+				p.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			p.flush(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+// flush writes a batch of events in one Redis pipeline
+func (p *Pipeline) flush(batch []AccessEvent) {
+	if len(batch) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.cfg.WriteTimeout)
+	defer cancel()
+
+	if err := p.AnalyticsStore.RecordBatch(ctx, batch); err != nil {
+		p.failed.Add(int64(len(batch)))
+		if p.cfg.OnError != nil {
+			p.cfg.OnError(err, len(batch))
+		}
+		return
+	}
+	p.processed.Add(int64(len(batch)))
+}
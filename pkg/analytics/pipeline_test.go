@@ -0,0 +1,165 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package analytics
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestPipelineFlushOnClose tests that queued events are written on shutdown
+func TestPipelineFlushOnClose(t *testing.T) {
+	mr, client := setupMockRedis()
+	defer mr.Close()
+	defer client.Close()
+
+	store := NewAnalyticsStore(client)
+
+	// Long flush interval so only Close writes the batch
+	pipeline := NewPipeline(store, PipelineConfig{
+		QueueSize:     100,
+		Workers:       2,
+		BatchSize:     50,
+		FlushInterval: time.Hour,
+	})
+
+	ctx := context.Background()
+	ips := []string{"192.168.1.1", "192.168.1.2", "192.168.1.1"}
+	for _, ip := range ips {
+		if err := pipeline.RecordURLAccess(ctx, "pipe-url", ip); err != nil {
+			t.Fatalf("RecordURLAccess failed: %v", err)
+		}
+	}
+
+	if err := pipeline.Close(ctx); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	analytics, err := pipeline.GetURLAnalytics(ctx, "pipe-url")
+	if err != nil {
+		t.Fatalf("GetURLAnalytics failed: %v", err)
+	}
+	if analytics.TotalClicks != 3 {
+		t.Errorf("Expected total clicks 3, got %d", analytics.TotalClicks)
+	}
+	if analytics.UniqueVisits != 2 {
+		t.Errorf("Expected unique visits 2, got %d", analytics.UniqueVisits)
+	}
+
+	stats := pipeline.Stats()
+	if stats.Enqueued != 3 || stats.Processed != 3 {
+		t.Errorf("Expected 3 enqueued and processed events, got %+v", stats)
+	}
+
+	// Recording after close must fail
+	if err := pipeline.RecordURLAccess(ctx, "pipe-url", "192.168.1.3"); !errors.Is(err, ErrPipelineClosed) {
+		t.Errorf("Expected ErrPipelineClosed, got %v", err)
+	}
+}
+
+// TestPipelineBatchSize tests that a full batch is written without waiting for the flush interval
+func TestPipelineBatchSize(t *testing.T) {
+	mr, client := setupMockRedis()
+	defer mr.Close()
+	defer client.Close()
+
+	pipeline := NewPipeline(NewAnalyticsStore(client), PipelineConfig{
+		QueueSize:     10,
+		Workers:       1,
+		BatchSize:     2,
+		FlushInterval: time.Hour,
+	})
+	defer pipeline.Close(context.Background())
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if err := pipeline.RecordURLAccess(ctx, "batch-url", "10.0.0.1"); err != nil {
+			t.Fatalf("RecordURLAccess failed: %v", err)
+		}
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for pipeline.Stats().Processed < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Batch was not written, stats: %+v", pipeline.Stats())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestPipelineDropWhenFull tests the drop policy of a saturated queue
+func TestPipelineDropWhenFull(t *testing.T) {
+	mr, client := setupMockRedis()
+	defer mr.Close()
+	defer client.Close()
+
+	// Build the pipeline without workers so nothing drains the queue
+	pipeline := &Pipeline{
+		AnalyticsStore: NewAnalyticsStore(client),
+		cfg:            PipelineConfig{EnqueueTimeout: 10 * time.Millisecond},
+		events:         make(chan AccessEvent, 1),
+	}
+
+	ctx := context.Background()
+	if err := pipeline.RecordURLAccess(ctx, "full-url", "10.0.0.1"); err != nil {
+		t.Fatalf("First event should be queued: %v", err)
+	}
+	if err := pipeline.RecordURLAccess(ctx, "full-url", "10.0.0.2"); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Expected ErrQueueFull, got %v", err)
+	}
+
+	stats := pipeline.Stats()
+	if stats.Dropped != 1 {
+		t.Errorf("Expected 1 dropped event, got %d", stats.Dropped)
+	}
+	if stats.QueueDepth != 1 || stats.QueueCapacity != 1 {
+		t.Errorf("Expected queue depth 1 of 1, got %d of %d", stats.QueueDepth, stats.QueueCapacity)
+	}
+}
+
+// TestPipelineWriteFailure tests that failed batches are reported
+func TestPipelineWriteFailure(t *testing.T) {
+	mr, client := setupMockRedis()
+	defer client.Close()
+
+	reported := make(chan int, 1)
+	pipeline := NewPipeline(NewAnalyticsStore(client), PipelineConfig{
+		Workers:       1,
+		FlushInterval: time.Hour,
+		WriteTimeout:  100 * time.Millisecond,
+		OnError: func(err error, events int) {
+			reported <- events
+		},
+	})
+
+	// Redis goes away before the batch is flushed
+	mr.Close()
+
+	ctx := context.Background()
+	if err := pipeline.RecordURLAccess(ctx, "fail-url", "10.0.0.1"); err != nil {
+		t.Fatalf("RecordURLAccess failed: %v", err)
+	}
+	pipeline.Close(ctx)
+
+	select {
+	case events := <-reported:
+		if events != 1 {
+			t.Errorf("Expected 1 failed event, got %d", events)
+		}
+	default:
+		t.Error("Expected write failure to be reported")
+	}
+
+	if failed := pipeline.Stats().Failed; failed != 1 {
+		t.Errorf("Expected 1 failed event, got %d", failed)
+	}
+}
@@ -0,0 +1,130 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package analytics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	// purgeScanCount is the SCAN/XRANGE page size used while purging
+	purgeScanCount = 500
+
+	// leaderboardPattern matches daily leaderboard keys only
+	leaderboardPattern = "analytics:top:*:[0-9][0-9][0-9][0-9]-[0-9][0-9]-[0-9][0-9]"
+)
+
+// Purger deletes all analytics recorded for a URL
+type Purger interface {
+	PurgeURLAnalytics(ctx context.Context, shortID string) error
+}
+
+// PurgeURLAnalytics deletes the counters, leaderboard entries and streamed
+// events of a URL. Events still queued in a Pipeline are written afterwards.
+func (a *AnalyticsStore) PurgeURLAnalytics(ctx context.Context, shortID string) (err error) {
+	ctx, span := tracing.Start(ctx, "AnalyticsStore.PurgeURLAnalytics", attribute.String("short_id", shortID))
+	defer func() { tracing.End(span, err) }()
+
+	if err := a.redis().Del(ctx, urlKeys(shortID)...).Err(); err != nil {
+		return fmt.Errorf("failed to delete analytics: %v", err)
+	}
+
+	if err := a.purgeLeaderboards(ctx, shortID); err != nil {
+		return err
+	}
+
+	if a.stream != nil {
+		if err := a.purgeStream(ctx, shortID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// purgeLeaderboards removes the URL from every daily leaderboard
+func (a *AnalyticsStore) purgeLeaderboards(ctx context.Context, shortID string) error {
+	keys, err := a.scanKeys(ctx, leaderboardPattern)
+	if err != nil {
+		return fmt.Errorf("failed to scan leaderboards: %v", err)
+	}
+
+	pipe := a.redis().Pipeline()
+	for _, key := range keys {
+		pipe.ZRem(ctx, key, shortID)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return fmt.Errorf("failed to purge leaderboards: %v", err)
+	}
+	return nil
+}
+
+// scanKeys returns all keys matching pattern. On a cluster every master is
+// scanned, a SCAN through the cluster client only covers a single node.
+func (a *AnalyticsStore) scanKeys(ctx context.Context, pattern string) ([]string, error) {
+	var (
+		mu   sync.Mutex
+		keys []string
+	)
+	scan := func(ctx context.Context, client redis.Cmdable) error {
+		iter := client.Scan(ctx, 0, pattern, purgeScanCount).Iterator()
+		for iter.Next(ctx) {
+			mu.Lock()
+			keys = append(keys, iter.Val())
+			mu.Unlock()
+		}
+		return iter.Err()
+	}
+
+	if cluster, ok := a.redis().(*redis.ClusterClient); ok {
+		err := cluster.ForEachMaster(ctx, func(ctx context.Context, client *redis.Client) error {
+			return scan(ctx, client)
+		})
+		return keys, err
+	}
+	return keys, scan(ctx, a.redis())
+}
+
+// purgeStream deletes the URL's events from the event stream
+func (a *AnalyticsStore) purgeStream(ctx context.Context, shortID string) error {
+	name := a.streamName()
+	start := "-"
+
+	for {
+		messages, err := a.redis().XRangeN(ctx, name, start, "+", purgeScanCount).Result()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return fmt.Errorf("failed to read event stream: %v", err)
+		}
+
+		var ids []string
+		for _, msg := range messages {
+			if value, _ := msg.Values["short_id"].(string); value == shortID {
+				ids = append(ids, msg.ID)
+			}
+		}
+		if len(ids) > 0 {
+			if err := a.redis().XDel(ctx, name, ids...).Err(); err != nil {
+				return fmt.Errorf("failed to delete streamed events: %v", err)
+			}
+		}
+
+		if len(messages) < purgeScanCount {
+			return nil
+		}
+		start = nextStreamID(messages[len(messages)-1].ID)
+	}
+}
@@ -0,0 +1,152 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package analytics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// TestPurgeURLAnalytics tests that all analytics of a URL are deleted
+func TestPurgeURLAnalytics(t *testing.T) {
+	mr, client := setupMockRedis()
+	defer mr.Close()
+	defer client.Close()
+
+	store := NewAnalyticsStore(client, WithEventStream(StreamConfig{Name: "test:events"}))
+	ctx := context.Background()
+
+	for _, shortID := range []string{"purge-url", "keep-url"} {
+		err := store.RecordAccessEvent(ctx, AccessEvent{
+			ShortID:   shortID,
+			IPAddress: "10.0.0.1",
+			Owner:     "alice",
+			Tags:      []string{"launch"},
+		})
+		if err != nil {
+			t.Fatalf("RecordAccessEvent failed: %v", err)
+		}
+	}
+
+	if err := store.PurgeURLAnalytics(ctx, "purge-url"); err != nil {
+		t.Fatalf("PurgeURLAnalytics failed: %v", err)
+	}
+
+	for _, key := range urlKeys("purge-url") {
+		if mr.Exists(key) {
+			t.Errorf("Expected key %s to be deleted", key)
+		}
+	}
+
+	top, err := store.TopLinks(ctx, Board{Scope: ScopeOwner, Value: "alice"}, 1, 10)
+	if err != nil {
+		t.Fatalf("TopLinks failed: %v", err)
+	}
+	if len(top) != 1 || top[0].ShortID != "keep-url" {
+		t.Errorf("Expected only keep-url on the leaderboard, got %+v", top)
+	}
+
+	var streamed []string
+	err = store.ExportEvents(ctx, "purge-url", time.Now().Add(-time.Hour), time.Now().Add(time.Hour), func(event StreamEvent) error {
+		streamed = append(streamed, event.ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ExportEvents failed: %v", err)
+	}
+	if len(streamed) != 0 {
+		t.Errorf("Expected streamed events to be deleted, got %v", streamed)
+	}
+
+	// Other URLs are untouched
+	analytics, err := store.GetURLAnalytics(ctx, "keep-url")
+	if err != nil || analytics.TotalClicks != 1 {
+		t.Errorf("Expected keep-url analytics to remain, got %+v (%v)", analytics, err)
+	}
+}
+
+// TestPurgeURLAnalytics_Cluster tests leaderboards and purging through a
+// cluster client, which scans every master
+func TestPurgeURLAnalytics_Cluster(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClusterClient(&redis.ClusterOptions{Addrs: []string{mr.Addr()}})
+	defer client.Close()
+
+	store := NewAnalyticsStore(client)
+	ctx := context.Background()
+
+	for _, shortID := range []string{"purge-url", "keep-url"} {
+		if err := store.RecordAccessEvent(ctx, AccessEvent{ShortID: shortID, Owner: "alice"}); err != nil {
+			t.Fatalf("RecordAccessEvent failed: %v", err)
+		}
+	}
+	if err := store.PurgeURLAnalytics(ctx, "purge-url"); err != nil {
+		t.Fatalf("PurgeURLAnalytics failed: %v", err)
+	}
+
+	// Several days are summed in one slot
+	top, err := store.TopLinks(ctx, Board{Scope: ScopeOwner, Value: "alice"}, 7, 10)
+	if err != nil {
+		t.Fatalf("TopLinks failed: %v", err)
+	}
+	if len(top) != 1 || top[0].ShortID != "keep-url" {
+		t.Errorf("Expected only keep-url on the leaderboard, got %+v", top)
+	}
+}
+
+// TestPrivacyOptions tests IP anonymization and retention of analytics keys
+func TestPrivacyOptions(t *testing.T) {
+	mr, client := setupMockRedis()
+	defer mr.Close()
+	defer client.Close()
+
+	store := NewAnalyticsStore(client,
+		WithIPAnonymizer(fixedAnonymizer{}),
+		WithRetention(24*time.Hour),
+	)
+	ctx := context.Background()
+
+	if err := store.RecordURLAccess(ctx, "private-url", "192.168.1.77"); err != nil {
+		t.Fatalf("RecordURLAccess failed: %v", err)
+	}
+
+	members, err := mr.Members(uniqueIPKey("private-url"))
+	if err != nil {
+		t.Fatalf("Failed to read unique IPs: %v", err)
+	}
+	if len(members) != 1 || members[0] != "anonymized" {
+		t.Errorf("Expected only anonymized IPs to be stored, got %v", members)
+	}
+
+	if ttl := mr.TTL(totalClicksKey("private-url")); ttl != 24*time.Hour {
+		t.Errorf("Expected retention TTL of 24h, got %v", ttl)
+	}
+
+	// Keys expire once the retention window passes without access
+	mr.FastForward(25 * time.Hour)
+	analytics, err := store.GetURLAnalytics(ctx, "private-url")
+	if err != nil {
+		t.Fatalf("GetURLAnalytics failed: %v", err)
+	}
+	if analytics.TotalClicks != 0 {
+		t.Errorf("Expected analytics to expire, got %d clicks", analytics.TotalClicks)
+	}
+}
+
+// fixedAnonymizer replaces every IP with a fixed value
+type fixedAnonymizer struct{}
+
+func (fixedAnonymizer) Anonymize(ip string, at time.Time) string {
+	return "anonymized"
+}
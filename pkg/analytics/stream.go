@@ -0,0 +1,233 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package analytics
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultStreamName is the default Redis Stream key for access events
+const DefaultStreamName = "analytics:events"
+
+// StreamConfig configures the access event stream
+type StreamConfig struct {
+	Name   string // Redis Stream key
+	MaxLen int64  // Approximate maximum stream length (0 means unbounded)
+	IPSalt string // Secret key of the HMAC over client IPs
+}
+
+// StreamEvent is an access event read back from the stream
+type StreamEvent struct {
+	ID        string    `json:"id"` // Stream entry ID, unique per event
+	ShortID   string    `json:"short_id"`
+	Timestamp time.Time `json:"timestamp"`
+	IPHash    string    `json:"ip_hash"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	Referrer  string    `json:"referrer,omitempty"`
+	Country   string    `json:"country,omitempty"`
+	Bot       bool      `json:"bot"`
+	Variant   string    `json:"variant,omitempty"`
+}
+
+// appendToStream adds XADDs for the event to the pipeline. The event goes to
+// the shared stream read by consumers and to a per-URL stream used by exports.
+func (a *AnalyticsStore) appendToStream(ctx context.Context, pipe redis.Pipeliner, event AccessEvent) {
+	values := map[string]interface{}{
+		"short_id":   event.ShortID,
+		"timestamp":  event.Timestamp.UTC().Format(time.RFC3339Nano),
+		"ip_hash":    hashIP(event.IPAddress, a.stream.IPSalt),
+		"user_agent": event.UserAgent,
+		"referrer":   event.Referrer,
+		"country":    event.Country,
+		"bot":        strconv.FormatBool(event.Bot),
+		"variant":    event.Variant,
+	}
+	for _, stream := range []string{a.streamName(), eventsKey(event.ShortID)} {
+		pipe.XAdd(ctx, &redis.XAddArgs{
+			Stream: stream,
+			MaxLen: a.stream.MaxLen,
+			Approx: a.stream.MaxLen > 0,
+			Values: values,
+		})
+	}
+}
+
+// streamName returns the configured stream key
+func (a *AnalyticsStore) streamName() string {
+	if a.stream == nil || a.stream.Name == "" {
+		return DefaultStreamName
+	}
+	return a.stream.Name
+}
+
+// hashIP returns an HMAC-SHA256 of the IP address keyed with the secret
+// salt, without the key the address space cannot be enumerated
+func hashIP(ip, salt string) string {
+	if ip == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, []byte(salt))
+	mac.Write([]byte(ip))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// parseStreamEvent converts a stream entry into a StreamEvent
+func parseStreamEvent(msg redis.XMessage) StreamEvent {
+	value := func(key string) string {
+		if v, ok := msg.Values[key].(string); ok {
+			return v
+		}
+		return ""
+	}
+
+	event := StreamEvent{
+		ID:        msg.ID,
+		ShortID:   value("short_id"),
+		IPHash:    value("ip_hash"),
+		UserAgent: value("user_agent"),
+		Referrer:  value("referrer"),
+		Country:   value("country"),
+		Bot:       value("bot") == "true",
+		Variant:   value("variant"),
+	}
+	event.Timestamp, _ = time.Parse(time.RFC3339Nano, value("timestamp"))
+
+	return event
+}
+
+// StreamReader reads access events through a Redis consumer group.
+// Every event is delivered to one consumer of the group and stays pending
+// until it is acknowledged, so a crashed consumer's events can be claimed
+// by another one. Consumers should treat the event ID as an idempotency key.
+type StreamReader struct {
+	client   redis.UniversalClient
+	stream   string
+	group    string
+	consumer string
+}
+
+// NewStreamReader creates a reader for a consumer in a consumer group
+func NewStreamReader(client redis.UniversalClient, stream, group, consumer string) *StreamReader {
+	if stream == "" {
+		stream = DefaultStreamName
+	}
+	return &StreamReader{
+		client:   client,
+		stream:   stream,
+		group:    group,
+		consumer: consumer,
+	}
+}
+
+// EnsureGroup creates the consumer group if it does not exist yet.
+// A new group starts with events appended after its creation.
+func (s *StreamReader) EnsureGroup(ctx context.Context) error {
+	err := s.client.XGroupCreateMkStream(ctx, s.stream, s.group, "$").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("failed to create consumer group: %v", err)
+	}
+	return nil
+}
+
+// Read returns up to count new events, waiting at most block for them.
+// A negative block returns immediately.
+func (s *StreamReader) Read(ctx context.Context, count int64, block time.Duration) ([]StreamEvent, error) {
+	streams, err := s.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    s.group,
+		Consumer: s.consumer,
+		Streams:  []string{s.stream, ">"},
+		Count:    count,
+		Block:    block,
+	}).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read events: %v", err)
+	}
+
+	var events []StreamEvent
+	for _, stream := range streams {
+		for _, msg := range stream.Messages {
+			events = append(events, parseStreamEvent(msg))
+		}
+	}
+	return events, nil
+}
+
+// Claim takes over up to count events left unacknowledged by other
+// consumers for longer than minIdle
+func (s *StreamReader) Claim(ctx context.Context, minIdle time.Duration, count int64) ([]StreamEvent, error) {
+	messages, _, err := s.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+		Stream:   s.stream,
+		Group:    s.group,
+		Consumer: s.consumer,
+		MinIdle:  minIdle,
+		Start:    "0-0",
+		Count:    count,
+	}).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("failed to claim events: %v", err)
+	}
+
+	events := make([]StreamEvent, 0, len(messages))
+	for _, msg := range messages {
+		events = append(events, parseStreamEvent(msg))
+	}
+	return events, nil
+}
+
+// Ack acknowledges processed events so they are not delivered again
+func (s *StreamReader) Ack(ctx context.Context, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := s.client.XAck(ctx, s.stream, s.group, ids...).Err(); err != nil {
+		return fmt.Errorf("failed to acknowledge events: %v", err)
+	}
+	return nil
+}
+
+// Process reads a batch of new events and acknowledges the ones handled
+// without error. Failed events stay pending and can be claimed later.
+func (s *StreamReader) Process(
+	ctx context.Context,
+	count int64,
+	block time.Duration,
+	handle func(StreamEvent) error,
+) (int, error) {
+	events, err := s.Read(ctx, count, block)
+	if err != nil {
+		return 0, err
+	}
+
+	var acked []string
+	for _, event := range events {
+		if err := handle(event); err != nil {
+			continue
+		}
+		acked = append(acked, event.ID)
+	}
+
+	if err := s.Ack(ctx, acked...); err != nil {
+		return 0, err
+	}
+	return len(acked), nil
+}
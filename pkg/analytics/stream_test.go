@@ -0,0 +1,136 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package analytics
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestEventStreamExport tests that access events are appended to the stream
+func TestEventStreamExport(t *testing.T) {
+	mr, client := setupMockRedis()
+	defer mr.Close()
+	defer client.Close()
+
+	store := NewAnalyticsStore(client, WithEventStream(StreamConfig{
+		Name:   "test:events",
+		MaxLen: 1000,
+		IPSalt: "salt",
+	}))
+
+	ctx := context.Background()
+	reader := NewStreamReader(client, "test:events", "exporters", "consumer-1")
+	if err := reader.EnsureGroup(ctx); err != nil {
+		t.Fatalf("EnsureGroup failed: %v", err)
+	}
+	// Creating the group twice is not an error
+	if err := reader.EnsureGroup(ctx); err != nil {
+		t.Fatalf("EnsureGroup should be idempotent: %v", err)
+	}
+
+	accessedAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	err := store.RecordAccessEvent(ctx, AccessEvent{
+		ShortID:   "stream-url",
+		IPAddress: "192.168.1.1",
+		UserAgent: "test-agent",
+		Referrer:  "https://ref.example.com",
+		Country:   "DE",
+		Timestamp: accessedAt,
+	})
+	if err != nil {
+		t.Fatalf("RecordAccessEvent failed: %v", err)
+	}
+
+	events, err := reader.Read(ctx, 10, -1)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(events))
+	}
+
+	event := events[0]
+	if event.ShortID != "stream-url" || event.Country != "DE" || event.UserAgent != "test-agent" {
+		t.Errorf("Unexpected event: %+v", event)
+	}
+	if !event.Timestamp.Equal(accessedAt) {
+		t.Errorf("Expected timestamp %v, got %v", accessedAt, event.Timestamp)
+	}
+	if event.IPHash == "" || event.IPHash == "192.168.1.1" {
+		t.Errorf("Expected hashed IP, got %q", event.IPHash)
+	}
+	// The hash is keyed, a plain digest of the address does not match it
+	plain := sha256.Sum256([]byte("salt192.168.1.1"))
+	if event.IPHash == hex.EncodeToString(plain[:]) || event.IPHash == hashIP("192.168.1.1", "other") {
+		t.Errorf("Expected an HMAC keyed with the salt, got %q", event.IPHash)
+	}
+
+	if err := reader.Ack(ctx, event.ID); err != nil {
+		t.Fatalf("Ack failed: %v", err)
+	}
+
+	// Acknowledged events are not delivered again
+	events, err = reader.Read(ctx, 10, -1)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if len(events) != 0 {
+		t.Errorf("Expected no new events, got %d", len(events))
+	}
+}
+
+// TestStreamReaderProcess tests that failed events stay pending and can be claimed
+func TestStreamReaderProcess(t *testing.T) {
+	mr, client := setupMockRedis()
+	defer mr.Close()
+	defer client.Close()
+
+	store := NewAnalyticsStore(client, WithEventStream(StreamConfig{Name: "test:events"}))
+
+	ctx := context.Background()
+	first := NewStreamReader(client, "test:events", "jobs", "first")
+	if err := first.EnsureGroup(ctx); err != nil {
+		t.Fatalf("EnsureGroup failed: %v", err)
+	}
+
+	for _, id := range []string{"ok-url", "fail-url"} {
+		if err := store.RecordURLAccess(ctx, id, "10.0.0.1"); err != nil {
+			t.Fatalf("RecordURLAccess failed: %v", err)
+		}
+	}
+
+	processed, err := first.Process(ctx, 10, -1, func(event StreamEvent) error {
+		if event.ShortID == "fail-url" {
+			return errors.New("processing failed")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	if processed != 1 {
+		t.Errorf("Expected 1 processed event, got %d", processed)
+	}
+
+	// A second consumer takes over the failed event
+	second := NewStreamReader(client, "test:events", "jobs", "second")
+	claimed, err := second.Claim(ctx, 0, 10)
+	if err != nil {
+		t.Fatalf("Claim failed: %v", err)
+	}
+	if len(claimed) != 1 || claimed[0].ShortID != "fail-url" {
+		t.Fatalf("Expected the failed event to be claimed, got %+v", claimed)
+	}
+}
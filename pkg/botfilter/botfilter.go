@@ -0,0 +1,199 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package botfilter
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultPatterns matches common link-preview bots, crawlers, uptime checkers and scanners
+var DefaultPatterns = []string{
+	`bot\b`, `crawler`, `spider`, `slurp`,
+	`slackbot`, `twitterbot`, `facebookexternalhit`, `linkedinbot`, `discordbot`,
+	`telegrambot`, `whatsapp`, `skypeuripreview`, `embedly`, `vkshare`,
+	`uptimerobot`, `pingdom`, `statuscake`, `site24x7`, `newrelicpinger`,
+	`curl/`, `wget/`, `python-requests`, `go-http-client`, `okhttp`, `httpclient`,
+	`headlesschrome`, `phantomjs`, `nmap`, `masscan`, `zgrab`, `nikto`,
+}
+
+// Classifier decides whether a request comes from a bot.
+// User-Agent patterns are case-insensitive regular expressions.
+type Classifier struct {
+	mutex    sync.RWMutex
+	patterns []*regexp.Regexp
+
+	// Pattern file for hot reloading
+	path    string
+	modTime time.Time
+}
+
+// NewClassifier creates a classifier with the given patterns,
+// or DefaultPatterns when none are given
+func NewClassifier(patterns ...string) (*Classifier, error) {
+	if len(patterns) == 0 {
+		patterns = DefaultPatterns
+	}
+
+	compiled, err := compilePatterns(patterns)
+	if err != nil {
+		return nil, err
+	}
+	return &Classifier{patterns: compiled}, nil
+}
+
+// LoadClassifier creates a classifier from a pattern file with one pattern
+// per line. Empty lines and lines starting with '#' are ignored.
+func LoadClassifier(path string) (*Classifier, error) {
+	c := &Classifier{path: path}
+	if err := c.Reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Reload re-reads the pattern file. The current patterns are kept on error.
+func (c *Classifier) Reload() error {
+	if c.path == "" {
+		return nil
+	}
+
+	info, err := os.Stat(c.path)
+	if err != nil {
+		return fmt.Errorf("failed to stat bot pattern file: %v", err)
+	}
+
+	patterns, err := readPatterns(c.path)
+	if err != nil {
+		return err
+	}
+	compiled, err := compilePatterns(patterns)
+	if err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	c.patterns = compiled
+	c.modTime = info.ModTime()
+	c.mutex.Unlock()
+
+	return nil
+}
+
+// Watch periodically reloads the pattern file when it changes, until ctx is
+// done. A non-positive interval disables reloading.
+func (c *Classifier) Watch(ctx context.Context, interval time.Duration, onError func(error)) {
+	if c.path == "" || interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			info, err := os.Stat(c.path)
+			if err == nil {
+				c.mutex.RLock()
+				changed := !info.ModTime().Equal(c.modTime)
+				c.mutex.RUnlock()
+				if !changed {
+					continue
+				}
+				err = c.Reload()
+			}
+			if err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}()
+}
+
+// IsBot reports whether the request looks like it comes from a bot
+func (c *Classifier) IsBot(r *http.Request) bool {
+	isBot, _ := c.Classify(r)
+	return isBot
+}
+
+// Classify reports whether the request comes from a bot and why
+func (c *Classifier) Classify(r *http.Request) (bool, string) {
+	// Link checkers and uptime monitors often only ask for headers
+	if r.Method == http.MethodHead {
+		return true, "head request"
+	}
+
+	userAgent := r.UserAgent()
+	if userAgent == "" {
+		return true, "missing user agent"
+	}
+
+	// Browsers always send an Accept header
+	if r.Header.Get("Accept") == "" {
+		return true, "missing accept header"
+	}
+
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	for _, pattern := range c.patterns {
+		if pattern.MatchString(userAgent) {
+			return true, "user agent matches " + pattern.String()
+		}
+	}
+
+	return false, ""
+}
+
+// readPatterns reads one pattern per line, skipping blanks and comments
+func readPatterns(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bot pattern file: %v", err)
+	}
+	defer file.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read bot pattern file: %v", err)
+	}
+	return patterns, nil
+}
+
+// compilePatterns compiles case-insensitive patterns
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile("(?i)" + pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bot pattern %q: %v", pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
@@ -0,0 +1,182 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package botfilter
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const browserUA = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0 Safari/537.36"
+
+func TestClassifier_IsBot(t *testing.T) {
+	classifier, err := NewClassifier()
+	if err != nil {
+		t.Fatalf("NewClassifier failed: %v", err)
+	}
+
+	testCases := []struct {
+		name      string
+		method    string
+		userAgent string
+		accept    string
+		expected  bool
+	}{
+		{
+			name:      "Browser",
+			method:    "GET",
+			userAgent: browserUA,
+			accept:    "text/html",
+			expected:  false,
+		},
+		{
+			name:      "Slack Link Preview",
+			method:    "GET",
+			userAgent: "Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)",
+			accept:    "*/*",
+			expected:  true,
+		},
+		{
+			name:      "Facebook Crawler",
+			method:    "GET",
+			userAgent: "facebookexternalhit/1.1",
+			accept:    "*/*",
+			expected:  true,
+		},
+		{
+			name:      "Uptime Checker",
+			method:    "GET",
+			userAgent: "Mozilla/5.0+(compatible; UptimeRobot/2.0; http://www.uptimerobot.com/)",
+			accept:    "*/*",
+			expected:  true,
+		},
+		{
+			name:      "HEAD Request",
+			method:    "HEAD",
+			userAgent: browserUA,
+			accept:    "text/html",
+			expected:  true,
+		},
+		{
+			name:      "Missing Accept Header",
+			method:    "GET",
+			userAgent: browserUA,
+			expected:  true,
+		},
+		{
+			name:     "Missing User Agent",
+			method:   "GET",
+			accept:   "text/html",
+			expected: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "/abc123", nil)
+			req.Header.Set("User-Agent", tc.userAgent)
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+
+			if isBot := classifier.IsBot(req); isBot != tc.expected {
+				t.Errorf("Expected bot %v, got %v", tc.expected, isBot)
+			}
+		})
+	}
+}
+
+func TestClassifier_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bots.txt")
+	if err := os.WriteFile(path, []byte("# comment\n\nmybot\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write pattern file: %v", err)
+	}
+
+	classifier, err := LoadClassifier(path)
+	if err != nil {
+		t.Fatalf("LoadClassifier failed: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/abc123", nil)
+	req.Header.Set("Accept", "*/*")
+	req.Header.Set("User-Agent", "OtherCrawler/1.0")
+
+	if classifier.IsBot(req) {
+		t.Fatalf("Pattern not in file should not match")
+	}
+
+	// Update the file and let the watcher pick it up
+	if err := os.WriteFile(path, []byte("othercrawler\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write pattern file: %v", err)
+	}
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(path, future, future)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	classifier.Watch(ctx, 10*time.Millisecond, nil)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !classifier.IsBot(req) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Pattern file was not reloaded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Invalid patterns keep the current list
+	if err := os.WriteFile(path, []byte("([\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write pattern file: %v", err)
+	}
+	if err := classifier.Reload(); err == nil {
+		t.Errorf("Expected error for invalid pattern")
+	}
+	if !classifier.IsBot(req) {
+		t.Errorf("Previous patterns should be kept after a failed reload")
+	}
+}
+
+func TestClassifier_WatchStops(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bots.txt")
+	if err := os.WriteFile(path, []byte("somecrawler\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write pattern file: %v", err)
+	}
+	classifier, err := LoadClassifier(path)
+	if err != nil {
+		t.Fatalf("LoadClassifier failed: %v", err)
+	}
+
+	// A non-positive interval disables reloading instead of panicking
+	classifier.Watch(context.Background(), 0, nil)
+	classifier.Watch(context.Background(), -time.Second, nil)
+
+	// A cancelled watcher no longer reloads the file
+	ctx, cancel := context.WithCancel(context.Background())
+	classifier.Watch(ctx, 10*time.Millisecond, nil)
+	cancel()
+	time.Sleep(20 * time.Millisecond)
+
+	if err := os.WriteFile(path, []byte("othercrawler\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write pattern file: %v", err)
+	}
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(path, future, future)
+	time.Sleep(50 * time.Millisecond)
+
+	req := httptest.NewRequest("GET", "/abc123", nil)
+	req.Header.Set("Accept", "*/*")
+	req.Header.Set("User-Agent", "OtherCrawler/1.0")
+	if classifier.IsBot(req) {
+		t.Errorf("Pattern file should not be reloaded after the watcher stopped")
+	}
+}
@@ -12,13 +12,16 @@ package errors
 import (
 	"encoding/json"
 	"net/http"
+
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/requestid"
 )
 
 // APIError is a custom error structure for API errors
 type APIError struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
-	Detail  string `json:"detail,omitempty"`
+	Code      int    `json:"code"`
+	Message   string `json:"message"`
+	Detail    string `json:"detail,omitempty"`
+	RequestID string `json:"request_id,omitempty"` // Copied from the X-Request-ID response header
 }
 
 // Error implements the error interface
@@ -47,9 +50,13 @@ var (
 	ErrInternal   = &APIError{Code: http.StatusInternalServerError, Message: "Server error"}
 )
 
-// WriteResponse writes the error as an HTTP response
+// WriteResponse writes the error as an HTTP response with the request ID set
+// by requestid.Middleware. Predefined errors are shared, so a copy is written.
 func (e *APIError) WriteResponse(w http.ResponseWriter) {
+	response := *e
+	response.RequestID = w.Header().Get(requestid.Header)
+
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(e.Code)
-	json.NewEncoder(w).Encode(e)
+	w.WriteHeader(response.Code)
+	json.NewEncoder(w).Encode(response)
 }
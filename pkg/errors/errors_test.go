@@ -10,8 +10,12 @@
 package errors
 
 import (
+	"encoding/json"
 	"net/http"
+	"net/http/httptest"
 	"testing"
+
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/requestid"
 )
 
 // TestNewAPIError tests the creation of a new API error
@@ -83,3 +87,31 @@ func TestAPIErrorImplementsError(t *testing.T) {
 		t.Errorf("Error() should return the message, got %s", apiErr.Error())
 	}
 }
+
+// TestWriteResponse tests that responses carry the request ID without
+// changing the shared predefined errors
+func TestWriteResponse(t *testing.T) {
+	w := httptest.NewRecorder()
+	w.Header().Set(requestid.Header, "req-1")
+	ErrInternal.WriteResponse(w)
+
+	var body APIError
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if w.Code != http.StatusInternalServerError || body.RequestID != "req-1" {
+		t.Errorf("Expected a 500 with the request ID, got %d %+v", w.Code, body)
+	}
+	if ErrInternal.RequestID != "" {
+		t.Errorf("Expected the predefined error to be unchanged, got %q", ErrInternal.RequestID)
+	}
+
+	// Without the middleware the ID is omitted
+	w = httptest.NewRecorder()
+	ErrBadRequest.WriteResponse(w)
+	var raw map[string]interface{}
+	json.NewDecoder(w.Body).Decode(&raw)
+	if _, ok := raw["request_id"]; ok {
+		t.Errorf("Expected no request_id field, got %v", raw)
+	}
+}
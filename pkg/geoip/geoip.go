@@ -0,0 +1,169 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package geoip
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Resolver resolves the ISO 3166 country code of a client address
+type Resolver interface {
+	Country(addr netip.Addr) string
+}
+
+// ipRange is a network of the database with its country
+type ipRange struct {
+	start   netip.Addr
+	end     netip.Addr
+	country string
+	// parent is the index of the smallest network containing this one, or -1
+	parent int
+}
+
+// DB is an in-memory country database built from CIDR networks.
+// Lookups are a binary search over the sorted networks; nested networks
+// resolve to the most specific one.
+type DB struct {
+	v4 []ipRange
+	v6 []ipRange
+}
+
+// Load reads a CSV database from a file, see Parse for the format
+func Load(path string) (*DB, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open GeoIP database: %v", err)
+	}
+	defer file.Close()
+
+	return Parse(file)
+}
+
+// Parse reads a CSV database with one "network,country" row per line, e.g.
+// "81.169.128.0/17,DE". A header row and rows without a country are skipped.
+func Parse(r io.Reader) (*DB, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.Comment = '#'
+
+	db := &DB{}
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read GeoIP database: %v", err)
+		}
+		if len(record) < 2 {
+			return nil, fmt.Errorf("invalid GeoIP row on line %d", line)
+		}
+
+		prefix, err := netip.ParsePrefix(strings.TrimSpace(record[0]))
+		if err != nil {
+			if line == 1 {
+				// Header row
+				continue
+			}
+			return nil, fmt.Errorf("invalid network on line %d: %v", line, err)
+		}
+		country := strings.ToUpper(strings.TrimSpace(record[1]))
+		if country == "" {
+			continue
+		}
+
+		db.add(prefix, country)
+	}
+
+	db.sort()
+	return db, nil
+}
+
+// add inserts the network of prefix
+func (db *DB) add(prefix netip.Prefix, country string) {
+	prefix = prefix.Masked()
+	entry := ipRange{start: prefix.Addr(), end: lastAddr(prefix), country: country}
+	if entry.start.Is4() {
+		db.v4 = append(db.v4, entry)
+	} else {
+		db.v6 = append(db.v6, entry)
+	}
+}
+
+// sort orders the networks by their first address, enclosing networks
+// before the ones nested in them, and links every network to its parent
+func (db *DB) sort() {
+	for _, ranges := range [][]ipRange{db.v4, db.v6} {
+		sort.SliceStable(ranges, func(i, j int) bool {
+			if ranges[i].start != ranges[j].start {
+				return ranges[i].start.Less(ranges[j].start)
+			}
+			return ranges[j].end.Less(ranges[i].end)
+		})
+
+		// CIDR networks are either nested or disjoint, so the enclosing
+		// networks of the current one form a stack
+		var open []int
+		for i := range ranges {
+			for len(open) > 0 && ranges[open[len(open)-1]].end.Less(ranges[i].start) {
+				open = open[:len(open)-1]
+			}
+			ranges[i].parent = -1
+			if len(open) > 0 {
+				ranges[i].parent = open[len(open)-1]
+			}
+			open = append(open, i)
+		}
+	}
+}
+
+// Len returns the number of networks in the database
+func (db *DB) Len() int {
+	return len(db.v4) + len(db.v6)
+}
+
+// Country returns the country code of addr, or "" when it is unknown
+func (db *DB) Country(addr netip.Addr) string {
+	addr = addr.Unmap()
+	ranges := db.v6
+	if addr.Is4() {
+		ranges = db.v4
+	}
+
+	// Last network starting at or before addr. When addr lies past its end,
+	// only networks enclosing it can still contain addr.
+	i := sort.Search(len(ranges), func(i int) bool {
+		return addr.Less(ranges[i].start)
+	}) - 1
+	for i >= 0 && ranges[i].end.Less(addr) {
+		i = ranges[i].parent
+	}
+	if i < 0 {
+		return ""
+	}
+	return ranges[i].country
+}
+
+// lastAddr returns the last address of a masked prefix
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	bytes := prefix.Addr().AsSlice()
+	for bit := prefix.Bits(); bit < len(bytes)*8; bit++ {
+		bytes[bit/8] |= 1 << (7 - bit%8)
+	}
+	addr, _ := netip.AddrFromSlice(bytes)
+	return addr
+}
@@ -0,0 +1,128 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package geoip
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testDB = `network,country_iso_code
+# Documentation ranges
+192.0.2.0/24,DE
+198.51.100.0/25,US
+198.51.100.128/25,FR
+203.0.113.0/24,
+2001:db8::/32,NL
+`
+
+func TestParse(t *testing.T) {
+	db, err := Parse(strings.NewReader(testDB))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if db.Len() != 4 {
+		t.Errorf("Expected 4 networks, got %d", db.Len())
+	}
+
+	testCases := []struct {
+		name     string
+		ip       string
+		expected string
+	}{
+		{name: "First Address", ip: "192.0.2.0", expected: "DE"},
+		{name: "Last Address", ip: "192.0.2.255", expected: "DE"},
+		{name: "Lower Half", ip: "198.51.100.127", expected: "US"},
+		{name: "Upper Half", ip: "198.51.100.128", expected: "FR"},
+		{name: "Gap Between Networks", ip: "198.51.101.1", expected: ""},
+		{name: "Network Without Country", ip: "203.0.113.7", expected: ""},
+		{name: "Before First Network", ip: "10.0.0.1", expected: ""},
+		{name: "IPv4 Mapped", ip: "::ffff:192.0.2.10", expected: "DE"},
+		{name: "IPv6", ip: "2001:db8:1::1", expected: "NL"},
+		{name: "Unknown IPv6", ip: "2001:db9::1", expected: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if country := db.Country(netip.MustParseAddr(tc.ip)); country != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, country)
+			}
+		})
+	}
+}
+
+func TestParse_NestedNetworks(t *testing.T) {
+	// Rows are out of order on purpose, nested networks win over enclosing ones
+	db, err := Parse(strings.NewReader(`network,country_iso_code
+10.1.0.0/16,DE
+10.0.0.0/8,US
+10.1.2.0/24,FR
+10.200.0.0/16,NL
+2001:db8:1::/48,AT
+2001:db8::/32,CH
+`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		ip       string
+		expected string
+	}{
+		{name: "Outer Network Before Nested", ip: "10.0.0.1", expected: "US"},
+		{name: "Nested Network", ip: "10.1.0.1", expected: "DE"},
+		{name: "Doubly Nested Network", ip: "10.1.2.3", expected: "FR"},
+		{name: "Back In Nested Network", ip: "10.1.3.1", expected: "DE"},
+		{name: "Back In Outer Network", ip: "10.2.0.1", expected: "US"},
+		{name: "Second Nested Network", ip: "10.200.0.1", expected: "NL"},
+		{name: "After Last Nested Network", ip: "10.255.255.255", expected: "US"},
+		{name: "Outside", ip: "11.0.0.1", expected: ""},
+		{name: "Nested IPv6", ip: "2001:db8:1::1", expected: "AT"},
+		{name: "Outer IPv6", ip: "2001:db8:2::1", expected: "CH"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if country := db.Country(netip.MustParseAddr(tc.ip)); country != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, country)
+			}
+		})
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	if _, err := Parse(strings.NewReader("192.0.2.0/24,DE\nnot-a-network,US\n")); err == nil {
+		t.Errorf("Expected error for an invalid network")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "geoip.csv")
+	if err := os.WriteFile(path, []byte(testDB), 0o600); err != nil {
+		t.Fatalf("Failed to write database: %v", err)
+	}
+
+	db, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if country := db.Country(netip.MustParseAddr("192.0.2.1")); country != "DE" {
+		t.Errorf("Expected DE, got %q", country)
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.csv")); err == nil {
+		t.Errorf("Expected error for a missing file")
+	}
+}
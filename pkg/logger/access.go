@@ -0,0 +1,52 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package logger
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.uber.org/zap"
+)
+
+// AccessLog writes one entry per request with its method, route, status,
+// size and latency. It reads the route after routing, so it must wrap the
+// chi router, after Middleware and requestid.Middleware.
+func (l *Logger) AccessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		fields := []zap.Field{
+			zap.String("method", r.Method),
+			zap.Int("status", status),
+			zap.Int("bytes", ww.BytesWritten()),
+			zap.Duration("latency", time.Since(start)),
+		}
+		// The path is not logged, only the short ID it names
+		if shortID := chi.URLParam(r, "shortened"); shortID != "" {
+			fields = append(fields, zap.String("short_id", shortID))
+		}
+
+		log := l.FromContext(r.Context())
+		if status >= http.StatusInternalServerError {
+			log.Warn("Request", fields...)
+			return
+		}
+		log.Info("Request", fields...)
+	})
+}
@@ -0,0 +1,116 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package logger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/requestid"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestAccessLog(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	redactor, err := NewRedactor(RedactionConfig{IPMode: "truncate"})
+	if err != nil {
+		t.Fatalf("Failed to create redactor: %v", err)
+	}
+	log := &Logger{Logger: zap.New(core), redactor: redactor}
+
+	r := chi.NewRouter()
+	r.Use(requestid.Middleware)
+	r.Use(Middleware)
+	r.Use(log.AccessLog)
+	r.Get("/{shortened}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	})
+	r.Get("/fail", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	testCases := []struct {
+		name     string
+		path     string
+		level    zapcore.Level
+		expected map[string]interface{}
+	}{
+		{
+			name:  "Redirect",
+			path:  "/abc123?email=jane@example.com",
+			level: zap.InfoLevel,
+			expected: map[string]interface{}{
+				"method":     "GET",
+				"route":      "/{shortened}",
+				"status":     int64(200),
+				"bytes":      int64(5),
+				"short_id":   "abc123",
+				"client_ip":  "203.0.113.0",
+				"request_id": "req-1",
+			},
+		},
+		{
+			name:  "Server Error",
+			path:  "/fail",
+			level: zap.WarnLevel,
+			expected: map[string]interface{}{
+				"route":  "/fail",
+				"status": int64(503),
+				"bytes":  int64(0),
+			},
+		},
+		{
+			name:  "Unmatched Route",
+			path:  "/a/b/c",
+			level: zap.InfoLevel,
+			expected: map[string]interface{}{
+				"status": int64(404),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			logs.TakeAll()
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			req.Header.Set(requestid.Header, "req-1")
+			req.Header.Set("X-Forwarded-For", "203.0.113.7")
+			r.ServeHTTP(httptest.NewRecorder(), req)
+
+			entries := logs.TakeAll()
+			if len(entries) != 1 {
+				t.Fatalf("Expected 1 access log entry, got %d", len(entries))
+			}
+			if entries[0].Level != tc.level {
+				t.Errorf("Expected level %s, got %s", tc.level, entries[0].Level)
+			}
+			fields := entries[0].ContextMap()
+			for key, value := range tc.expected {
+				if fields[key] != value {
+					t.Errorf("Expected %s=%v, got %v", key, value, fields[key])
+				}
+			}
+			if _, ok := fields["latency"]; !ok {
+				t.Error("Expected the latency to be logged")
+			}
+			// Query strings are never logged
+			for key, value := range fields {
+				if s, ok := value.(string); ok && strings.Contains(s, "email") {
+					t.Errorf("Expected the query not to be logged, got %s=%s", key, s)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package logger
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/privacy"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/requestid"
+	"go.uber.org/zap"
+)
+
+type clientIPKey struct{}
+
+// Middleware stores the client IP in the request context for FromContext
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), clientIPKey{}, privacy.ClientIP(r))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// FromContext returns a child logger with the request ID, route and redacted client IP
+// of the request in ctx, so all entries of a request can be correlated
+func (l *Logger) FromContext(ctx context.Context) *zap.Logger {
+	var fields []zap.Field
+	if id := requestid.FromContext(ctx); id != "" {
+		fields = append(fields, zap.String("request_id", id))
+	}
+	if rctx := chi.RouteContext(ctx); rctx != nil && rctx.RoutePattern() != "" {
+		fields = append(fields, zap.String("route", rctx.RoutePattern()))
+	}
+	if ip, ok := ctx.Value(clientIPKey{}).(string); ok {
+		fields = append(fields, zap.String("client_ip", l.RedactIP(ip)))
+	}
+
+	if len(fields) == 0 {
+		return l.Logger
+	}
+	return l.With(fields...)
+}
@@ -0,0 +1,60 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package logger
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/requestid"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestFromContext(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	log := &Logger{Logger: zap.New(core)}
+
+	r := chi.NewRouter()
+	r.Use(requestid.Middleware)
+	r.Use(Middleware)
+	r.Get("/{shortened}", func(w http.ResponseWriter, r *http.Request) {
+		log.FromContext(r.Context()).Info("Redirect attempt")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/abc123", nil)
+	req.Header.Set(requestid.Header, "req-1")
+	req.Header.Set("X-Forwarded-For", "203.0.113.7")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	entries := logs.All()
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 log entry, got %d", len(entries))
+	}
+	fields := entries[0].ContextMap()
+	expected := map[string]string{
+		"request_id": "req-1",
+		"route":      "/{shortened}",
+		"client_ip":  "203.0.113.7",
+	}
+	for key, value := range expected {
+		if fields[key] != value {
+			t.Errorf("Expected %s=%q, got %v", key, value, fields[key])
+		}
+	}
+
+	// Outside of requests the logger is returned unchanged
+	if log.FromContext(context.Background()) != log.Logger {
+		t.Error("Expected the base logger without request fields")
+	}
+}
@@ -10,56 +10,153 @@
 package logger
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
+	"path/filepath"
+	"time"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Log encodings
+const (
+	EncodingJSON    = "json"    // One JSON object per entry
+	EncodingConsole = "console" // Human readable, for local development
+)
+
+// Standard stream outputs, any other output is a file path
+const (
+	OutputStdout = "stdout"
+	OutputStderr = "stderr"
 )
 
 // Logger represents a structured logger
 type Logger struct {
 	*zap.Logger
+	level    zap.AtomicLevel
+	files    []io.Closer
+	redactor *Redactor
+}
+
+// Config selects the level, format and outputs of a logger
+type Config struct {
+	Level    string   // debug, info, warn, error or fatal
+	Encoding string   // See EncodingJSON and EncodingConsole
+	Outputs  []string // See OutputStdout and OutputStderr, other outputs are files
+
+	// Sampling keeps the first SamplingInitial entries with the same level and
+	// message per second and every SamplingThereafter-th after that.
+	// 0 disables sampling.
+	SamplingInitial    int
+	SamplingThereafter int
+
+	Rotation  RotationConfig  // Applies to file outputs
+	Redaction RedactionConfig // Applies to URLs, client IPs and access logs
+}
+
+// RotationConfig controls the rotation of log files
+type RotationConfig struct {
+	MaxSizeMB  int  // Size in megabytes at which a file is rotated
+	MaxAgeDays int  // Days rotated files are kept, 0 keeps them regardless of age
+	MaxBackups int  // Rotated files kept, 0 keeps all
+	Compress   bool // Gzip rotated files
 }
 
 // New creates a new configured logger
-func New(level string) (*Logger, error) {
-	// Determine log level
-	logLevel := getLogLevel(level)
-
-	// Logger configuration
-	config := zap.Config{
-		Level:    zap.NewAtomicLevelAt(logLevel),
-		Encoding: "json",
-		EncoderConfig: zapcore.EncoderConfig{
-			TimeKey:        "timestamp",
-			LevelKey:       "level",
-			NameKey:        "logger",
-			CallerKey:      "caller",
-			MessageKey:     "message",
-			StacktraceKey:  "stacktrace",
-			LineEnding:     zapcore.DefaultLineEnding,
-			EncodeLevel:    zapcore.LowercaseLevelEncoder,
-			EncodeTime:     zapcore.ISO8601TimeEncoder,
-			EncodeDuration: zapcore.SecondsDurationEncoder,
-			EncodeCaller:   zapcore.ShortCallerEncoder,
-		},
-		OutputPaths:      []string{"stdout", "./logs/app.log"},
-		ErrorOutputPaths: []string{"stderr", "./logs/error.log"},
+func New(cfg Config) (*Logger, error) {
+	level := zap.NewAtomicLevelAt(getLogLevel(cfg.Level))
+
+	encoder, err := newEncoder(cfg.Encoding)
+	if err != nil {
+		return nil, err
 	}
 
-	// Build logger
-	zapLogger, err := config.Build(zap.AddCaller(), zap.AddStacktrace(zap.ErrorLevel))
+	redactor, err := NewRedactor(cfg.Redaction)
 	if err != nil {
 		return nil, err
 	}
 
-	// Create logs directory
-	if err := os.MkdirAll("./logs", os.ModePerm); err != nil {
-		return nil, fmt.Errorf("failed to create log directory: %v", err)
+	if len(cfg.Outputs) == 0 {
+		return nil, errors.New("at least one log output is required")
+	}
+	l := &Logger{level: level, redactor: redactor}
+	syncers := make([]zapcore.WriteSyncer, 0, len(cfg.Outputs))
+	for _, output := range cfg.Outputs {
+		syncer, err := l.openOutput(output, cfg.Rotation)
+		if err != nil {
+			l.Close()
+			return nil, err
+		}
+		syncers = append(syncers, syncer)
+	}
+
+	core := zapcore.NewCore(encoder, zapcore.NewMultiWriteSyncer(syncers...), level)
+	if cfg.SamplingInitial > 0 {
+		core = zapcore.NewSamplerWithOptions(core, time.Second, cfg.SamplingInitial, cfg.SamplingThereafter)
+	}
+
+	l.Logger = zap.New(core,
+		zap.AddCaller(),
+		zap.AddStacktrace(zap.ErrorLevel),
+		zap.ErrorOutput(zapcore.Lock(os.Stderr)),
+	)
+	return l, nil
+}
+
+// newEncoder creates the encoder of an encoding, JSON when empty
+func newEncoder(encoding string) (zapcore.Encoder, error) {
+	encoderConfig := zapcore.EncoderConfig{
+		TimeKey:        "timestamp",
+		LevelKey:       "level",
+		NameKey:        "logger",
+		CallerKey:      "caller",
+		MessageKey:     "message",
+		StacktraceKey:  "stacktrace",
+		LineEnding:     zapcore.DefaultLineEnding,
+		EncodeLevel:    zapcore.LowercaseLevelEncoder,
+		EncodeTime:     zapcore.ISO8601TimeEncoder,
+		EncodeDuration: zapcore.SecondsDurationEncoder,
+		EncodeCaller:   zapcore.ShortCallerEncoder,
 	}
 
-	return &Logger{zapLogger}, nil
+	switch encoding {
+	case EncodingJSON, "":
+		return zapcore.NewJSONEncoder(encoderConfig), nil
+	case EncodingConsole:
+		encoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
+		return zapcore.NewConsoleEncoder(encoderConfig), nil
+	default:
+		return nil, fmt.Errorf("unknown log encoding %q", encoding)
+	}
+}
+
+// openOutput opens a standard stream or a rotated log file
+func (l *Logger) openOutput(output string, rotation RotationConfig) (zapcore.WriteSyncer, error) {
+	switch output {
+	case OutputStdout:
+		return zapcore.Lock(os.Stdout), nil
+	case OutputStderr:
+		return zapcore.Lock(os.Stderr), nil
+	}
+
+	// Create the log directory before the first write
+	if err := os.MkdirAll(filepath.Dir(output), os.ModePerm); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %v", err)
+	}
+	file := &lumberjack.Logger{
+		Filename:   output,
+		MaxSize:    rotation.MaxSizeMB,
+		MaxAge:     rotation.MaxAgeDays,
+		MaxBackups: rotation.MaxBackups,
+		Compress:   rotation.Compress,
+	}
+	l.files = append(l.files, file)
+	return zapcore.AddSync(file), nil
 }
 
 // getLogLevel converts string to zap log level
@@ -80,6 +177,35 @@ func getLogLevel(level string) zapcore.Level {
 	}
 }
 
+// LevelHandler serves the current level on GET and changes it on PUT with a
+// body like {"level":"debug"}, without restarting the server
+func (l *Logger) LevelHandler() http.Handler {
+	return l.level
+}
+
+// Close flushes buffered entries and closes the log files
+func (l *Logger) Close() error {
+	if l.Logger != nil {
+		// Syncing a terminal fails on some platforms, which is harmless
+		l.Sync()
+	}
+	var errs []error
+	for _, file := range l.files {
+		errs = append(errs, file.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// RedactURL masks the query values and credentials of a URL before it is logged
+func (l *Logger) RedactURL(raw string) string {
+	return l.redactor.URL(raw)
+}
+
+// RedactIP anonymizes a client IP before it is logged
+func (l *Logger) RedactIP(ip string) string {
+	return l.redactor.IP(ip)
+}
+
 // WithFields adds additional fields to the logger
 func (l *Logger) WithFields(fields map[string]interface{}) *zap.Logger {
 	var zapFields []zap.Field
@@ -91,35 +217,5 @@ func (l *Logger) WithFields(fields map[string]interface{}) *zap.Logger {
 
 // NewTestLogger creates a new logger for testing purposes
 func NewTestLogger() (*Logger, error) {
-	// Create logs directory
-	if err := os.MkdirAll("./logs", os.ModePerm); err != nil {
-		return nil, err
-	}
-
-	config := zap.Config{
-		Level:    zap.NewAtomicLevelAt(zap.InfoLevel),
-		Encoding: "json",
-		EncoderConfig: zapcore.EncoderConfig{
-			TimeKey:        "timestamp",
-			LevelKey:       "level",
-			NameKey:        "logger",
-			CallerKey:      "caller",
-			MessageKey:     "message",
-			StacktraceKey:  "stacktrace",
-			LineEnding:     zapcore.DefaultLineEnding,
-			EncodeLevel:    zapcore.LowercaseLevelEncoder,
-			EncodeTime:     zapcore.ISO8601TimeEncoder,
-			EncodeDuration: zapcore.SecondsDurationEncoder,
-			EncodeCaller:   zapcore.ShortCallerEncoder,
-		},
-		OutputPaths:      []string{"stdout"},
-		ErrorOutputPaths: []string{"stderr"},
-	}
-
-	zapLogger, err := config.Build(zap.AddCaller(), zap.AddStacktrace(zap.ErrorLevel))
-	if err != nil {
-		return nil, err
-	}
-
-	return &Logger{zapLogger}, nil
+	return New(Config{Level: "info", Outputs: []string{OutputStdout}})
 }
@@ -0,0 +1,112 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package logger
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNew_FileOutput(t *testing.T) {
+	testCases := []struct {
+		name     string
+		encoding string
+		check    func(t *testing.T, line string)
+	}{
+		{
+			name:     "JSON",
+			encoding: EncodingJSON,
+			check: func(t *testing.T, line string) {
+				var entry map[string]interface{}
+				if err := json.Unmarshal([]byte(line), &entry); err != nil {
+					t.Fatalf("Expected a JSON entry, got %q", line)
+				}
+				if entry["message"] != "hello" || entry["level"] != "info" {
+					t.Errorf("Unexpected entry %v", entry)
+				}
+			},
+		},
+		{
+			name:     "Console",
+			encoding: EncodingConsole,
+			check: func(t *testing.T, line string) {
+				if !strings.Contains(line, "INFO") || !strings.Contains(line, "hello") {
+					t.Errorf("Expected a console entry, got %q", line)
+				}
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// The directory does not exist yet
+			path := filepath.Join(t.TempDir(), "nested", "app.log")
+			l, err := New(Config{
+				Level:    "info",
+				Encoding: tc.encoding,
+				Outputs:  []string{path},
+				Rotation: RotationConfig{MaxSizeMB: 1, MaxBackups: 1},
+			})
+			if err != nil {
+				t.Fatalf("New failed: %v", err)
+			}
+			l.Debug("filtered")
+			l.Info("hello")
+			if err := l.Close(); err != nil {
+				t.Fatalf("Close failed: %v", err)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Failed to read log file: %v", err)
+			}
+			lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+			if len(lines) != 1 {
+				t.Fatalf("Expected 1 entry above the level, got %d", len(lines))
+			}
+			tc.check(t, lines[0])
+		})
+	}
+}
+
+func TestNew_Sampling(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	l, err := New(Config{
+		Level:              "info",
+		Outputs:            []string{path},
+		SamplingInitial:    2,
+		SamplingThereafter: 5,
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	for i := 0; i < 12; i++ {
+		l.Info("repeated")
+	}
+	l.Close()
+
+	data, _ := os.ReadFile(path)
+	// The first 2 entries, then the 5th and 10th of the remaining 10
+	if count := strings.Count(string(data), "repeated"); count != 4 {
+		t.Errorf("Expected 4 sampled entries, got %d", count)
+	}
+}
+
+func TestNew_InvalidConfig(t *testing.T) {
+	if _, err := New(Config{Encoding: "logfmt", Outputs: []string{OutputStdout}}); err == nil {
+		t.Error("Expected an error for an unknown encoding")
+	}
+	if _, err := New(Config{}); err == nil {
+		t.Error("Expected an error without outputs")
+	}
+}
@@ -0,0 +1,99 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package logger
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/privacy"
+)
+
+// Redacted replaces masked values in logs
+const Redacted = "REDACTED"
+
+// Query parameters treated as credentials
+var sensitiveParams = []string{"token", "password", "passwd", "secret", "key", "signature", "sig", "auth", "code", "session"}
+
+// RedactionConfig selects what is masked before URLs and IPs are logged
+type RedactionConfig struct {
+	Query       bool   // Mask all query parameter values
+	Credentials bool   // Mask URL passwords and credential-like query parameters
+	IPMode      string // See privacy.ModeNone, privacy.ModeTruncate and privacy.ModeHash
+}
+
+// Redactor masks personal data and credentials before they are logged
+type Redactor struct {
+	query       bool
+	credentials bool
+	ips         *privacy.Anonymizer
+}
+
+// NewRedactor creates a redactor, IPs are logged as they are when IPMode is empty.
+// Hashed IPs use a random secret, so they only correlate within one process.
+func NewRedactor(cfg RedactionConfig) (*Redactor, error) {
+	mode := cfg.IPMode
+	if mode == "" {
+		mode = privacy.ModeNone
+	}
+	ips, err := privacy.NewAnonymizer(mode, "")
+	if err != nil {
+		return nil, err
+	}
+	return &Redactor{query: cfg.Query, credentials: cfg.Credentials, ips: ips}, nil
+}
+
+// URL returns raw with its query values and credentials masked
+func (r *Redactor) URL(raw string) string {
+	if r == nil || (!r.query && !r.credentials) {
+		return raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		// Never log what could not be inspected
+		return Redacted
+	}
+
+	if r.credentials && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), Redacted)
+		}
+	}
+	if u.RawQuery != "" && (r.query || r.credentials) {
+		query := u.Query()
+		for name := range query {
+			if r.query || (r.credentials && isSensitive(name)) {
+				query[name] = []string{Redacted}
+			}
+		}
+		u.RawQuery = query.Encode()
+	}
+	return u.String()
+}
+
+// IP returns ip anonymized according to the IP mode
+func (r *Redactor) IP(ip string) string {
+	if r == nil {
+		return ip
+	}
+	return r.ips.Anonymize(ip, time.Now())
+}
+
+// isSensitive reports whether a query parameter name looks like a credential
+func isSensitive(name string) bool {
+	name = strings.ToLower(name)
+	for _, param := range sensitiveParams {
+		if name == param || strings.HasSuffix(name, "_"+param) || strings.HasSuffix(name, "-"+param) {
+			return true
+		}
+	}
+	return false
+}
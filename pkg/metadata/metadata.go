@@ -0,0 +1,287 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package metadata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	defaultTimeout     = 5 * time.Second
+	defaultMaxBodySize = 512 << 10
+	maxRedirects       = 5
+
+	maxTitleLength       = 200
+	maxDescriptionLength = 500
+
+	userAgent = "go-url-shortener/metadata"
+)
+
+// ErrForbiddenAddress occurs when a page resolves to a private, loopback or
+// otherwise internal address
+var ErrForbiddenAddress = errors.New("address is not publicly routable")
+
+// Metadata describes the page behind a link
+type Metadata struct {
+	Title       string    `json:"title,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Favicon     string    `json:"favicon,omitempty"`
+	FetchedAt   time.Time `json:"fetched_at"`
+}
+
+// Fetcher downloads pages and extracts their metadata
+type Fetcher struct {
+	client      *http.Client
+	maxBodySize int64
+}
+
+// Option configures optional Fetcher behaviour
+type Option func(*Fetcher)
+
+// WithTimeout limits the time of a whole fetch including redirects
+func WithTimeout(timeout time.Duration) Option {
+	return func(f *Fetcher) {
+		f.client.Timeout = timeout
+	}
+}
+
+// WithMaxBodySize limits how many bytes of a page are read
+func WithMaxBodySize(size int64) Option {
+	return func(f *Fetcher) {
+		f.maxBodySize = size
+	}
+}
+
+// WithTransport replaces the default transport, e.g. with the one of an
+// httptest server. The address checks of the default transport do not apply.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(f *Fetcher) {
+		f.client.Transport = transport
+	}
+}
+
+// NewFetcher creates a fetcher that only connects to public addresses
+func NewFetcher(options ...Option) *Fetcher {
+	f := &Fetcher{
+		client: &http.Client{
+			Timeout:       defaultTimeout,
+			Transport:     publicTransport(),
+			CheckRedirect: checkRedirect,
+		},
+		maxBodySize: defaultMaxBodySize,
+	}
+	for _, opt := range options {
+		opt(f)
+	}
+	return f
+}
+
+// publicTransport dials only public addresses. The check runs on the resolved
+// address of every connection, so redirects and DNS rebinding are covered too.
+func publicTransport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout: defaultTimeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return fmt.Errorf("invalid address %q: %v", address, err)
+			}
+			if !PublicAddr(addrPort.Addr()) {
+				return fmt.Errorf("%s: %w", addrPort.Addr(), ErrForbiddenAddress)
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	transport.MaxIdleConns = 10
+	transport.ResponseHeaderTimeout = defaultTimeout
+	return transport
+}
+
+// PublicAddr reports whether addr is a globally routable unicast address
+func PublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsGlobalUnicast() &&
+		!addr.IsPrivate() &&
+		!addr.IsLoopback() &&
+		!addr.IsLinkLocalUnicast() &&
+		!sharedAddressSpace.Contains(addr)
+}
+
+// sharedAddressSpace is the carrier-grade NAT range (RFC 6598)
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// checkRedirect limits redirects and keeps them on http and https
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+	return checkScheme(req.URL)
+}
+
+// checkScheme only allows web pages
+func checkScheme(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	return nil
+}
+
+// Fetch downloads the page at rawURL and extracts its metadata
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (*Metadata, error) {
+	pageURL, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %v", err)
+	}
+	if err := checkScheme(pageURL); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch page: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return nil, fmt.Errorf("unsupported content type %q", mediaType)
+	}
+
+	// Relative favicons resolve against the final URL after redirects
+	meta := Parse(io.LimitReader(resp.Body, f.maxBodySize), resp.Request.URL)
+	meta.FetchedAt = time.Now().UTC()
+	return meta, nil
+}
+
+// Parse extracts the metadata of an HTML document. Only the head is read,
+// parsing stops at the body.
+func Parse(r io.Reader, base *url.URL) *Metadata {
+	meta := &Metadata{}
+	var ogTitle, ogDescription, favicon string
+
+	tokenizer := html.NewTokenizer(r)
+	inTitle := false
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return finish(meta, ogTitle, ogDescription, favicon, base)
+		case html.TextToken:
+			if inTitle && meta.Title == "" {
+				meta.Title = string(tokenizer.Text())
+			}
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			if string(name) == "title" {
+				inTitle = false
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := tokenizer.TagName()
+			switch string(name) {
+			case "body":
+				return finish(meta, ogTitle, ogDescription, favicon, base)
+			case "title":
+				inTitle = true
+			case "meta":
+				attrs := attributes(tokenizer, hasAttr)
+				key := strings.ToLower(attrs["name"])
+				if key == "" {
+					key = strings.ToLower(attrs["property"])
+				}
+				switch key {
+				case "description":
+					meta.Description = attrs["content"]
+				case "og:title":
+					ogTitle = attrs["content"]
+				case "og:description":
+					ogDescription = attrs["content"]
+				}
+			case "link":
+				attrs := attributes(tokenizer, hasAttr)
+				for _, rel := range strings.Fields(strings.ToLower(attrs["rel"])) {
+					if rel == "icon" && favicon == "" {
+						favicon = attrs["href"]
+					}
+				}
+			}
+		}
+	}
+}
+
+// attributes returns the attributes of the current tag
+func attributes(tokenizer *html.Tokenizer, hasAttr bool) map[string]string {
+	attrs := make(map[string]string)
+	for hasAttr {
+		var key, value []byte
+		key, value, hasAttr = tokenizer.TagAttr()
+		attrs[string(key)] = string(value)
+	}
+	return attrs
+}
+
+// finish applies the Open Graph fallbacks and resolves the favicon
+func finish(meta *Metadata, ogTitle, ogDescription, favicon string, base *url.URL) *Metadata {
+	if strings.TrimSpace(meta.Title) == "" {
+		meta.Title = ogTitle
+	}
+	if strings.TrimSpace(meta.Description) == "" {
+		meta.Description = ogDescription
+	}
+	meta.Title = truncate(meta.Title, maxTitleLength)
+	meta.Description = truncate(meta.Description, maxDescriptionLength)
+
+	// Browsers fall back to /favicon.ico as well
+	if favicon == "" {
+		favicon = "/favicon.ico"
+	}
+	if base != nil {
+		if ref, err := url.Parse(strings.TrimSpace(favicon)); err == nil {
+			if icon := base.ResolveReference(ref); checkScheme(icon) == nil {
+				meta.Favicon = icon.String()
+			}
+		}
+	}
+	return meta
+}
+
+// truncate collapses whitespace and cuts text to at most max runes
+func truncate(text string, max int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	runes := []rune(text)
+	if len(runes) > max {
+		return string(runes[:max])
+	}
+	return text
+}
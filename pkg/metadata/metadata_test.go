@@ -0,0 +1,196 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package metadata
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestParse tests extracting metadata from HTML documents
+func TestParse(t *testing.T) {
+	base, _ := url.Parse("https://example.com/blog/post")
+
+	testCases := []struct {
+		name        string
+		document    string
+		title       string
+		description string
+		favicon     string
+	}{
+		{
+			name: "Standard Tags",
+			document: `<html><head><title>Hello &amp; Welcome</title>
+				<meta name="description" content="A post">
+				<link rel="shortcut icon" href="/static/icon.png"></head></html>`,
+			title:       "Hello & Welcome",
+			description: "A post",
+			favicon:     "https://example.com/static/icon.png",
+		},
+		{
+			name: "Open Graph Fallback",
+			document: `<head><meta property="og:title" content="OG Title">
+				<meta property="og:description" content="OG Description"></head>`,
+			title:       "OG Title",
+			description: "OG Description",
+			favicon:     "https://example.com/favicon.ico",
+		},
+		{
+			name:     "Relative Favicon",
+			document: `<head><title>  Spaced   Title </title><link rel="icon" href="icon.svg"></head>`,
+			title:    "Spaced Title",
+			favicon:  "https://example.com/blog/icon.svg",
+		},
+		{
+			name:     "Body Is Ignored",
+			document: `<head></head><body><title>Not A Title</title></body>`,
+			favicon:  "https://example.com/favicon.ico",
+		},
+		{
+			name:     "Script Favicon Rejected",
+			document: `<head><link rel="icon" href="javascript:alert(1)"></head>`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			meta := Parse(strings.NewReader(tc.document), base)
+			if meta.Title != tc.title {
+				t.Errorf("Expected title %q, got %q", tc.title, meta.Title)
+			}
+			if meta.Description != tc.description {
+				t.Errorf("Expected description %q, got %q", tc.description, meta.Description)
+			}
+			if meta.Favicon != tc.favicon {
+				t.Errorf("Expected favicon %q, got %q", tc.favicon, meta.Favicon)
+			}
+		})
+	}
+}
+
+// TestFetch tests fetching metadata from a page
+func TestFetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/moved":
+			http.Redirect(w, r, "/docs/page", http.StatusFound)
+		case "/docs/page":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte(`<title>Docs</title><link rel="icon" href="favicon.png">`))
+		case "/large":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<!--" + strings.Repeat("x", 4096) + "--><title>Too Late</title>"))
+		case "/image":
+			w.Header().Set("Content-Type", "image/png")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	fetcher := NewFetcher(WithTransport(server.Client().Transport), WithMaxBodySize(1024))
+	ctx := context.Background()
+
+	meta, err := fetcher.Fetch(ctx, server.URL+"/moved")
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if meta.Title != "Docs" {
+		t.Errorf("Expected title Docs, got %q", meta.Title)
+	}
+	if meta.Favicon != server.URL+"/docs/favicon.png" {
+		t.Errorf("Expected favicon resolved against the redirect target, got %q", meta.Favicon)
+	}
+	if meta.FetchedAt.IsZero() {
+		t.Error("FetchedAt should not be zero")
+	}
+
+	meta, err = fetcher.Fetch(ctx, server.URL+"/large")
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if meta.Title != "" {
+		t.Errorf("Expected the body to be cut at the size limit, got title %q", meta.Title)
+	}
+
+	for _, path := range []string{"/image", "/missing"} {
+		if _, err := fetcher.Fetch(ctx, server.URL+path); err == nil {
+			t.Errorf("Expected an error for %s", path)
+		}
+	}
+	if _, err := fetcher.Fetch(ctx, "file:///etc/passwd"); err == nil {
+		t.Error("Expected an error for a file URL")
+	}
+}
+
+// TestFetchTimeout tests that slow pages are abandoned
+func TestFetchTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	fetcher := NewFetcher(WithTransport(server.Client().Transport), WithTimeout(50*time.Millisecond))
+	if _, err := fetcher.Fetch(context.Background(), server.URL); err == nil {
+		t.Error("Expected a timeout error")
+	}
+}
+
+// TestFetchPrivateAddress tests that the default transport refuses internal addresses
+func TestFetchPrivateAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Request should not reach a loopback server")
+	}))
+	defer server.Close()
+
+	_, err := NewFetcher().Fetch(context.Background(), server.URL)
+	if !errors.Is(err, ErrForbiddenAddress) {
+		t.Errorf("Expected ErrForbiddenAddress, got %v", err)
+	}
+}
+
+// TestPublicAddr tests classifying addresses
+func TestPublicAddr(t *testing.T) {
+	testCases := []struct {
+		addr     string
+		expected bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"::1", false},
+		{"fd00::1", false},
+		{"fe80::1", false},
+		{"::ffff:127.0.0.1", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.addr, func(t *testing.T) {
+			if got := PublicAddr(netip.MustParseAddr(tc.addr)); got != tc.expected {
+				t.Errorf("Expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/redis/go-redis/v9"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/analytics"
+)
+
+const namespace = "url_shortener"
+
+// Redirect results
+const (
+	RedirectHit  = "hit"  // The link was found and served
+	RedirectMiss = "miss" // The link does not exist, expired or is used up
+)
+
+// Link cache lookup results
+const (
+	CacheHit         = "hit"          // The link was served from the cache
+	CacheNegativeHit = "negative_hit" // The cache knows the short ID does not exist
+	CacheMiss        = "miss"         // The link was read from Redis
+)
+
+// unmatchedRoute labels requests no route matched, so unknown paths do not
+// create new series
+const unmatchedRoute = "unmatched"
+
+// Metrics holds the Prometheus collectors of the service.
+// The recording methods are safe to call on a nil *Metrics, which records nothing.
+type Metrics struct {
+	registry     *prometheus.Registry
+	requests     *prometheus.CounterVec
+	duration     *prometheus.HistogramVec
+	redirects    *prometheus.CounterVec
+	idCollisions prometheus.Counter
+	rateLimited  *prometheus.CounterVec
+	cacheLookups *prometheus.CounterVec
+}
+
+// New creates the collectors on a dedicated registry together with the Go
+// runtime and process collectors
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by method, route pattern and status code.",
+		}, []string{"method", "route", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by method and route pattern.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		redirects: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "redirects_total",
+			Help:      "Short link lookups by result, hit or miss.",
+		}, []string{"result"}),
+		idCollisions: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "short_id_collisions_total",
+			Help:      "Generated short IDs that already existed and were retried.",
+		}),
+		rateLimited: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rate_limited_total",
+			Help:      "Requests rejected by a rate limiter.",
+		}, []string{"limiter"}),
+		cacheLookups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "link_cache_lookups_total",
+			Help:      "Link cache lookups by result, hit, negative_hit or miss.",
+		}, []string{"result"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.duration,
+		m.redirects,
+		m.idCollisions,
+		m.rateLimited,
+		m.cacheLookups,
+	)
+	return m
+}
+
+// Registry returns the registry the collectors are registered with
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Middleware counts and times requests per chi route pattern.
+// It reads the pattern after routing, so it must wrap the chi router.
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	if m == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		route := unmatchedRoute
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		m.requests.WithLabelValues(r.Method, route, strconv.Itoa(status)).Inc()
+		m.duration.WithLabelValues(r.Method, route).Observe(time.Since(start).Seconds())
+	})
+}
+
+// Redirect counts a short link lookup, see RedirectHit and RedirectMiss
+func (m *Metrics) Redirect(result string) {
+	if m == nil {
+		return
+	}
+	m.redirects.WithLabelValues(result).Inc()
+}
+
+// IDCollision counts a generated short ID that was already taken
+func (m *Metrics) IDCollision() {
+	if m == nil {
+		return
+	}
+	m.idCollisions.Inc()
+}
+
+// CacheLookup counts a link cache lookup, see CacheHit, CacheNegativeHit and CacheMiss
+func (m *Metrics) CacheLookup(result string) {
+	if m == nil {
+		return
+	}
+	m.cacheLookups.WithLabelValues(result).Inc()
+}
+
+// RateLimited counts a request rejected by the named limiter
+func (m *Metrics) RateLimited(limiter string) {
+	if m == nil {
+		return
+	}
+	m.rateLimited.WithLabelValues(limiter).Inc()
+}
+
+// RegisterAnalytics exposes the counters and queue depth of the analytics pipeline
+func (m *Metrics) RegisterAnalytics(stats func() analytics.PipelineStats) {
+	if m == nil {
+		return
+	}
+	counter := func(name, help string, value func(analytics.PipelineStats) int64) prometheus.Collector {
+		return prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "analytics",
+			Name:      name,
+			Help:      help,
+		}, func() float64 { return float64(value(stats())) })
+	}
+	gauge := func(name, help string, value func(analytics.PipelineStats) int) prometheus.Collector {
+		return prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "analytics",
+			Name:      name,
+			Help:      help,
+		}, func() float64 { return float64(value(stats())) })
+	}
+
+	m.registry.MustRegister(
+		gauge("queue_depth", "Access events waiting in the queue.",
+			func(s analytics.PipelineStats) int { return s.QueueDepth }),
+		gauge("queue_capacity", "Maximum number of queued access events.",
+			func(s analytics.PipelineStats) int { return s.QueueCapacity }),
+		counter("events_enqueued_total", "Access events added to the queue.",
+			func(s analytics.PipelineStats) int64 { return s.Enqueued }),
+		counter("events_dropped_total", "Access events dropped because the queue was full.",
+			func(s analytics.PipelineStats) int64 { return s.Dropped }),
+		counter("events_processed_total", "Access events written to Redis.",
+			func(s analytics.PipelineStats) int64 { return s.Processed }),
+		counter("events_failed_total", "Access events that failed to be written.",
+			func(s analytics.PipelineStats) int64 { return s.Failed }),
+	)
+}
+
+// RegisterRedisPool exposes the connection pool statistics of a Redis client
+func (m *Metrics) RegisterRedisPool(stats func() *redis.PoolStats) {
+	if m == nil {
+		return
+	}
+	counter := func(name, help string, value func(*redis.PoolStats) uint32) prometheus.Collector {
+		return prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "redis_pool",
+			Name:      name,
+			Help:      help,
+		}, func() float64 { return float64(value(stats())) })
+	}
+	gauge := func(name, help string, value func(*redis.PoolStats) uint32) prometheus.Collector {
+		return prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "redis_pool",
+			Name:      name,
+			Help:      help,
+		}, func() float64 { return float64(value(stats())) })
+	}
+
+	m.registry.MustRegister(
+		counter("hits_total", "Free connections found in the pool.",
+			func(s *redis.PoolStats) uint32 { return s.Hits }),
+		counter("misses_total", "Free connections not found in the pool.",
+			func(s *redis.PoolStats) uint32 { return s.Misses }),
+		counter("timeouts_total", "Waits for a connection that timed out.",
+			func(s *redis.PoolStats) uint32 { return s.Timeouts }),
+		counter("stale_connections_total", "Stale connections removed from the pool.",
+			func(s *redis.PoolStats) uint32 { return s.StaleConns }),
+		gauge("connections", "Connections in the pool.",
+			func(s *redis.PoolStats) uint32 { return s.TotalConns }),
+		gauge("idle_connections", "Idle connections in the pool.",
+			func(s *redis.PoolStats) uint32 { return s.IdleConns }),
+	)
+}
@@ -0,0 +1,142 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/redis/go-redis/v9"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/analytics"
+)
+
+// TestMiddleware tests that requests are labelled with their route pattern
+func TestMiddleware(t *testing.T) {
+	m := New()
+
+	r := chi.NewRouter()
+	r.Use(m.Middleware)
+	r.Get("/{shortened}", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "https://example.com", http.StatusFound)
+	})
+	r.Post("/shorten", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	requests := []struct {
+		method string
+		path   string
+	}{
+		{http.MethodGet, "/abc123"},
+		{http.MethodGet, "/def456"},
+		{http.MethodPost, "/shorten"},
+		{http.MethodGet, "/a/b/c"},
+	}
+	for _, req := range requests {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(req.method, req.path, nil))
+	}
+
+	testCases := []struct {
+		method   string
+		route    string
+		status   string
+		expected float64
+	}{
+		{http.MethodGet, "/{shortened}", "302", 2},
+		{http.MethodPost, "/shorten", "200", 1},
+		{http.MethodGet, unmatchedRoute, "404", 1},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.route, func(t *testing.T) {
+			got := testutil.ToFloat64(m.requests.WithLabelValues(tc.method, tc.route, tc.status))
+			if got != tc.expected {
+				t.Errorf("Expected %v requests, got %v", tc.expected, got)
+			}
+		})
+	}
+
+	// Short IDs never become labels
+	if count := testutil.CollectAndCount(m.duration); count != 3 {
+		t.Errorf("Expected 3 latency series, got %d", count)
+	}
+}
+
+// TestCounters tests the application counters
+func TestCounters(t *testing.T) {
+	m := New()
+	m.Redirect(RedirectHit)
+	m.Redirect(RedirectHit)
+	m.Redirect(RedirectMiss)
+	m.IDCollision()
+	m.RateLimited("requests")
+	m.CacheLookup(CacheHit)
+
+	if got := testutil.ToFloat64(m.redirects.WithLabelValues(RedirectHit)); got != 2 {
+		t.Errorf("Expected 2 hits, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.redirects.WithLabelValues(RedirectMiss)); got != 1 {
+		t.Errorf("Expected 1 miss, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.idCollisions); got != 1 {
+		t.Errorf("Expected 1 collision, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.rateLimited.WithLabelValues("requests")); got != 1 {
+		t.Errorf("Expected 1 rejection, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.cacheLookups.WithLabelValues(CacheHit)); got != 1 {
+		t.Errorf("Expected 1 cache hit, got %v", got)
+	}
+
+	// A nil Metrics records nothing and does not panic
+	var disabled *Metrics
+	disabled.Redirect(RedirectHit)
+	disabled.IDCollision()
+	disabled.RateLimited("requests")
+	disabled.CacheLookup(CacheMiss)
+	disabled.RegisterAnalytics(nil)
+	disabled.RegisterRedisPool(nil)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	disabled.Middleware(next).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
+
+// TestHandler tests the exposition of registered collectors
+func TestHandler(t *testing.T) {
+	m := New()
+	m.RegisterAnalytics(func() analytics.PipelineStats {
+		return analytics.PipelineStats{QueueDepth: 7, QueueCapacity: 100, Dropped: 3}
+	})
+	m.RegisterRedisPool(func() *redis.PoolStats {
+		return &redis.PoolStats{Hits: 42, TotalConns: 5, IdleConns: 2}
+	})
+
+	rr := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, _ := io.ReadAll(rr.Body)
+
+	expected := []string{
+		"url_shortener_analytics_queue_depth 7",
+		"url_shortener_analytics_queue_capacity 100",
+		"url_shortener_analytics_events_dropped_total 3",
+		"url_shortener_redis_pool_hits_total 42",
+		"url_shortener_redis_pool_connections 5",
+		"url_shortener_redis_pool_idle_connections 2",
+		"go_goroutines",
+	}
+	for _, line := range expected {
+		if !strings.Contains(string(body), line) {
+			t.Errorf("Expected %q in metrics output", line)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package privacy
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+)
+
+// IP anonymization modes
+const (
+	ModeNone     = "none"     // Store client IPs as they are
+	ModeTruncate = "truncate" // Zero the host part: IPv4 /24, IPv6 /48
+	ModeHash     = "hash"     // HMAC with a salt that rotates every UTC day
+)
+
+// Anonymizer transforms client IPs before they are stored
+type Anonymizer struct {
+	mode   string
+	secret []byte
+}
+
+// NewAnonymizer creates an anonymizer for the mode.
+// In hash mode the daily salts are derived from secret; when it is empty a
+// random secret is generated, so hashes differ between processes.
+func NewAnonymizer(mode, secret string) (*Anonymizer, error) {
+	switch mode {
+	case ModeNone, ModeTruncate:
+		return &Anonymizer{mode: mode}, nil
+	case ModeHash:
+		key := []byte(secret)
+		if len(key) == 0 {
+			key = make([]byte, 32)
+			if _, err := rand.Read(key); err != nil {
+				return nil, fmt.Errorf("failed to generate IP hash secret: %v", err)
+			}
+		}
+		return &Anonymizer{mode: mode, secret: key}, nil
+	default:
+		return nil, fmt.Errorf("unknown IP anonymization mode %q", mode)
+	}
+}
+
+// Mode returns the anonymization mode
+func (a *Anonymizer) Mode() string {
+	return a.mode
+}
+
+// Anonymize returns the form of ip that may be stored for an access at the given time
+func (a *Anonymizer) Anonymize(ip string, at time.Time) string {
+	if ip == "" || a.mode == ModeNone {
+		return ip
+	}
+
+	addr, err := ParseIP(ip)
+	if a.mode == ModeTruncate {
+		if err != nil {
+			// Never store what could not be truncated
+			return ""
+		}
+		return Truncate(addr).String()
+	}
+
+	// Hash the canonical form when possible so equal addresses match
+	value := ip
+	if err == nil {
+		value = addr.String()
+	}
+	return a.hash(value, at)
+}
+
+// hash returns HMAC(dailySalt, value) with a salt derived from the secret and the UTC date
+func (a *Anonymizer) hash(value string, at time.Time) string {
+	salt := hmac.New(sha256.New, a.secret)
+	salt.Write([]byte(at.UTC().Format("2006-01-02")))
+
+	mac := hmac.New(sha256.New, salt.Sum(nil))
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// Truncate zeroes the host part of an address: IPv4 to /24, IPv6 to /48
+func Truncate(addr netip.Addr) netip.Addr {
+	bits := 48
+	if addr.Is4() {
+		bits = 24
+	}
+	prefix, err := addr.Prefix(bits)
+	if err != nil {
+		return addr
+	}
+	return prefix.Addr()
+}
+
+// ParseIP parses a client address that may carry a port or a
+// comma-separated X-Forwarded-For chain (the first entry is used)
+func ParseIP(value string) (netip.Addr, error) {
+	value = strings.TrimSpace(value)
+	if first, _, found := strings.Cut(value, ","); found {
+		value = strings.TrimSpace(first)
+	}
+	if host, _, err := net.SplitHostPort(value); err == nil {
+		value = host
+	}
+
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	return addr.Unmap(), nil
+}
+
+// ClientIP returns the client address of a request, preferring the
+// X-Forwarded-For and X-Real-IP headers set by proxies
+func ClientIP(r *http.Request) string {
+	ip := r.Header.Get("X-Forwarded-For")
+	if ip == "" {
+		ip = r.Header.Get("X-Real-IP")
+	}
+	if ip == "" {
+		ip = r.RemoteAddr
+	}
+	// Drop ports and proxy chains
+	if addr, err := ParseIP(ip); err == nil {
+		return addr.String()
+	}
+	return ip
+}
+
+// ParseProxies parses trusted proxy addresses given as CIDR prefixes or single IPs
+func ParseProxies(values []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if prefix, err := netip.ParsePrefix(value); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy address %q", value)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
+// TrustedClientIP returns the client address of a request without trusting
+// headers the client can set. X-Forwarded-For is only followed from the right
+// while the hop it came through is one of the trusted proxies.
+func TrustedClientIP(r *http.Request, trusted []netip.Prefix) string {
+	addr, err := ParseIP(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0 && isTrusted(addr, trusted); i-- {
+		hop, err := ParseIP(hops[i])
+		if err != nil {
+			break
+		}
+		addr = hop
+	}
+	return addr.String()
+}
+
+// isTrusted reports whether addr is within one of the trusted prefixes
+func isTrusted(addr netip.Addr, trusted []netip.Prefix) bool {
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
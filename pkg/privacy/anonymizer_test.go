@@ -0,0 +1,154 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package privacy
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAnonymizer_Truncate(t *testing.T) {
+	anonymizer, err := NewAnonymizer(ModeTruncate, "")
+	if err != nil {
+		t.Fatalf("NewAnonymizer failed: %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		ip       string
+		expected string
+	}{
+		{name: "IPv4", ip: "192.168.1.123", expected: "192.168.1.0"},
+		{name: "IPv4 With Port", ip: "203.0.113.9:54321", expected: "203.0.113.0"},
+		{name: "Forwarded Chain", ip: "198.51.100.7, 10.0.0.1", expected: "198.51.100.0"},
+		{name: "IPv6", ip: "2001:db8:abcd:12:1:2:3:4", expected: "2001:db8:abcd::"},
+		{name: "IPv4 Mapped IPv6", ip: "::ffff:192.0.2.44", expected: "192.0.2.0"},
+		{name: "Invalid", ip: "not-an-ip", expected: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if result := anonymizer.Anonymize(tc.ip, time.Now()); result != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestAnonymizer_Hash(t *testing.T) {
+	anonymizer, err := NewAnonymizer(ModeHash, "secret")
+	if err != nil {
+		t.Fatalf("NewAnonymizer failed: %v", err)
+	}
+
+	day := time.Date(2025, 5, 1, 10, 0, 0, 0, time.UTC)
+	first := anonymizer.Anonymize("192.168.1.1", day)
+
+	if first == "" || first == "192.168.1.1" {
+		t.Fatalf("Expected a hash, got %q", first)
+	}
+
+	// Same IP on the same day hashes the same, port and mapping ignored
+	if same := anonymizer.Anonymize("[::ffff:192.168.1.1]:8080", day.Add(5*time.Hour)); same != first {
+		t.Errorf("Expected equal hashes within a day, got %q and %q", first, same)
+	}
+
+	// The salt rotates every day
+	if next := anonymizer.Anonymize("192.168.1.1", day.Add(24*time.Hour)); next == first {
+		t.Errorf("Expected hash to change on the next day")
+	}
+
+	// Different secrets give different hashes
+	other, _ := NewAnonymizer(ModeHash, "other-secret")
+	if other.Anonymize("192.168.1.1", day) == first {
+		t.Errorf("Expected hash to depend on the secret")
+	}
+}
+
+func TestNewAnonymizer_InvalidMode(t *testing.T) {
+	if _, err := NewAnonymizer("scramble", ""); err == nil {
+		t.Errorf("Expected error for unknown mode")
+	}
+
+	none, err := NewAnonymizer(ModeNone, "")
+	if err != nil {
+		t.Fatalf("NewAnonymizer failed: %v", err)
+	}
+	if ip := none.Anonymize("192.168.1.1", time.Now()); ip != "192.168.1.1" {
+		t.Errorf("Expected IP to be kept, got %q", ip)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	testCases := []struct {
+		name       string
+		headers    map[string]string
+		remoteAddr string
+		expected   string
+	}{
+		{name: "Remote Address", remoteAddr: "192.0.2.1:1234", expected: "192.0.2.1"},
+		{name: "Forwarded Chain", headers: map[string]string{"X-Forwarded-For": "203.0.113.7, 10.0.0.1"}, remoteAddr: "10.0.0.1:80", expected: "203.0.113.7"},
+		{name: "Real IP", headers: map[string]string{"X-Real-IP": "2001:db8::1"}, remoteAddr: "10.0.0.1:80", expected: "2001:db8::1"},
+		{name: "Unparsable", headers: map[string]string{"X-Forwarded-For": "unknown"}, expected: "unknown"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tc.remoteAddr
+			for key, value := range tc.headers {
+				r.Header.Set(key, value)
+			}
+			if ip := ClientIP(r); ip != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, ip)
+			}
+		})
+	}
+}
+
+func TestTrustedClientIP(t *testing.T) {
+	trusted, err := ParseProxies([]string{"10.0.0.0/8", "192.0.2.10"})
+	if err != nil {
+		t.Fatalf("ParseProxies failed: %v", err)
+	}
+
+	testCases := []struct {
+		name       string
+		forwarded  string
+		remoteAddr string
+		expected   string
+	}{
+		{name: "Direct Client", remoteAddr: "203.0.113.7:1234", expected: "203.0.113.7"},
+		{name: "Spoofed Header", forwarded: "198.51.100.1", remoteAddr: "203.0.113.7:1234", expected: "203.0.113.7"},
+		{name: "Trusted Proxy", forwarded: "203.0.113.7", remoteAddr: "10.0.0.1:80", expected: "203.0.113.7"},
+		{name: "Spoofed Through Proxy", forwarded: "198.51.100.1, 203.0.113.7", remoteAddr: "10.0.0.1:80", expected: "203.0.113.7"},
+		{name: "Proxy Chain", forwarded: "203.0.113.7, 10.0.0.2", remoteAddr: "192.0.2.10:80", expected: "203.0.113.7"},
+		{name: "Proxy Without Header", remoteAddr: "10.0.0.1:80", expected: "10.0.0.1"},
+		{name: "Unparsable Hop", forwarded: "unknown", remoteAddr: "10.0.0.1:80", expected: "10.0.0.1"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tc.remoteAddr
+			if tc.forwarded != "" {
+				r.Header.Set("X-Forwarded-For", tc.forwarded)
+			}
+			if ip := TrustedClientIP(r, trusted); ip != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, ip)
+			}
+		})
+	}
+
+	if _, err := ParseProxies([]string{"proxy.internal"}); err == nil {
+		t.Errorf("Expected an error for an invalid proxy address")
+	}
+}
@@ -23,6 +23,17 @@ type RateLimiter struct {
 	mutex    sync.Mutex
 	limit    rate.Limit
 	burst    int
+	onReject func()
+}
+
+// Option configures optional RateLimiter behaviour
+type Option func(*RateLimiter)
+
+// WithRejectHook calls fn for every rejected request, e.g. to count rejections
+func WithRejectHook(fn func()) Option {
+	return func(r *RateLimiter) {
+		r.onReject = fn
+	}
 }
 
 // visitorState IP bazlı rate limiter durumu
@@ -32,16 +43,29 @@ type visitorState struct {
 }
 
 // NewRateLimiter creates a new rate limiter
-func NewRateLimiter(requestsPerSecond float64, burst int) *RateLimiter {
-	return &RateLimiter{
+func NewRateLimiter(requestsPerSecond float64, burst int, options ...Option) *RateLimiter {
+	limiter := &RateLimiter{
 		visitors: make(map[string]*visitorState),
 		limit:    rate.Limit(requestsPerSecond),
 		burst:    burst,
 	}
+	for _, opt := range options {
+		opt(limiter)
+	}
+	return limiter
 }
 
 // Allow checks if a request from a specific IP is allowed
 func (r *RateLimiter) Allow(ip string) bool {
+	allowed := r.allow(ip)
+	if !allowed && r.onReject != nil {
+		r.onReject()
+	}
+	return allowed
+}
+
+// allow takes a token from the limiter of the IP
+func (r *RateLimiter) allow(ip string) bool {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
@@ -54,6 +54,20 @@ func TestRateLimiter(t *testing.T) {
 	}
 }
 
+// TestRateLimiterRejectHook tests that every rejected request is reported
+func TestRateLimiterRejectHook(t *testing.T) {
+	rejected := 0
+	limiter := NewRateLimiter(1, 2, WithRejectHook(func() { rejected++ }))
+
+	for i := 0; i < 5; i++ {
+		limiter.Allow("192.168.1.1")
+	}
+
+	if rejected != 3 {
+		t.Errorf("Expected 3 rejections, got %d", rejected)
+	}
+}
+
 // TestRateLimiterMiddleware tests the middleware functionality
 func TestRateLimiterMiddleware(t *testing.T) {
 	// Create a new rate limiter with very low limit
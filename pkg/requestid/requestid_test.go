@@ -0,0 +1,61 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package requestid
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestMiddleware(t *testing.T) {
+	testCases := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{name: "Generated", incoming: "", keep: false},
+		{name: "Propagated", incoming: "req-42.edge:1", keep: true},
+		{name: "Invalid Characters", incoming: "id\nwith newline", keep: false},
+		{name: "Too Long", incoming: strings.Repeat("a", maxLength+1), keep: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var fromContext string
+			handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fromContext = FromContext(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/abc123", nil)
+			if tc.incoming != "" {
+				req.Header.Set(Header, tc.incoming)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			id := w.Header().Get(Header)
+			if id != fromContext {
+				t.Errorf("Expected the response header %q to match the context %q", id, fromContext)
+			}
+			if tc.keep && id != tc.incoming {
+				t.Errorf("Expected the incoming ID %q to be kept, got %q", tc.incoming, id)
+			}
+			if !tc.keep {
+				if _, err := uuid.Parse(id); err != nil {
+					t.Errorf("Expected a generated UUID, got %q", id)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package targeting
+
+import (
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"strings"
+)
+
+const (
+	// MinVariants and MaxVariants bound the destinations of a split link
+	MinVariants = 2
+	MaxVariants = 10
+
+	// maxVariantWeight bounds a single weight, weights are relative
+	maxVariantWeight = 10000
+)
+
+// variantName restricts names to values safe in cookies and CSV exports
+var variantName = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+// Variant is one weighted destination of an A/B split link
+type Variant struct {
+	Name   string `json:"name"`
+	Target string `json:"target"`
+	Weight int    `json:"weight"`
+}
+
+// PickVariant chooses a variant for a visitor key with probability
+// proportional to its weight. A key always gets the same variant as long
+// as the variants do not change.
+func PickVariant(variants []Variant, key string) (Variant, bool) {
+	total := 0
+	for _, variant := range variants {
+		total += variant.Weight
+	}
+	if total <= 0 {
+		return Variant{}, false
+	}
+
+	hash := fnv.New64a()
+	hash.Write([]byte(key))
+	point := int(hash.Sum64() % uint64(total))
+
+	for _, variant := range variants {
+		if point < variant.Weight {
+			return variant, true
+		}
+		point -= variant.Weight
+	}
+	return Variant{}, false
+}
+
+// FindVariant returns the variant with the given name
+func FindVariant(variants []Variant, name string) (Variant, bool) {
+	for _, variant := range variants {
+		if variant.Name == name {
+			return variant, true
+		}
+	}
+	return Variant{}, false
+}
+
+// NormalizeVariants validates the names and weights of split variants
+func NormalizeVariants(variants []Variant) ([]Variant, error) {
+	if len(variants) < MinVariants || len(variants) > MaxVariants {
+		return nil, fmt.Errorf("a split link needs %d to %d variants", MinVariants, MaxVariants)
+	}
+
+	normalized := make([]Variant, 0, len(variants))
+	seen := make(map[string]bool)
+	for _, variant := range variants {
+		variant.Name = strings.TrimSpace(variant.Name)
+		variant.Target = strings.TrimSpace(variant.Target)
+
+		if !variantName.MatchString(variant.Name) {
+			return nil, fmt.Errorf("variant name %q must be 1 to 32 letters, digits, '-' or '_'", variant.Name)
+		}
+		if seen[variant.Name] {
+			return nil, fmt.Errorf("duplicate variant name %q", variant.Name)
+		}
+		if variant.Weight <= 0 || variant.Weight > maxVariantWeight {
+			return nil, fmt.Errorf("variant %q weight must be between 1 and %d", variant.Name, maxVariantWeight)
+		}
+		if variant.Target == "" {
+			return nil, fmt.Errorf("variant %q target is required", variant.Name)
+		}
+
+		seen[variant.Name] = true
+		normalized = append(normalized, variant)
+	}
+	return normalized, nil
+}
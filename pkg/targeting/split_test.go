@@ -0,0 +1,77 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package targeting
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestPickVariant(t *testing.T) {
+	variants := []Variant{
+		{Name: "a", Target: "https://example.com/a", Weight: 70},
+		{Name: "b", Target: "https://example.com/b", Weight: 30},
+	}
+
+	// Sticky per key
+	first, ok := PickVariant(variants, "abc123|192.0.2.1")
+	if !ok {
+		t.Fatalf("Expected a variant")
+	}
+	for i := 0; i < 10; i++ {
+		if again, _ := PickVariant(variants, "abc123|192.0.2.1"); again.Name != first.Name {
+			t.Fatalf("Expected the same variant for the same key, got %s and %s", first.Name, again.Name)
+		}
+	}
+
+	// Split follows the weights
+	counts := make(map[string]int)
+	for i := 0; i < 10000; i++ {
+		variant, _ := PickVariant(variants, fmt.Sprintf("visitor-%d", i))
+		counts[variant.Name]++
+	}
+	if counts["a"] < 6500 || counts["a"] > 7500 {
+		t.Errorf("Expected about 7000 visitors for variant a, got %d", counts["a"])
+	}
+
+	if _, ok := PickVariant(nil, "key"); ok {
+		t.Errorf("Expected no variant without variants")
+	}
+}
+
+func TestNormalizeVariants(t *testing.T) {
+	valid := []Variant{
+		{Name: " control ", Target: "https://example.com/a", Weight: 1},
+		{Name: "new-page", Target: "https://example.com/b", Weight: 1},
+	}
+	variants, err := NormalizeVariants(valid)
+	if err != nil {
+		t.Fatalf("NormalizeVariants failed: %v", err)
+	}
+	if variants[0].Name != "control" {
+		t.Errorf("Expected trimmed name, got %q", variants[0].Name)
+	}
+	if variant, ok := FindVariant(variants, "new-page"); !ok || variant.Target != "https://example.com/b" {
+		t.Errorf("Expected to find variant new-page, got %+v", variant)
+	}
+
+	invalid := map[string][]Variant{
+		"Single Variant": {{Name: "a", Target: "https://example.com", Weight: 1}},
+		"Duplicate Name": {{Name: "a", Target: "https://example.com", Weight: 1}, {Name: "a", Target: "https://example.com", Weight: 1}},
+		"Zero Weight":    {{Name: "a", Target: "https://example.com", Weight: 0}, {Name: "b", Target: "https://example.com", Weight: 1}},
+		"Cookie Unsafe":  {{Name: "a;b", Target: "https://example.com", Weight: 1}, {Name: "b", Target: "https://example.com", Weight: 1}},
+		"Missing Target": {{Name: "a", Weight: 1}, {Name: "b", Target: "https://example.com", Weight: 1}},
+	}
+	for name, variants := range invalid {
+		if _, err := NormalizeVariants(variants); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
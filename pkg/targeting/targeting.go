@@ -0,0 +1,285 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package targeting
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	// Embedded zone database so rule time zones load on hosts without one
+	_ "time/tzdata"
+)
+
+// Operating systems detected from the User-Agent
+const (
+	OSiOS     = "ios"
+	OSAndroid = "android"
+	OSWindows = "windows"
+	OSMacOS   = "macos"
+	OSLinux   = "linux"
+	OSOther   = "other"
+)
+
+// Device types detected from the User-Agent
+const (
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceDesktop = "desktop"
+)
+
+// Rule sends visitors matching all of its conditions to Target.
+// Within a condition any of the listed values matches; empty conditions match everyone.
+type Rule struct {
+	Target    string     `json:"target"`
+	Countries []string   `json:"countries,omitempty"` // ISO 3166 codes, e.g. "DE"
+	OS        []string   `json:"os,omitempty"`        // ios, android, windows, macos, linux, other
+	Devices   []string   `json:"devices,omitempty"`   // mobile, tablet, desktop
+	Languages []string   `json:"languages,omitempty"` // Primary language subtags, e.g. "de"
+	Hours     *HourRange `json:"hours,omitempty"`
+}
+
+// HourRange matches visits from hour From up to, excluding, hour To in a
+// time zone. A range like 22 to 6 wraps around midnight.
+type HourRange struct {
+	From     int    `json:"from"`
+	To       int    `json:"to"`
+	Timezone string `json:"timezone,omitempty"` // IANA name, UTC when empty
+
+	location *time.Location // Resolved Timezone, set by Normalize and when decoded
+}
+
+// locations caches loaded time zones by name
+var locations sync.Map
+
+// loadLocation returns the time zone of an IANA name, UTC when empty
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	if cached, ok := locations.Load(name); ok {
+		return cached.(*time.Location), nil
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	locations.Store(name, location)
+	return location, nil
+}
+
+// UnmarshalJSON decodes the range and resolves its time zone once, so
+// stored links do not load it on every evaluation
+func (h *HourRange) UnmarshalJSON(data []byte) error {
+	type plain HourRange
+	if err := json.Unmarshal(data, (*plain)(h)); err != nil {
+		return err
+	}
+	// Unknown zones are rejected by Normalize and never match
+	h.location, _ = loadLocation(h.Timezone)
+	return nil
+}
+
+// Visitor holds the request properties rules are evaluated against
+type Visitor struct {
+	Country   string
+	OS        string
+	Device    string
+	Languages []string
+	Time      time.Time
+}
+
+// NewVisitor describes the client of a request, country is resolved by the caller
+func NewVisitor(r *http.Request, country string, at time.Time) Visitor {
+	os, device := ParseUserAgent(r.UserAgent())
+	return Visitor{
+		Country:   strings.ToUpper(country),
+		OS:        os,
+		Device:    device,
+		Languages: ParseAcceptLanguage(r.Header.Get("Accept-Language")),
+		Time:      at,
+	}
+}
+
+// Select returns the target of the first rule matching the visitor
+func Select(rules []Rule, visitor Visitor) (string, bool) {
+	for _, rule := range rules {
+		if rule.Matches(visitor) {
+			return rule.Target, true
+		}
+	}
+	return "", false
+}
+
+// Matches reports whether the visitor satisfies every condition of the rule
+func (r Rule) Matches(v Visitor) bool {
+	if len(r.Countries) > 0 && !contains(r.Countries, v.Country) {
+		return false
+	}
+	if len(r.OS) > 0 && !contains(r.OS, v.OS) {
+		return false
+	}
+	if len(r.Devices) > 0 && !contains(r.Devices, v.Device) {
+		return false
+	}
+	if len(r.Languages) > 0 && !containsAny(r.Languages, v.Languages) {
+		return false
+	}
+	if r.Hours != nil && !r.Hours.Contains(v.Time) {
+		return false
+	}
+	return true
+}
+
+// Contains reports whether the time falls into the hour range
+func (h *HourRange) Contains(at time.Time) bool {
+	location := h.location
+	if location == nil {
+		loaded, err := loadLocation(h.Timezone)
+		if err != nil {
+			return false
+		}
+		location = loaded
+	}
+
+	hour := at.In(location).Hour()
+	if h.From <= h.To {
+		return hour >= h.From && hour < h.To
+	}
+	return hour >= h.From || hour < h.To
+}
+
+// Normalize validates the rule conditions and returns them in canonical case
+func (r Rule) Normalize() (Rule, error) {
+	normalized := Rule{Target: strings.TrimSpace(r.Target)}
+	if normalized.Target == "" {
+		return Rule{}, fmt.Errorf("rule target is required")
+	}
+
+	for _, country := range r.Countries {
+		country = strings.ToUpper(strings.TrimSpace(country))
+		if len(country) != 2 {
+			return Rule{}, fmt.Errorf("invalid country code %q", country)
+		}
+		normalized.Countries = append(normalized.Countries, country)
+	}
+	for _, os := range r.OS {
+		os = strings.ToLower(strings.TrimSpace(os))
+		switch os {
+		case OSiOS, OSAndroid, OSWindows, OSMacOS, OSLinux, OSOther:
+		default:
+			return Rule{}, fmt.Errorf("unknown operating system %q", os)
+		}
+		normalized.OS = append(normalized.OS, os)
+	}
+	for _, device := range r.Devices {
+		device = strings.ToLower(strings.TrimSpace(device))
+		switch device {
+		case DeviceMobile, DeviceTablet, DeviceDesktop:
+		default:
+			return Rule{}, fmt.Errorf("unknown device %q", device)
+		}
+		normalized.Devices = append(normalized.Devices, device)
+	}
+	for _, language := range r.Languages {
+		language = primaryLanguage(language)
+		if language == "" {
+			return Rule{}, fmt.Errorf("invalid language")
+		}
+		normalized.Languages = append(normalized.Languages, language)
+	}
+
+	if r.Hours != nil {
+		hours := *r.Hours
+		if hours.From < 0 || hours.From > 23 || hours.To < 0 || hours.To > 24 || hours.From == hours.To {
+			return Rule{}, fmt.Errorf("hours must range from 0 to 24 and not be empty")
+		}
+		location, err := loadLocation(hours.Timezone)
+		if err != nil {
+			return Rule{}, fmt.Errorf("unknown time zone %q", hours.Timezone)
+		}
+		hours.location = location
+		normalized.Hours = &hours
+	}
+
+	return normalized, nil
+}
+
+// ParseUserAgent detects the operating system and device type of a User-Agent
+func ParseUserAgent(userAgent string) (os, device string) {
+	ua := strings.ToLower(userAgent)
+
+	switch {
+	case strings.Contains(ua, "iphone") || strings.Contains(ua, "ipod"):
+		return OSiOS, DeviceMobile
+	case strings.Contains(ua, "ipad"):
+		return OSiOS, DeviceTablet
+	case strings.Contains(ua, "android"):
+		// Android tablets leave "mobile" out of the User-Agent
+		if strings.Contains(ua, "mobile") {
+			return OSAndroid, DeviceMobile
+		}
+		return OSAndroid, DeviceTablet
+	case strings.Contains(ua, "windows phone"):
+		return OSOther, DeviceMobile
+	case strings.Contains(ua, "windows"):
+		return OSWindows, DeviceDesktop
+	case strings.Contains(ua, "macintosh") || strings.Contains(ua, "mac os x"):
+		return OSMacOS, DeviceDesktop
+	case strings.Contains(ua, "linux") || strings.Contains(ua, "x11") || strings.Contains(ua, "cros"):
+		return OSLinux, DeviceDesktop
+	case strings.Contains(ua, "mobile"):
+		return OSOther, DeviceMobile
+	default:
+		return OSOther, DeviceDesktop
+	}
+}
+
+// ParseAcceptLanguage returns the primary language subtags of an
+// Accept-Language header in the order given, ignoring quality values
+func ParseAcceptLanguage(header string) []string {
+	var languages []string
+	for _, part := range strings.Split(header, ",") {
+		tag, _, _ := strings.Cut(part, ";")
+		if language := primaryLanguage(tag); language != "" && language != "*" && !contains(languages, language) {
+			languages = append(languages, language)
+		}
+	}
+	return languages
+}
+
+// primaryLanguage returns the lower-cased primary subtag of a language tag, "de" for "de-AT"
+func primaryLanguage(tag string) string {
+	primary, _, _ := strings.Cut(strings.TrimSpace(tag), "-")
+	return strings.ToLower(primary)
+}
+
+// contains reports whether values holds value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// containsAny reports whether values holds any of candidates
+func containsAny(values, candidates []string) bool {
+	for _, candidate := range candidates {
+		if contains(values, candidate) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,147 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package targeting
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	iPhoneUA  = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 Mobile/15E148 Safari/604.1"
+	androidUA = "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 Chrome/124.0 Mobile Safari/537.36"
+	desktopUA = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 Chrome/124.0 Safari/537.36"
+)
+
+func TestParseUserAgent(t *testing.T) {
+	testCases := []struct {
+		name           string
+		userAgent      string
+		expectedOS     string
+		expectedDevice string
+	}{
+		{name: "iPhone", userAgent: iPhoneUA, expectedOS: OSiOS, expectedDevice: DeviceMobile},
+		{name: "iPad", userAgent: "Mozilla/5.0 (iPad; CPU OS 17_4 like Mac OS X)", expectedOS: OSiOS, expectedDevice: DeviceTablet},
+		{name: "Android Phone", userAgent: androidUA, expectedOS: OSAndroid, expectedDevice: DeviceMobile},
+		{name: "Android Tablet", userAgent: "Mozilla/5.0 (Linux; Android 14; SM-X910) Chrome/124.0 Safari/537.36", expectedOS: OSAndroid, expectedDevice: DeviceTablet},
+		{name: "Windows", userAgent: desktopUA, expectedOS: OSWindows, expectedDevice: DeviceDesktop},
+		{name: "macOS", userAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_4) Safari/605.1.15", expectedOS: OSMacOS, expectedDevice: DeviceDesktop},
+		{name: "Linux", userAgent: "Mozilla/5.0 (X11; Linux x86_64) Firefox/125.0", expectedOS: OSLinux, expectedDevice: DeviceDesktop},
+		{name: "Empty", userAgent: "", expectedOS: OSOther, expectedDevice: DeviceDesktop},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			os, device := ParseUserAgent(tc.userAgent)
+			if os != tc.expectedOS || device != tc.expectedDevice {
+				t.Errorf("Expected %s/%s, got %s/%s", tc.expectedOS, tc.expectedDevice, os, device)
+			}
+		})
+	}
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	languages := ParseAcceptLanguage("de-AT, de;q=0.9, en-US;q=0.8, *;q=0.1")
+	if strings.Join(languages, ",") != "de,en" {
+		t.Errorf("Expected [de en], got %v", languages)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	rules := []Rule{
+		{Target: "https://apps.apple.com/app", OS: []string{OSiOS}},
+		{Target: "https://play.google.com/app", OS: []string{OSAndroid}},
+		{Target: "https://example.com/de", Countries: []string{"DE", "AT"}},
+		{Target: "https://example.com/night", Hours: &HourRange{From: 22, To: 6, Timezone: "Europe/Berlin"}},
+		{Target: "https://example.com/fr", Languages: []string{"fr"}, Devices: []string{DeviceDesktop}},
+	}
+
+	noon := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	night := time.Date(2025, 3, 10, 23, 30, 0, 0, time.UTC) // 00:30 in Berlin
+
+	testCases := []struct {
+		name           string
+		userAgent      string
+		language       string
+		country        string
+		at             time.Time
+		expectedTarget string
+	}{
+		{name: "iOS", userAgent: iPhoneUA, country: "DE", at: noon, expectedTarget: "https://apps.apple.com/app"},
+		{name: "Android", userAgent: androidUA, country: "US", at: noon, expectedTarget: "https://play.google.com/app"},
+		{name: "Country", userAgent: desktopUA, country: "at", at: noon, expectedTarget: "https://example.com/de"},
+		{name: "Time Of Day", userAgent: desktopUA, country: "US", at: night, expectedTarget: "https://example.com/night"},
+		{name: "Language And Device", userAgent: desktopUA, language: "fr-CA,en;q=0.5", country: "CA", at: noon, expectedTarget: "https://example.com/fr"},
+		{name: "No Match", userAgent: desktopUA, language: "en", country: "US", at: noon, expectedTarget: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/abc123", nil)
+			req.Header.Set("User-Agent", tc.userAgent)
+			req.Header.Set("Accept-Language", tc.language)
+
+			target, ok := Select(rules, NewVisitor(req, tc.country, tc.at))
+			if target != tc.expectedTarget || ok != (tc.expectedTarget != "") {
+				t.Errorf("Expected %q, got %q (%v)", tc.expectedTarget, target, ok)
+			}
+		})
+	}
+}
+
+func TestRule_Normalize(t *testing.T) {
+	rule, err := Rule{
+		Target:    " https://example.com ",
+		Countries: []string{"de"},
+		OS:        []string{"iOS"},
+		Languages: []string{"DE-at"},
+	}.Normalize()
+	if err != nil {
+		t.Fatalf("Normalize failed: %v", err)
+	}
+	if rule.Target != "https://example.com" || rule.Countries[0] != "DE" || rule.OS[0] != OSiOS || rule.Languages[0] != "de" {
+		t.Errorf("Unexpected normalized rule %+v", rule)
+	}
+
+	// The time zone is resolved once and kept for evaluation
+	rule, err = Rule{Target: "https://example.com", Hours: &HourRange{From: 9, To: 17, Timezone: "Asia/Tokyo"}}.Normalize()
+	if err != nil {
+		t.Fatalf("Normalize failed: %v", err)
+	}
+	if rule.Hours.location == nil || rule.Hours.location.String() != "Asia/Tokyo" {
+		t.Errorf("Expected the time zone to be resolved, got %v", rule.Hours.location)
+	}
+
+	// Decoded ranges of stored links resolve it as well
+	var decoded Rule
+	if err := json.Unmarshal([]byte(`{"target":"https://example.com","hours":{"from":9,"to":17,"timezone":"Asia/Tokyo"}}`), &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if decoded.Hours.location == nil || !decoded.Hours.Contains(time.Date(2025, 3, 1, 1, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected 10:00 in Tokyo to match, got location %v", decoded.Hours.location)
+	}
+
+	invalid := []Rule{
+		{},
+		{Target: "https://example.com", Countries: []string{"Germany"}},
+		{Target: "https://example.com", OS: []string{"symbian"}},
+		{Target: "https://example.com", Devices: []string{"watch"}},
+		{Target: "https://example.com", Hours: &HourRange{From: 9, To: 9}},
+		{Target: "https://example.com", Hours: &HourRange{From: 9, To: 17, Timezone: "Mars/Olympus"}},
+	}
+	for _, rule := range invalid {
+		if _, err := rule.Normalize(); err == nil {
+			t.Errorf("Expected error for rule %+v", rule)
+		}
+	}
+}
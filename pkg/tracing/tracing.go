@@ -0,0 +1,218 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/redis/go-redis/extra/redisotel/v9"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Span exporters
+const (
+	ExporterNone   = "none"   // Tracing is disabled
+	ExporterStdout = "stdout" // Spans are written as JSON to stdout
+	ExporterOTLP   = "otlp"   // Spans are sent to an OTLP/HTTP collector
+)
+
+// instrumentationName identifies the spans created by this service
+const instrumentationName = "github.com/yasin-yalcin-dev/go-url-shortener"
+
+// Config selects where spans are exported to
+type Config struct {
+	Exporter     string  // See ExporterNone, ExporterStdout and ExporterOTLP
+	OTLPEndpoint string  // host:port of the OTLP/HTTP collector
+	ServiceName  string  // service.name resource attribute
+	SampleRatio  float64 // Fraction of new traces that are sampled
+}
+
+// Provider owns the tracer provider installed by Setup
+type Provider struct {
+	provider *sdktrace.TracerProvider
+}
+
+// Option configures optional Setup behaviour
+type Option func(*setupOptions)
+
+type setupOptions struct {
+	exporter sdktrace.SpanExporter
+	writer   io.Writer
+}
+
+// WithExporter exports spans to the given exporter instead of the configured
+// one, e.g. a tracetest.InMemoryExporter in tests
+func WithExporter(exporter sdktrace.SpanExporter) Option {
+	return func(o *setupOptions) {
+		o.exporter = exporter
+	}
+}
+
+// WithWriter sets where the stdout exporter writes to
+func WithWriter(w io.Writer) Option {
+	return func(o *setupOptions) {
+		o.writer = w
+	}
+}
+
+// Setup installs the global tracer provider and the W3C trace context
+// propagator. The returned provider must be shut down to flush spans.
+func Setup(ctx context.Context, cfg Config, options ...Option) (*Provider, error) {
+	opts := &setupOptions{writer: os.Stdout}
+	for _, opt := range options {
+		opt(opts)
+	}
+
+	// Trace context is propagated even when spans are not exported
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	exporter := opts.exporter
+	if exporter == nil {
+		var err error
+		exporter, err = newExporter(ctx, cfg, opts.writer)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if exporter == nil {
+		return &Provider{}, nil
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(cfg.ServiceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace resource: %v", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return &Provider{provider: provider}, nil
+}
+
+// newExporter creates the configured exporter, nil when tracing is disabled
+func newExporter(ctx context.Context, cfg Config, w io.Writer) (sdktrace.SpanExporter, error) {
+	switch cfg.Exporter {
+	case ExporterNone, "":
+		return nil, nil
+	case ExporterStdout:
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(w))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create stdout exporter: %v", err)
+		}
+		return exporter, nil
+	case ExporterOTLP:
+		var options []otlptracehttp.Option
+		if cfg.OTLPEndpoint != "" {
+			options = append(options, otlptracehttp.WithEndpoint(cfg.OTLPEndpoint), otlptracehttp.WithInsecure())
+		}
+		exporter, err := otlptracehttp.New(ctx, options...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OTLP exporter: %v", err)
+		}
+		return exporter, nil
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", cfg.Exporter)
+	}
+}
+
+// Shutdown flushes pending spans and stops the provider
+func (p *Provider) Shutdown(ctx context.Context) error {
+	if p == nil || p.provider == nil {
+		return nil
+	}
+	return p.provider.Shutdown(ctx)
+}
+
+// InstrumentRedis adds a span for every command and pipeline of a Redis client
+func InstrumentRedis(client redis.UniversalClient) error {
+	if err := redisotel.InstrumentTracing(client); err != nil {
+		return fmt.Errorf("failed to instrument Redis client: %v", err)
+	}
+	return nil
+}
+
+// Start starts a span with the tracer of the service. Spans are dropped
+// until Setup installed a provider.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// StartLinked starts a span linked to the spans of other traces, e.g. a batch
+// written in the background for several requests
+func StartLinked(ctx context.Context, name string, links []trace.Link, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithLinks(links...), trace.WithAttributes(attrs...))
+}
+
+// End records err on the span, if any, and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Middleware continues traces from W3C traceparent headers and starts a
+// server span per request, named after the chi route pattern once routed
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := otel.Tracer(instrumentationName).Start(ctx, r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.URLPath(r.URL.Path),
+				semconv.UserAgentOriginal(r.UserAgent()),
+			),
+		)
+		defer span.End()
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r.WithContext(ctx))
+
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			span.SetName(r.Method + " " + rctx.RoutePattern())
+			span.SetAttributes(semconv.HTTPRoute(rctx.RoutePattern()))
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	})
+}
@@ -0,0 +1,192 @@
+/*
+ ** ** ** ** ** **
+  \ \ / / \ \ / /
+   \ V /   \ V /
+    | |     | |
+    |_|     |_|
+   Yasin   Yalcin
+*/
+
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// setupMemory installs a provider that keeps finished spans in memory
+func setupMemory(t *testing.T) (*Provider, *tracetest.InMemoryExporter) {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	provider, err := Setup(context.Background(), Config{ServiceName: "test", SampleRatio: 1}, WithExporter(exporter))
+	if err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	return provider, exporter
+}
+
+// flush exports the ended spans, the in-memory exporter drops them on shutdown
+func flush(t *testing.T, provider *Provider) {
+	t.Helper()
+	if err := provider.provider.ForceFlush(context.Background()); err != nil {
+		t.Fatalf("ForceFlush failed: %v", err)
+	}
+}
+
+// findSpan returns the finished span with the given name
+func findSpan(spans tracetest.SpanStubs, name string) (tracetest.SpanStub, bool) {
+	for _, span := range spans {
+		if span.Name == name {
+			return span, true
+		}
+	}
+	return tracetest.SpanStub{}, false
+}
+
+// TestMiddleware tests trace context propagation and route naming
+func TestMiddleware(t *testing.T) {
+	provider, exporter := setupMemory(t)
+
+	r := chi.NewRouter()
+	r.Use(Middleware)
+	r.Get("/{shortened}", func(w http.ResponseWriter, r *http.Request) {
+		_, span := Start(r.Context(), "ShortenHandler.Redirect")
+		span.End()
+		w.WriteHeader(http.StatusFound)
+	})
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodGet, "/abc123", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	flush(t, provider)
+	spans := exporter.GetSpans()
+
+	server, ok := findSpan(spans, "GET /{shortened}")
+	if !ok {
+		t.Fatalf("Expected a server span named after the route, got %d spans", len(spans))
+	}
+	if server.SpanKind != trace.SpanKindServer {
+		t.Errorf("Expected a server span, got %v", server.SpanKind)
+	}
+	if server.SpanContext.TraceID().String() != traceID {
+		t.Errorf("Expected the incoming trace to be continued, got trace %s", server.SpanContext.TraceID())
+	}
+	if !server.Parent.IsRemote() {
+		t.Error("Expected the server span to have the remote parent")
+	}
+
+	handler, ok := findSpan(spans, "ShortenHandler.Redirect")
+	if !ok {
+		t.Fatal("Expected a handler span")
+	}
+	if handler.Parent.SpanID() != server.SpanContext.SpanID() {
+		t.Error("Expected the handler span to be a child of the server span")
+	}
+}
+
+// TestEnd tests recording errors on spans
+func TestEnd(t *testing.T) {
+	provider, exporter := setupMemory(t)
+
+	_, failed := Start(context.Background(), "failed")
+	End(failed, errors.New("boom"))
+	_, succeeded := Start(context.Background(), "succeeded")
+	End(succeeded, nil)
+
+	flush(t, provider)
+	spans := exporter.GetSpans()
+
+	if span, _ := findSpan(spans, "failed"); span.Status.Code != codes.Error || len(span.Events) != 1 {
+		t.Errorf("Expected an error status and event, got %v with %d events", span.Status, len(span.Events))
+	}
+	if span, _ := findSpan(spans, "succeeded"); span.Status.Code == codes.Error {
+		t.Errorf("Expected no error status, got %v", span.Status)
+	}
+}
+
+// TestStartLinked tests linking a batch span to request spans
+func TestStartLinked(t *testing.T) {
+	provider, exporter := setupMemory(t)
+
+	var links []trace.Link
+	for i := 0; i < 2; i++ {
+		_, span := Start(context.Background(), "request")
+		links = append(links, trace.Link{SpanContext: span.SpanContext()})
+		span.End()
+	}
+	_, batch := StartLinked(context.Background(), "batch", links)
+	batch.End()
+
+	flush(t, provider)
+	span, ok := findSpan(exporter.GetSpans(), "batch")
+	if !ok || len(span.Links) != 2 {
+		t.Errorf("Expected a batch span with 2 links, got %+v", span.Links)
+	}
+}
+
+// TestInstrumentRedis tests that Redis commands create spans
+func TestInstrumentRedis(t *testing.T) {
+	provider, exporter := setupMemory(t)
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	if err := InstrumentRedis(client); err != nil {
+		t.Fatalf("InstrumentRedis failed: %v", err)
+	}
+
+	ctx, parent := Start(context.Background(), "RedisStore.GetLink")
+	client.Get(ctx, "abc123")
+	parent.End()
+
+	flush(t, provider)
+	spans := exporter.GetSpans()
+	command, ok := findSpan(spans, "get")
+	if !ok {
+		t.Fatalf("Expected a span for the GET command, got %d spans", len(spans))
+	}
+	if command.Parent.SpanID() != parent.SpanContext().SpanID() {
+		t.Error("Expected the command span to be a child of the store span")
+	}
+}
+
+// TestSetup tests the configured exporters
+func TestSetup(t *testing.T) {
+	// Disabled tracing installs no provider
+	provider, err := Setup(context.Background(), Config{Exporter: ExporterNone})
+	if err != nil || provider.Shutdown(context.Background()) != nil {
+		t.Errorf("Expected disabled tracing to set up, got %v", err)
+	}
+
+	if _, err := Setup(context.Background(), Config{Exporter: "zipkin"}); err == nil {
+		t.Error("Expected an error for an unknown exporter")
+	}
+
+	var out bytes.Buffer
+	provider, err = Setup(context.Background(), Config{Exporter: ExporterStdout, ServiceName: "test", SampleRatio: 1}, WithWriter(&out))
+	if err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	_, span := Start(context.Background(), "stdout-span")
+	span.End()
+	provider.Shutdown(context.Background())
+
+	if !strings.Contains(out.String(), "stdout-span") {
+		t.Errorf("Expected the span to be written to stdout, got %q", out.String())
+	}
+}
@@ -36,14 +36,25 @@ run_test() {
 
 # List of test packages
 test_packages=(
+    "internal/audit"
+    "internal/cache"
     "internal/config"
     "internal/handler"
     "internal/redis"
     "internal/service"
     "pkg/analytics"
+    "pkg/botfilter"
+    "pkg/geoip"
+    "pkg/metadata"
+    "pkg/metrics"
+    "pkg/privacy"
     "pkg/errors"
+    "pkg/logger"
     "pkg/ratelimiter"
+    "pkg/requestid"
     "pkg/shortener"
+    "pkg/targeting"
+    "pkg/tracing"
     "pkg/validator"
 )
 
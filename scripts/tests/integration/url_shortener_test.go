@@ -24,9 +24,11 @@ import (
 
 	"github.com/yasin-yalcin-dev/go-url-shortener/internal/config"
 	"github.com/yasin-yalcin-dev/go-url-shortener/internal/handler"
+	"github.com/yasin-yalcin-dev/go-url-shortener/internal/model"
 	"github.com/yasin-yalcin-dev/go-url-shortener/internal/service"
 	customerrors "github.com/yasin-yalcin-dev/go-url-shortener/pkg/errors"
 	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/logger"
+	"github.com/yasin-yalcin-dev/go-url-shortener/pkg/metadata"
 )
 
 // Mock URLStore for testing
@@ -47,6 +49,36 @@ func (m *mockURLStore) GetOriginalURL(ctx context.Context, shortID string) (stri
 	return url, nil
 }
 
+func (m *mockURLStore) SaveLinkWithTTL(ctx context.Context, link *model.Link, ttl time.Duration) error {
+	m.urls[link.ShortID] = link.Original
+	return nil
+}
+
+func (m *mockURLStore) GetLink(ctx context.Context, shortID string) (*model.Link, error) {
+	url, exists := m.urls[shortID]
+	if !exists {
+		return nil, customerrors.New(http.StatusNotFound, "URL not found")
+	}
+	return &model.Link{ShortID: shortID, Original: url}, nil
+}
+
+func (m *mockURLStore) ConsumeClick(ctx context.Context, shortID string, maxClicks int64) (int64, error) {
+	return 1, nil
+}
+
+func (m *mockURLStore) ClicksUsed(ctx context.Context, shortID string) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockURLStore) SaveLinkMetadata(ctx context.Context, shortID string, meta *metadata.Metadata) error {
+	return nil
+}
+
+func (m *mockURLStore) DeleteLink(ctx context.Context, shortID string) error {
+	delete(m.urls, shortID)
+	return nil
+}
+
 func setupTestServer() (*handler.ShortenHandler, *chi.Mux) {
 	// Create mock configuration
 	cfg := &config.Config{